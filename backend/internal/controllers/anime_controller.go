@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"anime-tanyaayomi/internal/services"
-	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -10,15 +9,109 @@ import (
 type AnimeController struct {
 	Service          *services.SankavollereiService
 	AnimeIndoService *services.AnimeIndoService
+	Providers        *services.ProviderRegistry
+	StreamResolver   *services.StreamResolver
 }
 
-func NewAnimeController() *AnimeController {
+func NewAnimeController(providers *services.ProviderRegistry) *AnimeController {
+	sankavollerei := services.NewSankavollereiService("")
+	animeIndo := services.NewAnimeIndoService()
+
 	return &AnimeController{
 		// Keep Sankavollerei for episode streaming (existing functionality)
-		Service: services.NewSankavollereiService(""),
+		Service: sankavollerei,
 		// Add Anime Indo for anime data (new functionality)
-		AnimeIndoService: services.NewAnimeIndoService(),
+		AnimeIndoService: animeIndo,
+		// Named providers for /anime/:provider/* routes
+		Providers: providers,
+		// Ordered stream sources merged by GetStream (configured via STREAM_SOURCES)
+		StreamResolver: services.NewDefaultStreamResolver(sankavollerei, animeIndo, services.NewZoroService()),
+	}
+}
+
+// ========== Provider Registry Endpoints ==========
+
+// unknownProvider responds 404 with the list of registered anime providers
+func (c *AnimeController) unknownProvider(ctx *fiber.Ctx, err error) error {
+	names, _ := c.Providers.AnimeNames()
+	return ctx.Status(404).JSON(fiber.Map{"error": err.Error(), "providers": names})
+}
+
+// SearchByProvider searches anime using the named provider
+func (c *AnimeController) SearchByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Anime(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	query := ctx.Query("q")
+	if query == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Query param 'q' is required"})
+	}
+
+	results, err := provider.SearchAnime(query)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": fiber.Map{
+			"animeList": results,
+		},
+	})
+}
+
+// GetDetailByProvider returns anime details from the named provider
+func (c *AnimeController) GetDetailByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Anime(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	slug := ctx.Params("slug")
+	if slug == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Slug is required"})
+	}
+
+	detail, err := provider.GetAnimeDetail(slug)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": detail})
+}
+
+// GetStreamByProvider returns episode streaming data from the named provider
+func (c *AnimeController) GetStreamByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Anime(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	slug := ctx.Params("slug")
+	if slug == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Slug is required"})
 	}
+
+	stream, err := provider.GetEpisodeStream(slug)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": stream})
+}
+
+// GetProviders lists the registered anime and manga providers
+func (c *AnimeController) GetProviders(ctx *fiber.Ctx) error {
+	animeNames, animeDefault := c.Providers.AnimeNames()
+	mangaNames, mangaDefault := c.Providers.MangaNames()
+
+	return ctx.JSON(fiber.Map{
+		"data": fiber.Map{
+			"anime": fiber.Map{"providers": animeNames, "default": animeDefault},
+			"manga": fiber.Map{"providers": mangaNames, "default": mangaDefault},
+		},
+	})
 }
 
 // ========== New Anime Indo Endpoints ==========
@@ -181,57 +274,20 @@ func (c *AnimeController) GetDetail(ctx *fiber.Ctx) error {
 	return ctx.JSON(fiber.Map{"data": result.Data})
 }
 
-// GetStream returns episode streaming data (Sankavollerei + Anime Indo)
+// GetStream returns every working server for an episode from all stream sources
+// (Otakudesu, Anime Indo, Zoro) plus the per-source results
 func (c *AnimeController) GetStream(ctx *fiber.Ctx) error {
 	slug := ctx.Params("slug")
 	if slug == "" {
 		return ctx.Status(400).JSON(fiber.Map{"error": "Slug is required"})
 	}
 
-	// Try Sankavollerei first (handles Otakudesu slugs)
-	result, err := c.Service.GetEpisodeStream(slug)
-	if err == nil {
-		// Success with Sankavollerei
-		return ctx.JSON(fiber.Map{
-			"data": fiber.Map{
-				"title":               result.Data.Title,
-				"defaultStreamingUrl": result.Data.DefaultStreamingUrl,
-				"stream_link":         result.Data.StreamLink,
-				"url":                 result.Data.URL,
-				"animeId":             result.Data.AnimeID,
-				"server":              result.Data.Server,
-				"downloadUrl":         result.Data.DownloadURL,
-			},
-		})
-	}
-
-	// Fallback: Try Anime Indo API (handles clean slugs like "one-piece-episode-1155")
-	animeIndoStreams, animeIndoErr := c.AnimeIndoService.GetEpisodeStream(slug, "")
-	if animeIndoErr == nil && len(animeIndoStreams) > 0 {
-		// Extract anime slug (remove episode number) for animeId
-		animeSlug := slug
-		if idx := strings.Index(slug, "-episode-"); idx != -1 {
-			animeSlug = slug[:idx]
-		}
-
-		// Convert Anime Indo response to Sankavollerei format
-		return ctx.JSON(fiber.Map{
-			"data": fiber.Map{
-				"title":               slug,
-				"defaultStreamingUrl": animeIndoStreams["default"],
-				"stream_link":         animeIndoStreams,
-				"url":                 animeIndoStreams["default"],
-				"animeId":             animeSlug, // Clean anime slug without episode
-				"server": fiber.Map{
-					"qualities": []fiber.Map{},
-				},
-				"downloadUrl": "",
-			},
-		})
-	}
-
-	// Both failed
-	return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	result, err := c.StreamResolver.Resolve(slug)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error(), "sources": result.Sources})
+	}
+
+	return ctx.JSON(fiber.Map{"data": result})
 }
 
 // GetLatest returns latest episodes from Sankavollerei (Legacy)
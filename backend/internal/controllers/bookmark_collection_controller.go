@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetCollections lists the user's collections with item counts
+func (c *BookmarkController) GetCollections(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	collections, err := c.Service.ListCollections(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": collections})
+}
+
+func (c *BookmarkController) CreateCollection(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.CollectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	collection, err := c.Service.CreateCollection(userID, req)
+	if err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.Status(201).JSON(fiber.Map{"data": collection})
+}
+
+// UpdateCollection renames a collection / changes its description
+func (c *BookmarkController) UpdateCollection(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.CollectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	collection, err := c.Service.UpdateCollection(userID, id, req)
+	if err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": collection})
+}
+
+// DeleteCollection removes a collection but keeps its bookmarks
+func (c *BookmarkController) DeleteCollection(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	if err := c.Service.DeleteCollection(userID, id); err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Collection removed"})
+}
+
+// AddToCollection adds a bookmark ({"bookmark_id": n}) to a collection
+func (c *BookmarkController) AddToCollection(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.CollectionItemRequest
+	if err := ctx.BodyParser(&req); err != nil || req.BookmarkID == 0 {
+		return ctx.Status(400).JSON(fiber.Map{"error": "bookmark_id is required"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	if err := c.Service.AddToCollection(userID, id, req.BookmarkID); err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Bookmark added to collection"})
+}
+
+func (c *BookmarkController) RemoveFromCollection(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	bookmarkID, _ := strconv.Atoi(ctx.Params("bookmarkId"))
+	if err := c.Service.RemoveFromCollection(userID, id, bookmarkID); err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Bookmark removed from collection"})
+}
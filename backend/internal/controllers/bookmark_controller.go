@@ -1,8 +1,10 @@
 package controllers
 
 import (
+	"anime-tanyaayomi/internal/middleware"
 	"anime-tanyaayomi/internal/models"
 	"anime-tanyaayomi/internal/services"
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,17 +20,26 @@ func NewBookmarkController() *BookmarkController {
 	}
 }
 
-// Helper to get UserID (In real app, extract from JWT)
+// Helper to get UserID set by middleware.RequireAuth from the access token
 func getUserID(ctx *fiber.Ctx) int {
-	// For demo: get user_id from header or query param.
-	// WARNING: Insecure. Access control should be implemented properly.
-	uid, _ := strconv.Atoi(ctx.Get("X-User-ID"))
-	if uid == 0 {
-		return 0
+	return middleware.UserID(ctx)
+}
+
+// bookmarkError maps service errors to 400/404/409/500
+func bookmarkError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidBookmark), errors.Is(err, services.ErrInvalidCollection):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrBookmarkNotFound), errors.Is(err, services.ErrCollectionNotFound):
+		return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrCollectionExists):
+		return ctx.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
-	return uid
 }
 
+// AddBookmark creates a bookmark (status defaults to planning; score, notes, tags optional)
 func (c *BookmarkController) AddBookmark(ctx *fiber.Ctx) error {
 	userID := getUserID(ctx)
 	if userID == 0 {
@@ -42,24 +53,102 @@ func (c *BookmarkController) AddBookmark(ctx *fiber.Ctx) error {
 	b.UserID = userID
 
 	if err := c.Service.AddBookmark(b); err != nil {
-		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return bookmarkError(ctx, err)
 	}
 
 	return ctx.Status(201).JSON(fiber.Map{"message": "Bookmark added"})
 }
 
+// GetBookmarks lists bookmarks.
+// Query: type, status, tag, collection (id), q (title), sort=created|updated|title|score,
+// order=asc|desc (default desc), page (default 1), limit (default 50, max 200)
 func (c *BookmarkController) GetBookmarks(ctx *fiber.Ctx) error {
 	userID := getUserID(ctx)
 	if userID == 0 {
 		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
-	bookmarks, err := c.Service.GetBookmarks(userID)
+	order := ctx.Query("order", "desc")
+	if order != "asc" && order != "desc" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "order must be 'asc' or 'desc'"})
+	}
+
+	page, err := c.Service.ListBookmarks(userID, models.BookmarkFilter{
+		Type:         ctx.Query("type"),
+		Status:       ctx.Query("status"),
+		Tag:          ctx.Query("tag"),
+		CollectionID: ctx.QueryInt("collection"),
+		Query:        ctx.Query("q"),
+		Sort:         ctx.Query("sort"),
+		Desc:         order == "desc",
+		Page:         ctx.QueryInt("page", 1),
+		Limit:        ctx.QueryInt("limit", 50),
+	})
+	if err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": page.Items,
+		"pagination": fiber.Map{
+			"page":        page.Page,
+			"limit":       page.Limit,
+			"total":       page.Total,
+			"total_pages": page.TotalPages,
+		},
+	})
+}
+
+// GetBookmark returns one bookmark with its collections
+func (c *BookmarkController) GetBookmark(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	bookmark, err := c.Service.GetBookmark(userID, id)
+	if err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": bookmark})
+}
+
+// UpdateBookmark changes status, score (0 clears), notes and/or tags
+func (c *BookmarkController) UpdateBookmark(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.BookmarkUpdate
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	bookmark, err := c.Service.UpdateBookmark(userID, id, req)
+	if err != nil {
+		return bookmarkError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": bookmark})
+}
+
+// GetTags lists the user's tags with usage counts
+func (c *BookmarkController) GetTags(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	tags, err := c.Service.GetTags(userID)
 	if err != nil {
 		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return ctx.JSON(fiber.Map{"data": bookmarks})
+	return ctx.JSON(fiber.Map{"data": tags})
 }
 
 func (c *BookmarkController) RemoveBookmark(ctx *fiber.Ctx) error {
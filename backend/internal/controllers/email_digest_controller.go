@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+	"html/template"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type EmailDigestController struct {
+	Service *services.EmailDigestService
+}
+
+func NewEmailDigestController() *EmailDigestController {
+	return &EmailDigestController{
+		Service: services.GetEmailDigestService(),
+	}
+}
+
+func (c *EmailDigestController) GetPreferences(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	prefs, err := c.Service.GetPreferences(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": prefs, "enabled": c.Service.Mailer != nil})
+}
+
+// UpdatePreferences sets {"email": ..., "frequency": "off|daily|weekly"}. A new address
+// gets a confirmation email; the frequency stays pending until it's confirmed.
+func (c *EmailDigestController) UpdatePreferences(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.EmailDigestRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	prefs, err := c.Service.SetPreferences(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDigestPreferences) {
+			return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": prefs})
+}
+
+// SendTest emails a digest of the last week right away
+func (c *EmailDigestController) SendTest(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	err := c.Service.SendPreview(userID)
+	switch {
+	case errors.Is(err, services.ErrMailerNotConfigured):
+		return ctx.Status(503).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDigestPreferences), errors.Is(err, services.ErrDigestEmpty):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailThrottled):
+		return ctx.Status(429).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return ctx.Status(502).JSON(fiber.Map{"error": "Failed to send email: " + err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Test digest sent"})
+}
+
+// ResendVerification emails the confirmation link for the saved address again
+func (c *EmailDigestController) ResendVerification(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	err := c.Service.SendVerification(userID)
+	switch {
+	case errors.Is(err, services.ErrMailerNotConfigured):
+		return ctx.Status(503).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDigestPreferences):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEmailThrottled):
+		return ctx.Status(429).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return ctx.Status(502).JSON(fiber.Map{"error": "Failed to send email: " + err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Confirmation email sent"})
+}
+
+var verifyPage = template.Must(template.New("verify").Parse(`<!DOCTYPE html>
+<html lang="id"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Confirm email</title></head>
+<body style="font-family:Arial,Helvetica,sans-serif;max-width:480px;margin:48px auto;padding:0 16px;">
+{{if .Done}}<p>Your email address is confirmed. Release digests will be sent to it.</p>
+{{else if .Error}}<p>{{.Error}}</p>
+{{else}}<p>Receive release digest emails at this address?</p>
+<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">Confirm</button></form>
+{{end}}</body></html>`))
+
+// VerifyPage asks for confirmation, like UnsubscribePage: a prefetching link scanner
+// must not confirm an address on the owner's behalf
+func (c *EmailDigestController) VerifyPage(ctx *fiber.Ctx) error {
+	return renderPage(ctx, verifyPage, 200, fiber.Map{"Token": ctx.Query("token")})
+}
+
+// Verify handles the confirmation form
+func (c *EmailDigestController) Verify(ctx *fiber.Ctx) error {
+	token := ctx.Query("token")
+	if token == "" {
+		token = ctx.FormValue("token")
+	}
+
+	if err := c.Service.Verify(token); err != nil {
+		if errors.Is(err, services.ErrInvalidVerificationToken) {
+			return renderPage(ctx, verifyPage, 400, fiber.Map{"Error": "This confirmation link is invalid or has expired."})
+		}
+		return renderPage(ctx, verifyPage, 500, fiber.Map{"Error": "Something went wrong, please try again later."})
+	}
+
+	return renderPage(ctx, verifyPage, 200, fiber.Map{"Done": true})
+}
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="id"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Unsubscribe</title></head>
+<body style="font-family:Arial,Helvetica,sans-serif;max-width:480px;margin:48px auto;padding:0 16px;">
+{{if .Done}}<p>You will no longer receive digest emails. You can turn them back on in your settings.</p>
+{{else if .Error}}<p>{{.Error}}</p>
+{{else}}<p>Stop receiving release digest emails?</p>
+<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">Unsubscribe</button></form>
+{{end}}</body></html>`))
+
+// UnsubscribePage asks for confirmation; link scanners that prefetch GET must not unsubscribe anyone
+func (c *EmailDigestController) UnsubscribePage(ctx *fiber.Ctx) error {
+	return renderPage(ctx, unsubscribePage, 200, fiber.Map{"Token": ctx.Query("token")})
+}
+
+// Unsubscribe handles the confirmation form and RFC 8058 one-click POSTs from mail clients
+func (c *EmailDigestController) Unsubscribe(ctx *fiber.Ctx) error {
+	token := ctx.Query("token")
+	if token == "" {
+		token = ctx.FormValue("token")
+	}
+
+	if err := c.Service.Unsubscribe(token); err != nil {
+		if errors.Is(err, services.ErrInvalidUnsubscribeToken) {
+			return renderPage(ctx, unsubscribePage, 400, fiber.Map{"Error": "This unsubscribe link is invalid."})
+		}
+		return renderPage(ctx, unsubscribePage, 500, fiber.Map{"Error": "Something went wrong, please try again later."})
+	}
+
+	return renderPage(ctx, unsubscribePage, 200, fiber.Map{"Done": true})
+}
+
+func renderPage(ctx *fiber.Ctx, page *template.Template, status int, data fiber.Map) error {
+	ctx.Type("html", "utf-8")
+	ctx.Status(status)
+	return page.Execute(ctx.Response().BodyWriter(), data)
+}
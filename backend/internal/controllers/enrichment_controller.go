@@ -0,0 +1,292 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EnrichmentController serves the admin enrichment API: the background queue and
+// reviewing/overriding enriched_metadata rows
+type EnrichmentController struct {
+	Queue    *services.EnrichmentQueue
+	Metadata *services.EnrichmentAdminService
+}
+
+func NewEnrichmentController() *EnrichmentController {
+	return &EnrichmentController{
+		Queue:    services.GetEnrichmentQueue(),
+		Metadata: services.NewEnrichmentAdminService(),
+	}
+}
+
+// enrichedMetadataError maps service errors to 400/404/409/500
+func enrichedMetadataError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidEnrichedMetadata):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEnrichedMetadataNotFound), errors.Is(err, services.ErrRevisionNotFound):
+		return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrEnrichedMetadataExists):
+		return ctx.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+// queryBool reads an optional true/false query parameter
+func queryBool(ctx *fiber.Ctx, key string) (*bool, error) {
+	value := ctx.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", key)
+	}
+	return &parsed, nil
+}
+
+// GetQueue returns job counts per status and the LLM rate/budget usage
+func (c *EnrichmentController) GetQueue(ctx *fiber.Ctx) error {
+	stats, err := c.Queue.Stats()
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.JSON(fiber.Map{"data": stats})
+}
+
+// GetJobs lists jobs. Query: status (pending/running/done/rejected/failed), page, limit
+func (c *EnrichmentController) GetJobs(ctx *fiber.Ctx) error {
+	status := ctx.Query("status")
+	switch status {
+	case "", "pending", "running", "done", "rejected", "failed":
+	default:
+		return ctx.Status(400).JSON(fiber.Map{"error": "status must be pending, running, done, rejected or failed"})
+	}
+
+	page, limit := pageParams(ctx)
+	jobs, total, err := c.Queue.Jobs(status, page, limit)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": jobs,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// RetryJob queues a rejected or failed job again
+func (c *EnrichmentController) RetryJob(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid job id"})
+	}
+
+	if err := c.Queue.Retry(id); err != nil {
+		if errors.Is(err, services.ErrEnrichmentJobNotFound) {
+			return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Job queued"})
+}
+
+// GetMetadata lists enriched_metadata rows. Query: q (title), media_type, source,
+// needs_review, locked, page, limit
+func (c *EnrichmentController) GetMetadata(ctx *fiber.Ctx) error {
+	needsReview, err := queryBool(ctx, "needs_review")
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	locked, err := queryBool(ctx, "locked")
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	page, limit := pageParams(ctx)
+	rows, total, err := c.Metadata.List(models.EnrichedMetadataFilter{
+		Query:       ctx.Query("q"),
+		MediaType:   ctx.Query("media_type"),
+		Source:      ctx.Query("source"),
+		NeedsReview: needsReview,
+		Locked:      locked,
+		Page:        page,
+		Limit:       limit,
+	})
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": rows,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// CreateMetadata adds a manual (locked) row
+func (c *EnrichmentController) CreateMetadata(ctx *fiber.Ctx) error {
+	var req models.EnrichedMetadataRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	data, err := c.Metadata.Create(getUserID(ctx), req)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.Status(201).JSON(fiber.Map{"data": data})
+}
+
+func (c *EnrichmentController) GetMetadataRow(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+	}
+
+	data, err := c.Metadata.Get(id)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": data})
+}
+
+// UpdateMetadata changes the fields present in the body; edits make the row manual and locked
+func (c *EnrichmentController) UpdateMetadata(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+	}
+
+	var req models.EnrichedMetadataRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	data, err := c.Metadata.Update(getUserID(ctx), id, req)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": data})
+}
+
+// LockMetadata (POST) and UnlockMetadata (DELETE) on /:id/lock
+func (c *EnrichmentController) LockMetadata(ctx *fiber.Ctx) error {
+	return c.setLocked(ctx, true)
+}
+
+func (c *EnrichmentController) UnlockMetadata(ctx *fiber.Ctx) error {
+	return c.setLocked(ctx, false)
+}
+
+func (c *EnrichmentController) setLocked(ctx *fiber.Ctx, locked bool) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+	}
+
+	data, err := c.Metadata.SetLocked(getUserID(ctx), id, locked)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": data})
+}
+
+func (c *EnrichmentController) DeleteMetadata(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+	}
+
+	if err := c.Metadata.Delete(getUserID(ctx), id); err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Metadata deleted"})
+}
+
+// GetRowRevisions is the history of a row's title. Query: page, limit
+func (c *EnrichmentController) GetRowRevisions(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid id"})
+	}
+
+	page, limit := pageParams(ctx)
+	revisions, total, err := c.Metadata.RowRevisions(id, page, limit)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+	return revisionPage(ctx, revisions, total, page, limit)
+}
+
+// GetRevisions is the history of a title, including deleted rows. Query: title,
+// media_type, page, limit
+func (c *EnrichmentController) GetRevisions(ctx *fiber.Ctx) error {
+	page, limit := pageParams(ctx)
+	revisions, total, err := c.Metadata.Revisions(ctx.Query("title"), ctx.Query("media_type"), page, limit)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+	return revisionPage(ctx, revisions, total, page, limit)
+}
+
+func revisionPage(ctx *fiber.Ctx, revisions []models.EnrichedMetadataRevision, total int, page int, limit int) error {
+	return ctx.JSON(fiber.Map{
+		"data": revisions,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+func (c *EnrichmentController) GetRevision(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("revisionId"), 10, 64)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid revision id"})
+	}
+
+	revision, err := c.Metadata.GetRevision(id)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": revision})
+}
+
+// RestoreRevision puts the row back the way it was in a revision (a new revision itself)
+func (c *EnrichmentController) RestoreRevision(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseInt(ctx.Params("revisionId"), 10, 64)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid revision id"})
+	}
+
+	data, err := c.Metadata.Restore(getUserID(ctx), id)
+	if err != nil {
+		return enrichedMetadataError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": data})
+}
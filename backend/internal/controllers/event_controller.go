@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const eventHeartbeat = 25 * time.Second
+
+type EventController struct {
+	Broker *services.EventBroker
+}
+
+func NewEventController() *EventController {
+	return &EventController{
+		Broker: services.GetEventBroker(),
+	}
+}
+
+// Stream is the Server-Sent Events stream of episode.released / chapter.released events.
+// ?bookmarked=true limits it to the user's bookmarks (token via header or ?access_token=).
+// Reconnecting clients get what they missed after Last-Event-ID (or ?lastEventId=).
+func (c *EventController) Stream(ctx *fiber.Ctx) error {
+	var filter *services.BookmarkEventFilter
+	if ctx.QueryBool("bookmarked") {
+		userID := getUserID(ctx)
+		if userID == 0 {
+			return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+		}
+		filter = &services.BookmarkEventFilter{UserID: userID}
+	}
+
+	lastEventID := ctx.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("lastEventId")
+	}
+	var lastID int64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || parsed < 0 {
+			return ctx.Status(400).JSON(fiber.Map{"error": "Invalid Last-Event-ID"})
+		}
+		lastID = parsed
+	} else {
+		latest, err := services.LatestReleaseEventID()
+		if err != nil {
+			return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		lastID = latest
+	}
+
+	// Subscribe before replaying so nothing falls between the replay and the live stream
+	events, unsubscribe := c.Broker.Subscribe()
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no") // nginx must not buffer the stream
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		fmt.Fprint(w, "retry: 5000\n\n")
+		if w.Flush() != nil {
+			return
+		}
+
+		send := func(event models.ReleaseEvent) error {
+			if event.ID <= lastID {
+				return nil // Already replayed
+			}
+			lastID = event.ID
+			if filter != nil {
+				if ok, err := filter.Match(event); err != nil || !ok {
+					return nil
+				}
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return nil
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name(), data)
+			return w.Flush()
+		}
+
+		for {
+			missed, err := services.ReleaseEventsSince(lastID, services.MaxEventReplay)
+			if err != nil {
+				fmt.Printf("[Events] ⚠️  Replay failed: %v\n", err)
+				return
+			}
+			for _, event := range missed {
+				if send(event) != nil {
+					return
+				}
+			}
+			if len(missed) < services.MaxEventReplay {
+				break
+			}
+		}
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return // Dropped as too slow; the client reconnects and replays
+				}
+				if send(event) != nil {
+					return
+				}
+			case <-heartbeat.C:
+				// Comment line: keeps proxies from closing the connection and detects gone clients
+				fmt.Fprint(w, ": ping\n\n")
+				if w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+	return nil
+}
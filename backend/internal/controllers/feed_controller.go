@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"encoding/xml"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FeedController struct {
+	Service *services.FeedService
+}
+
+func NewFeedController() *FeedController {
+	return &FeedController{
+		Service: services.NewFeedService(),
+	}
+}
+
+// sendFeed writes an Atom document; cacheControl differs for public and private feeds
+func (c *FeedController) sendFeed(ctx *fiber.Ctx, feed *models.AtomFeed, err error, cacheControl string) error {
+	if err != nil {
+		return ctx.Status(502).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	ctx.Set("Content-Type", "application/atom+xml; charset=utf-8")
+	ctx.Set("Cache-Control", cacheControl)
+	return ctx.Send(append([]byte(xml.Header), body...))
+}
+
+func (c *FeedController) LatestAnime(ctx *fiber.Ctx) error {
+	feed, err := c.Service.LatestAnime()
+	return c.sendFeed(ctx, feed, err, "public, max-age=300")
+}
+
+func (c *FeedController) LatestManga(ctx *fiber.Ctx) error {
+	feed, err := c.Service.LatestManga()
+	return c.sendFeed(ctx, feed, err, "public, max-age=300")
+}
+
+func (c *FeedController) AnimeGenre(ctx *fiber.Ctx) error {
+	feed, err := c.Service.AnimeGenre(ctx.Params("slug"))
+	return c.sendFeed(ctx, feed, err, "public, max-age=1800")
+}
+
+func (c *FeedController) MangaGenre(ctx *fiber.Ctx) error {
+	feed, err := c.Service.MangaGenre(ctx.Params("slug"))
+	return c.sendFeed(ctx, feed, err, "public, max-age=1800")
+}
+
+// UserFeed serves a private feed; the token in the URL is the only credential
+func (c *FeedController) UserFeed(ctx *fiber.Ctx) error {
+	feed, err := c.Service.UserFeed(ctx.Params("token"))
+	if errors.Is(err, services.ErrInvalidFeedToken) {
+		return ctx.Status(404).JSON(fiber.Map{"error": "Feed not found"})
+	}
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.sendFeed(ctx, feed, nil, "private, max-age=300")
+}
+
+// GetFeedToken tells whether the user has a private feed (the URL is only shown on creation)
+func (c *FeedController) GetFeedToken(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	token, err := c.Service.GetFeedToken(userID)
+	if err != nil {
+		if errors.Is(err, services.ErrFeedTokenNotFound) {
+			return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": token})
+}
+
+// RotateFeedToken creates the private feed URL, invalidating the previous one
+func (c *FeedController) RotateFeedToken(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	token, err := c.Service.RotateFeedToken(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(201).JSON(fiber.Map{"data": token})
+}
+
+func (c *FeedController) RevokeFeedToken(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.RevokeFeedToken(userID); err != nil {
+		if errors.Is(err, services.ErrFeedTokenNotFound) {
+			return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Private feed removed"})
+}
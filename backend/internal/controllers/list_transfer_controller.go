@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/services"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ListTransferController struct {
+	Service *services.ListTransferService
+}
+
+func NewListTransferController(providers *services.ProviderRegistry) *ListTransferController {
+	return &ListTransferController{
+		Service: services.NewListTransferService(providers),
+	}
+}
+
+// Import reads a MAL XML (.xml or .xml.gz) or AniList JSON list, either as the raw body
+// or as a multipart "file" field, and queues it; the matched titles are bookmarked in the
+// background. Poll GET /me/imports/:id for progress and the report. AniList exports should
+// include user { mediaListOptions { scoreFormat } }, otherwise scores are read as 0-10.
+// Query: format=mal|anilist (auto-detected when empty), dry_run=true to only get the report.
+func (c *ListTransferController) Import(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	format := ctx.Query("format")
+	if format != "" && format != services.ListFormatMAL && format != services.ListFormatAniList {
+		return ctx.Status(400).JSON(fiber.Map{"error": "format must be 'mal' or 'anilist'"})
+	}
+
+	data, err := uploadedFile(ctx)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	entries, detected, err := services.ParseListExport(data, format)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	job, err := c.Service.Import(userID, entries, detected, ctx.QueryBool("dry_run"))
+	if err != nil {
+		return importError(ctx, err)
+	}
+
+	return ctx.Status(202).JSON(fiber.Map{"data": job})
+}
+
+// GetImports lists the user's imports with their progress
+func (c *ListTransferController) GetImports(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	page, limit := pageParams(ctx)
+	jobs, total, err := services.GetImportQueue().Jobs(userID, page, limit)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": jobs,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// GetImport returns one import's progress, and its report once it is done
+func (c *ListTransferController) GetImport(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid import id"})
+	}
+
+	job, err := services.GetImportQueue().Job(userID, id)
+	if err != nil {
+		return importError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": job})
+}
+
+// importError maps import errors to 400/404/409/500
+func importError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrTooManyEntries), errors.Is(err, services.ErrNothingToImport):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrImportJobNotFound):
+		return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrImportInProgress):
+		return ctx.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// ImportTachiyomi reads a Tachiyomi/Mihon backup (.tachibk / .proto.gz), as the raw body or
+// a multipart "file" field, and queues it; the library manga are bookmarked and their read
+// chapters marked in the background (see GetImport).
+// Query: dry_run=true to only get the report.
+func (c *ListTransferController) ImportTachiyomi(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	data, err := uploadedFile(ctx)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	backup, err := services.ParseTachiyomiBackup(data)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	job, err := c.Service.ImportTachiyomi(userID, backup, ctx.QueryBool("dry_run"))
+	if err != nil {
+		return importError(ctx, err)
+	}
+
+	return ctx.Status(202).JSON(fiber.Map{"data": job})
+}
+
+// Export downloads bookmarks and watch/read progress.
+// Query: format=anilist (default, anime + manga) or format=mal with type=anime|manga.
+func (c *ListTransferController) Export(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	format := ctx.Query("format", services.ListFormatAniList)
+	mediaType := ctx.Query("type", "anime")
+	if mediaType != "anime" && mediaType != "manga" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "type must be 'anime' or 'manga'"})
+	}
+
+	entries, err := c.Service.Export(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var buf bytes.Buffer
+	date := time.Now().Format("2006-01-02")
+
+	switch format {
+	case services.ListFormatMAL:
+		err = services.WriteMALExport(&buf, entries, mediaType)
+		ctx.Set("Content-Type", "application/xml; charset=utf-8")
+		ctx.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%slist_%s.xml"`, mediaType, date))
+	case services.ListFormatAniList:
+		err = services.WriteAniListExport(&buf, entries)
+		ctx.Set("Content-Type", "application/json; charset=utf-8")
+		ctx.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="anilist_%s.json"`, date))
+	default:
+		return ctx.Status(400).JSON(fiber.Map{"error": "format must be 'mal' or 'anilist'"})
+	}
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Send(buf.Bytes())
+}
+
+// uploadedFile returns the multipart "file" field if present, otherwise the raw body
+func uploadedFile(ctx *fiber.Ctx) ([]byte, error) {
+	data := ctx.Body()
+	if fileHeader, err := ctx.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, errors.New("Invalid file")
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return nil, errors.New("Invalid file")
+		}
+	}
+	if len(data) == 0 {
+		return nil, errors.New("Empty import file")
+	}
+	return data, nil
+}
@@ -1,18 +1,27 @@
 package controllers
 
 import (
+	"anime-tanyaayomi/internal/middleware"
 	"anime-tanyaayomi/internal/services"
+	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type MangaController struct {
-	Service *services.SankavollereiService
+	Service   *services.SankavollereiService
+	Providers *services.ProviderRegistry
+	Progress  *services.ReadingProgressService
 }
 
-func NewMangaController() *MangaController {
+func NewMangaController(providers *services.ProviderRegistry) *MangaController {
+	sankavollerei := services.NewSankavollereiService("")
+
 	return &MangaController{
-		Service: services.NewSankavollereiService(""),
+		Service:   sankavollerei,
+		Providers: providers,
+		// Read state for authenticated GetDetail requests
+		Progress: services.NewReadingProgressService(sankavollerei),
 	}
 }
 
@@ -88,6 +97,15 @@ func (c *MangaController) GetDetail(ctx *fiber.Ctx) error {
 		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// Logged in (via middleware.OptionalAuth): add read state per chapter and unread count
+	if userID := middleware.UserID(ctx); userID != 0 {
+		annotated, err := c.Progress.AnnotateDetail(userID, slug, result)
+		if err == nil {
+			return ctx.JSON(annotated)
+		}
+		fmt.Printf("[Manga] ⚠️  Failed to load read state for %s: %v\n", slug, err)
+	}
+
 	return ctx.JSON(result)
 }
 
@@ -103,5 +121,86 @@ func (c *MangaController) GetChapter(ctx *fiber.Ctx) error {
 		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	policy := services.GetImageProxyPolicy()
+	result.ProxyImages = make([]string, 0, len(result.Images))
+	for _, img := range result.Images {
+		result.ProxyImages = append(result.ProxyImages, policy.ProxyURL(img))
+	}
+
 	return ctx.JSON(result)
 }
+
+// ========== Provider Registry Endpoints ==========
+
+// unknownProvider responds 404 with the list of registered manga providers
+func (c *MangaController) unknownProvider(ctx *fiber.Ctx, err error) error {
+	names, _ := c.Providers.MangaNames()
+	return ctx.Status(404).JSON(fiber.Map{"error": err.Error(), "providers": names})
+}
+
+// SearchByProvider searches manga using the named provider
+func (c *MangaController) SearchByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Manga(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	query := ctx.Query("q")
+	if query == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Query param 'q' is required"})
+	}
+
+	results, err := provider.SearchManga(query)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": fiber.Map{
+			"mangaList": results,
+		},
+	})
+}
+
+// GetDetailByProvider fetches manga detail from the named provider
+func (c *MangaController) GetDetailByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Manga(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	slug := ctx.Params("slug")
+	if slug == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Slug is required"})
+	}
+
+	detail, err := provider.GetMangaDetail(slug)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(detail)
+}
+
+// GetChapterByProvider fetches chapter images from the named provider
+func (c *MangaController) GetChapterByProvider(ctx *fiber.Ctx) error {
+	provider, err := c.Providers.Manga(ctx.Params("provider"))
+	if err != nil {
+		return c.unknownProvider(ctx, err)
+	}
+
+	chapterId := ctx.Params("chapterId")
+	if chapterId == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Chapter ID is required"})
+	}
+
+	images, err := provider.GetChapterImages(chapterId)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"chapterId": chapterId,
+		"images":    images,
+	})
+}
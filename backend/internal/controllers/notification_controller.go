@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationController struct {
+	Service *services.NotificationService
+}
+
+func NewNotificationController() *NotificationController {
+	return &NotificationController{
+		Service: services.NewNotificationService(),
+	}
+}
+
+// GetNotifications lists notifications, newest first.
+// Query: unread=true for unread only, page (default 1), limit (default 30, max 100)
+func (c *NotificationController) GetNotifications(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit", 30)
+	if limit < 1 || limit > 100 {
+		limit = 30
+	}
+
+	notifications, total, err := c.Service.List(userID, ctx.QueryBool("unread"), page, limit)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": notifications,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// GetUnreadCount returns the number of unread notifications (for the badge)
+func (c *NotificationController) GetUnreadCount(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	count, err := c.Service.UnreadCount(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": fiber.Map{"unread": count}})
+}
+
+func (c *NotificationController) MarkRead(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	if err := c.Service.MarkRead(userID, id); err != nil {
+		if errors.Is(err, services.ErrNotificationNotFound) {
+			return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Notification marked as read"})
+}
+
+func (c *NotificationController) MarkAllRead(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	count, err := c.Service.MarkAllRead(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Notifications marked as read", "count": count})
+}
@@ -1,113 +1,326 @@
 package controllers
 
 import (
+	"anime-tanyaayomi/internal/services"
 	"bufio"
+	"context"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
-	"os"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Browsers/CDNs may keep proxied images for a week; URLs are content-stable
+const imageCacheControl = "public, max-age=604800"
+
+// HLS segments never change, playlists may (live streams)
+const hlsSegmentCacheControl = "public, max-age=86400"
+
 type ProxyController struct {
-	Client *http.Client
+	Client   *http.Client
+	Policy   *services.ImageProxyPolicy
+	Cache    *services.ImageDiskCache
+	Profiles *services.HeaderProfileTable
+	HLS      *services.HLSProxy
 }
 
 func NewProxyController() *ProxyController {
-	// Create a client that respects HTTP_PROXY environment variable
-	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-	}
-
-	// Helper to check if a proxy env is set (optional, for logging)
-	if os.Getenv("HTTP_PROXY") != "" {
-		// fmt.Println("ProxyController: Using HTTP_PROXY")
-	}
+	policy := services.GetImageProxyPolicy()
 
+	// The client respects HTTP_PROXY, but direct connections and redirects
+	// are only allowed to public addresses on allowlisted hosts
 	return &ProxyController{
-		Client: &http.Client{
-			Transport: transport,
-			Timeout:   30 * time.Second,
-		},
+		Client:   policy.NewHTTPClient(30 * time.Second),
+		Policy:   policy,
+		Cache:    services.GetImageDiskCache(),
+		Profiles: services.GetHeaderProfileTable(),
+		HLS:      services.GetHLSProxy(),
 	}
 }
 
-// GetImage proxies an image URL through the backend
+// GetImage proxies an image URL through the backend.
+// Optional w, h (max box, aspect kept) and format (jpeg|png) return a resized copy.
+// Originals and variants are kept in the on-disk cache.
 func (c *ProxyController) GetImage(ctx *fiber.Ctx) error {
 	imageURL := ctx.Query("url")
 	if imageURL == "" {
 		return ctx.Status(400).SendString("Missing url parameter")
 	}
 
+	// Signature covers the url exactly as our backend emitted it
+	if !c.Policy.VerifySignature(imageURL, ctx.Query("sig")) {
+		return ctx.Status(403).SendString(services.ErrInvalidSignature.Error())
+	}
+
 	// Decode URL if it's double encoded or just to be safe
 	if decoded, err := url.QueryUnescape(imageURL); err == nil {
 		imageURL = decoded
 	}
 
-	// Create request
-	req, err := http.NewRequest("GET", imageURL, nil)
+	target, err := url.Parse(imageURL)
 	if err != nil {
-		return ctx.Status(500).SendString("Failed to create request: " + err.Error())
-	}
-
-	// Set headers to mimic a real browser request from the source site
-	// Most of these images are from Komikindo scrapers, so acting like Komikindo usually works
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-	req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8")
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9,id;q=0.8")
-	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("Pragma", "no-cache")
-
-	// CRITICAL: Set Referer to the likely origin site (Komikindo)
-	// Some images might block unknown referers, but allow their own site or empty.
-	// We'll try masking as Komikindo first.
-	req.Header.Set("Referer", "https://komikindo.ch/")
-	req.Header.Set("Origin", "https://komikindo.ch")
-
-	// Add Fetch Metadata headers
-	req.Header.Set("Sec-Fetch-Dest", "image")
-	req.Header.Set("Sec-Fetch-Mode", "no-cors")
-	req.Header.Set("Sec-Fetch-Site", "cross-site")
-
-	// Execute request
-	resp, err := c.Client.Do(req)
+		return ctx.Status(400).SendString("Invalid url parameter")
+	}
+	if err := c.Policy.CheckURL(target); err != nil {
+		return ctx.Status(403).SendString("URL not allowed: " + err.Error())
+	}
+
+	transform, err := services.NewImageTransform(ctx.QueryInt("w"), ctx.QueryInt("h"), strings.ToLower(ctx.Query("format")))
 	if err != nil {
-		return ctx.Status(502).SendString("Failed to fetch image: " + err.Error())
+		return ctx.Status(400).SendString(err.Error())
+	}
+
+	// 1. Requested variant already on disk
+	variantKey := services.ImageCacheKey(target.String(), transform)
+	if cached, ok := c.Cache.Get(variantKey); ok {
+		return c.sendImage(ctx, cached)
+	}
+
+	// 2. Original from disk or upstream
+	originalKey := services.ImageCacheKey(target.String(), services.ImageTransform{})
+	original, ok := c.Cache.Get(originalKey)
+	if !ok {
+		if err := services.ResolvePublicHost(ctx.Context(), target.Hostname()); err != nil {
+			return ctx.Status(403).SendString("URL not allowed: " + err.Error())
+		}
+
+		body, contentType, status, err := c.fetchImage(target)
+		if err != nil {
+			return ctx.Status(status).SendString(err.Error())
+		}
+		original = c.Cache.Put(originalKey, body, contentType)
+	}
+
+	if transform.IsZero() {
+		return c.sendImage(ctx, original)
+	}
+
+	// 3. Resize / convert and store the variant
+	body, contentType, err := services.TransformImage(original.Body, transform)
+	if err != nil {
+		if transform.Format != "" {
+			return ctx.Status(415).SendString(err.Error())
+		}
+		// Undecodable source (e.g. webp): serve the original rather than failing the page
+		fmt.Printf("[ImageProxy] ⚠️  Resize skipped for %s: %v\n", target.Host, err)
+		return c.sendImage(ctx, original)
 	}
-	// MOVED: defer resp.Body.Close() - Do not close here for streaming
+
+	return c.sendImage(ctx, c.Cache.Put(variantKey, body, contentType))
+}
+
+// fetchImage downloads and validates an upstream image. The int is the HTTP status to use on error.
+// Header profiles for the host are tried in order; a 403 moves on to the next profile.
+func (c *ProxyController) fetchImage(target *url.URL) ([]byte, string, int, error) {
+	profiles := c.Profiles.ProfilesFor(target.Hostname())
+
+	var resp *http.Response
+	for i, profile := range profiles {
+		// Create request
+		req, err := http.NewRequest("GET", target.String(), nil)
+		if err != nil {
+			return nil, "", 500, fmt.Errorf("Failed to create request: %w", err)
+		}
+
+		// Set headers to mimic a real browser request
+		req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/*,*/*;q=0.8")
+		req.Header.Set("Accept-Language", "en-US,en;q=0.9,id;q=0.8")
+		req.Header.Set("Cache-Control", "no-cache")
+		req.Header.Set("Pragma", "no-cache")
+
+		// Add Fetch Metadata headers
+		req.Header.Set("Sec-Fetch-Dest", "image")
+		req.Header.Set("Sec-Fetch-Mode", "no-cors")
+		req.Header.Set("Sec-Fetch-Site", "cross-site")
+
+		// Referer/Origin/User-Agent (+ extras) for this host
+		profile.Apply(req)
+
+		// Execute request
+		resp, err = c.Client.Do(req)
+		if err != nil {
+			return nil, "", 502, fmt.Errorf("Failed to fetch image: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusForbidden && i < len(profiles)-1 {
+			resp.Body.Close()
+			fmt.Printf("[ImageProxy] 403 from %s with profile '%s', retrying with '%s'\n", target.Host, profile.Name, profiles[i+1].Name)
+			continue
+		}
+		break
+	}
+	if resp == nil {
+		return nil, "", 500, fmt.Errorf("No header profile configured for %s", target.Host)
+	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		resp.Body.Close() // Close manually on error
+		return nil, "", resp.StatusCode, fmt.Errorf("Upstream server returned error")
+	}
+
+	if resp.ContentLength > c.Policy.MaxBytes {
+		return nil, "", 502, fmt.Errorf("Upstream image too large")
+	}
+
+	// Read at most MaxBytes+1 so oversized bodies without Content-Length are caught too
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.Policy.MaxBytes+1))
+	if err != nil {
+		return nil, "", 502, fmt.Errorf("Failed to read image: %w", err)
+	}
+	if int64(len(body)) > c.Policy.MaxBytes {
+		return nil, "", 502, fmt.Errorf("Upstream image too large")
+	}
+
+	contentType, err := imageContentType(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, "", 415, err
+	}
+
+	return body, contentType, 200, nil
+}
+
+// GetHLS proxies a signed HLS playlist, segment or key with the Referer the stream host expects.
+// Playlists are rewritten so every URI goes back through this endpoint; other
+// responses are streamed with Range support.
+func (c *ProxyController) GetHLS(ctx *fiber.Ctx) error {
+	rawURL := ctx.Query("url")
+	referer := ctx.Query("ref")
+	if rawURL == "" {
+		return ctx.Status(400).SendString("Missing url parameter")
+	}
+
+	if !c.HLS.VerifySignature(rawURL, referer, ctx.Query("sig")) {
+		return ctx.Status(403).SendString(services.ErrInvalidSignature.Error())
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return ctx.Status(400).SendString("Invalid url parameter")
+	}
+	if err := services.CheckPublicURL(target); err != nil {
+		return ctx.Status(403).SendString("URL not allowed: " + err.Error())
+	}
+	if err := services.ResolvePublicHost(ctx.Context(), target.Hostname()); err != nil {
+		return ctx.Status(403).SendString("URL not allowed: " + err.Error())
+	}
+
+	// Not ctx.Context(): fasthttp recycles it once the handler returns, while segments are
+	// still being streamed. The fetch is cancelled when the stream is closed (sent, or the
+	// client went away) and the client's own timeout bounds it otherwise.
+	fetchCtx, cancel := context.WithCancel(context.Background())
+	req, err := c.HLS.NewRequest(fetchCtx, target, referer)
+	if err != nil {
+		cancel()
+		return ctx.Status(500).SendString("Failed to create request")
+	}
+	// Playlists are always fetched whole so they can be rewritten
+	if rangeHeader := ctx.Get("Range"); rangeHeader != "" && !strings.EqualFold(path.Ext(target.Path), ".m3u8") {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := c.HLS.Client.Do(req)
+	if err != nil {
+		cancel()
+		return ctx.Status(502).SendString("Failed to fetch stream: " + err.Error())
+	}
+	body := &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		body.Close()
 		return ctx.Status(resp.StatusCode).SendString("Upstream server returned error")
 	}
 
-	// Copy Content-Type header
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "" {
-		// Fallback detection
-		if strings.HasSuffix(imageURL, ".jpg") || strings.HasSuffix(imageURL, ".jpeg") {
-			contentType = "image/jpeg"
-		} else if strings.HasSuffix(imageURL, ".png") {
-			contentType = "image/png"
-		} else if strings.HasSuffix(imageURL, ".webp") {
-			contentType = "image/webp"
+	reader := bufio.NewReader(body)
+	head, _ := reader.Peek(16)
+
+	if resp.StatusCode == http.StatusOK && services.IsHLSPlaylist(target, resp.Header.Get("Content-Type"), head) {
+		defer body.Close()
+
+		playlist, err := io.ReadAll(io.LimitReader(reader, services.MaxHLSPlaylistBytes+1))
+		if err != nil {
+			return ctx.Status(502).SendString("Failed to read playlist")
 		}
+		if len(playlist) > services.MaxHLSPlaylistBytes {
+			return ctx.Status(502).SendString("Upstream playlist too large")
+		}
+
+		// Relative URIs resolve against the final URL after redirects
+		ctx.Set("Content-Type", "application/vnd.apple.mpegurl")
+		ctx.Set("Cache-Control", "no-cache")
+		return ctx.Send(c.HLS.RewritePlaylist(playlist, resp.Request.URL, referer))
+	}
+
+	for _, header := range []string{"Content-Type", "Content-Range", "Accept-Ranges", "Last-Modified", "ETag"} {
+		if value := resp.Header.Get(header); value != "" {
+			ctx.Set(header, value)
+		}
+	}
+	ctx.Set("Cache-Control", hlsSegmentCacheControl)
+	ctx.Set("X-Content-Type-Options", "nosniff")
+
+	// fasthttp closes the stream once it has been sent or the write fails
+	ctx.Status(resp.StatusCode)
+	return ctx.SendStream(struct {
+		io.Reader
+		io.Closer
+	}{reader, body}, int(resp.ContentLength))
+}
+
+// cancelOnClose cancels an upstream request's context when its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// sendImage writes the image with caching headers, answering 304 on a matching If-None-Match
+func (c *ProxyController) sendImage(ctx *fiber.Ctx, img *services.CachedImage) error {
+	ctx.Set("Cache-Control", imageCacheControl)
+	ctx.Set("ETag", img.ETag)
+	ctx.Set("X-Content-Type-Options", "nosniff")
+
+	if etagMatches(ctx.Get("If-None-Match"), img.ETag) {
+		return ctx.SendStatus(304)
 	}
-	ctx.Set("Content-Type", contentType)
 
-	// Stream the body directly to the client
-	// Fiber's Context.Response().BodyWriter() might be needed for streaming,
-	// but SetBodyStreamWriter expects func(*bufio.Writer)
+	ctx.Set("Content-Type", img.ContentType)
+	return ctx.Send(img.Body)
+}
 
-	// Using io.Copy to Fiber's writer
-	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		defer resp.Body.Close() // Close AFTER streaming is done
-		io.Copy(w, resp.Body)
-		w.Flush()
-	})
+// etagMatches handles "*", lists and weak validators in If-None-Match
+func etagMatches(header string, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
 
-	return nil
+// imageContentType accepts raster images only. A missing or generic upstream
+// Content-Type is replaced by sniffing the body. SVG is rejected since it can carry scripts.
+func imageContentType(header string, body []byte) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(header)
+	if mediaType == "" || mediaType == "application/octet-stream" || mediaType == "binary/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+
+	if !strings.HasPrefix(mediaType, "image/") || mediaType == "image/svg+xml" {
+		return "", fmt.Errorf("upstream content is not an image (%s)", mediaType)
+	}
+	return mediaType, nil
 }
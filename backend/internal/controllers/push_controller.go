@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PushController struct {
+	Service *services.PushSubscriptionService // nil when no VAPID keys could be loaded
+}
+
+func NewPushController() *PushController {
+	sender, err := services.GetWebPushSender()
+	if err != nil {
+		return &PushController{}
+	}
+	return &PushController{
+		Service: services.NewPushSubscriptionService(sender),
+	}
+}
+
+func (c *PushController) unavailable(ctx *fiber.Ctx) error {
+	return ctx.Status(503).JSON(fiber.Map{"error": "Push notifications are not configured"})
+}
+
+// GetPublicKey returns the VAPID public key for pushManager.subscribe({applicationServerKey})
+func (c *PushController) GetPublicKey(ctx *fiber.Ctx) error {
+	if c.Service == nil {
+		return c.unavailable(ctx)
+	}
+
+	return ctx.JSON(fiber.Map{"data": fiber.Map{"public_key": c.Service.Sender.Keys.PublicKey()}})
+}
+
+func (c *PushController) GetSubscriptions(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if c.Service == nil {
+		return c.unavailable(ctx)
+	}
+
+	subscriptions, err := c.Service.List(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": subscriptions})
+}
+
+// Subscribe registers this device; the body is the browser's PushSubscription JSON
+func (c *PushController) Subscribe(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if c.Service == nil {
+		return c.unavailable(ctx)
+	}
+
+	var req models.PushSubscriptionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+	if req.DeviceName == "" {
+		req.DeviceName = ctx.Get("User-Agent")
+	}
+
+	subscription, err := c.Service.Subscribe(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPushSubscription) {
+			return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.Status(201).JSON(fiber.Map{"data": subscription})
+}
+
+// Unsubscribe removes a device by :id, or by {"endpoint": ...} in the body
+func (c *PushController) Unsubscribe(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if c.Service == nil {
+		return c.unavailable(ctx)
+	}
+
+	id, _ := strconv.Atoi(ctx.Params("id"))
+	var req models.PushSubscriptionRequest
+	if id == 0 {
+		if err := ctx.BodyParser(&req); err != nil || req.Endpoint == "" {
+			return ctx.Status(400).JSON(fiber.Map{"error": "endpoint is required"})
+		}
+	}
+
+	if err := c.Service.Unsubscribe(userID, id, req.Endpoint); err != nil {
+		if errors.Is(err, services.ErrPushSubscriptionNotFound) {
+			return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Push subscription removed"})
+}
+
+// SendTest pushes a test notification to all of the user's devices
+func (c *PushController) SendTest(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	if c.Service == nil {
+		return c.unavailable(ctx)
+	}
+
+	delivered, err := c.Service.SendTest(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Test notification sent", "count": delivered})
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReadingProgressController struct {
+	Service *services.ReadingProgressService
+}
+
+func NewReadingProgressController() *ReadingProgressController {
+	return &ReadingProgressController{
+		Service: services.NewReadingProgressService(services.NewSankavollereiService("")),
+	}
+}
+
+// readingError maps service errors to 400/404/500
+func readingError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidReadingProgress):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrChapterNotFound):
+		return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// SaveProgress records the chapter and page the user is on
+func (c *ReadingProgressController) SaveProgress(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.ReadingProgressRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	progress, err := c.Service.SaveProgress(userID, req)
+	if err != nil {
+		return readingError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": progress})
+}
+
+// GetRecent lists recently read mangas with their last position (?limit=, default 20)
+func (c *ReadingProgressController) GetRecent(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := ctx.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	list, err := c.Service.GetRecent(userID, limit)
+	if err != nil {
+		return readingError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": list})
+}
+
+// GetReadState returns the last position and read chapters for one manga
+func (c *ReadingProgressController) GetReadState(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	state, err := c.Service.GetReadState(userID, ctx.Params("mangaSlug"))
+	if err != nil {
+		return readingError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": state})
+}
+
+// SetChaptersRead marks a list of chapters read or unread
+func (c *ReadingProgressController) SetChaptersRead(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.ChapterReadRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	if err := c.Service.SetChaptersRead(userID, ctx.Params("mangaSlug"), req.Chapters, req.Read); err != nil {
+		return readingError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Chapters updated", "count": len(req.Chapters)})
+}
+
+// MarkPreviousRead marks a chapter and all chapters before it as read
+func (c *ReadingProgressController) MarkPreviousRead(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.MarkPreviousRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	count, err := c.Service.MarkPreviousRead(userID, ctx.Params("mangaSlug"), req.ChapterSlug)
+	if err != nil {
+		return readingError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Chapters marked as read", "count": count})
+}
@@ -1,19 +1,23 @@
 package controllers
 
 import (
+	"anime-tanyaayomi/internal/middleware"
 	"anime-tanyaayomi/internal/models"
 	"anime-tanyaayomi/internal/services"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserController struct {
-	Service *services.UserService
+	Service     *services.UserService
+	AuthService *services.AuthService
 }
 
 func NewUserController() *UserController {
 	return &UserController{
-		Service: services.NewUserService(),
+		Service:     services.NewUserService(),
+		AuthService: services.NewAuthService(),
 	}
 }
 
@@ -41,6 +45,37 @@ func (c *UserController) Login(ctx *fiber.Ctx) error {
 		return ctx.Status(401).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
 
-	// In a real app, generate JWT here.
-	return ctx.JSON(fiber.Map{"message": "Login successful", "user": user})
+	tokens, err := c.AuthService.IssueTokens(user)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Login successful", "user": user, "tokens": tokens})
+}
+
+// Refresh exchanges a refresh token for a new token pair (the old one is revoked)
+func (c *UserController) Refresh(ctx *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := ctx.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	tokens, err := c.AuthService.Refresh(req.RefreshToken)
+	switch {
+	case errors.Is(err, services.ErrInvalidToken), errors.Is(err, services.ErrTokenExpired), errors.Is(err, services.ErrTokenRevoked):
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized: " + err.Error()})
+	case err != nil:
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"tokens": tokens})
+}
+
+// Logout revokes the session of the current access token
+func (c *UserController) Logout(ctx *fiber.Ctx) error {
+	if err := c.AuthService.RevokeSession(middleware.SessionID(ctx)); err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Logout successful"})
 }
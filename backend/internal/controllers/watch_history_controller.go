@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WatchHistoryController struct {
+	Service *services.WatchHistoryService
+}
+
+func NewWatchHistoryController() *WatchHistoryController {
+	return &WatchHistoryController{
+		Service: services.NewWatchHistoryService(services.NewSankavollereiService("")),
+	}
+}
+
+// SaveProgress records the player position for an episode
+func (c *WatchHistoryController) SaveProgress(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.ProgressRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	progress, err := c.Service.SaveProgress(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidProgress) {
+			return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": progress})
+}
+
+// GetHistory lists recently watched episodes (?limit=, default 50)
+func (c *WatchHistoryController) GetHistory(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	limit := ctx.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	history, err := c.Service.GetHistory(userID, limit)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": history})
+}
+
+// GetAnimeProgress returns progress for each started episode of one anime
+func (c *WatchHistoryController) GetAnimeProgress(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	progress, err := c.Service.GetAnimeProgress(userID, ctx.Params("animeSlug"))
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": progress})
+}
+
+// RemoveProgress deletes one episode from the history
+func (c *WatchHistoryController) RemoveProgress(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	if err := c.Service.RemoveProgress(userID, ctx.Params("episodeSlug")); err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Progress removed"})
+}
+
+// ContinueWatching returns the episode to play next for each series in the history
+func (c *WatchHistoryController) ContinueWatching(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	items, err := c.Service.ContinueWatching(userID)
+	if err != nil {
+		return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return ctx.JSON(fiber.Map{"data": items})
+}
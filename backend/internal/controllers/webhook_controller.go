@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"anime-tanyaayomi/internal/services"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// WebhookController serves /api/me/webhooks, and /api/admin/webhooks when Admin is set
+// (any user's webhooks instead of the caller's own)
+type WebhookController struct {
+	Service *services.WebhookService
+	Admin   bool
+}
+
+func NewWebhookController(admin bool) *WebhookController {
+	return &WebhookController{
+		Service: services.NewWebhookService(),
+		Admin:   admin,
+	}
+}
+
+// owner is the ownerID passed to the service: the caller, or 0 (anyone) for admins
+func (c *WebhookController) owner(ctx *fiber.Ctx) (int, bool) {
+	if c.Admin {
+		return 0, true
+	}
+	userID := getUserID(ctx)
+	return userID, userID != 0
+}
+
+// webhookError maps service errors to 400/404/500
+func webhookError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		return ctx.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrWebhookDeliveryNotFound):
+		return ctx.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return ctx.Status(500).JSON(fiber.Map{"error": err.Error()})
+}
+
+func pageParams(ctx *fiber.Ctx) (int, int) {
+	page := ctx.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := ctx.QueryInt("limit", 30)
+	if limit < 1 || limit > 100 {
+		limit = 30
+	}
+	return page, limit
+}
+
+func (c *WebhookController) GetWebhooks(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	page, limit := pageParams(ctx)
+	webhooks, total, err := c.Service.List(ownerID, page, limit)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": webhooks,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// CreateWebhook registers a URL; the response has the signing secret (shown only here)
+func (c *WebhookController) CreateWebhook(ctx *fiber.Ctx) error {
+	userID := getUserID(ctx)
+	if userID == 0 {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+
+	var req models.WebhookRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	webhook, err := c.Service.Create(userID, req)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.Status(201).JSON(fiber.Map{"data": webhook})
+}
+
+func (c *WebhookController) GetWebhook(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+
+	webhook, err := c.Service.Get(ownerID, id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": webhook})
+}
+
+// UpdateWebhook changes the fields present in the body
+func (c *WebhookController) UpdateWebhook(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+
+	var req models.WebhookRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid request"})
+	}
+
+	webhook, err := c.Service.Update(ownerID, id, req)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": webhook})
+}
+
+func (c *WebhookController) DeleteWebhook(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+
+	if err := c.Service.Delete(ownerID, id); err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"message": "Webhook deleted"})
+}
+
+// RotateSecret issues a new signing secret; the old one stops working immediately
+func (c *WebhookController) RotateSecret(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+
+	webhook, err := c.Service.RotateSecret(ownerID, id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{"data": webhook})
+}
+
+// Ping queues a test delivery
+func (c *WebhookController) Ping(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+
+	delivery, err := c.Service.Ping(ownerID, id)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.Status(202).JSON(fiber.Map{"data": delivery})
+}
+
+// GetDeliveries is the delivery log. Query: status (pending/succeeded/failed), page, limit
+func (c *WebhookController) GetDeliveries(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+	status := ctx.Query("status")
+	if status != "" && status != "pending" && status != "succeeded" && status != "failed" {
+		return ctx.Status(400).JSON(fiber.Map{"error": "status must be pending, succeeded or failed"})
+	}
+
+	page, limit := pageParams(ctx)
+	deliveries, total, err := c.Service.Deliveries(ownerID, id, status, page, limit)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"data": deliveries,
+		"pagination": fiber.Map{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// ReplayDelivery sends the payload of a logged delivery again (as a new delivery)
+func (c *WebhookController) ReplayDelivery(ctx *fiber.Ctx) error {
+	ownerID, ok := c.owner(ctx)
+	if !ok {
+		return ctx.Status(401).JSON(fiber.Map{"error": "Unauthorized"})
+	}
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid webhook id"})
+	}
+	deliveryID, err := strconv.ParseInt(ctx.Params("deliveryId"), 10, 64)
+	if err != nil {
+		return ctx.Status(400).JSON(fiber.Map{"error": "Invalid delivery id"})
+	}
+
+	delivery, err := c.Service.Replay(ownerID, id, deliveryID)
+	if err != nil {
+		return webhookError(ctx, err)
+	}
+
+	return ctx.Status(202).JSON(fiber.Map{"data": delivery})
+}
@@ -85,6 +85,8 @@ type StreamServer struct {
 	Title    string `json:"title"`
 	ServerID string `json:"serverId"`
 	Href     string `json:"href"`
+	Source   string `json:"source,omitempty"` // Which stream source produced it (otakudesu, animeindo, zoro)
+	IsM3U8   bool   `json:"isM3U8,omitempty"` // HLS playlist instead of an embed page
 }
 
 // QualityOption represents a video quality with its server list
@@ -117,6 +119,28 @@ type StreamResponse struct {
 	} `json:"data"`
 }
 
+// StreamSourceResult reports how one source in the stream fallback chain did
+type StreamSourceResult struct {
+	Source     string `json:"source"`
+	OK         bool   `json:"ok"`
+	Servers    int    `json:"servers"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// ResolvedStream is the merged result of every stream source for one episode.
+// Keeps the StreamResponse field names so existing players keep working.
+type ResolvedStream struct {
+	Title               string               `json:"title"`
+	DefaultStreamingUrl string               `json:"defaultStreamingUrl"`
+	StreamLink          map[string]string    `json:"stream_link"` // Server label -> direct URL (plus "default")
+	URL                 string               `json:"url"`
+	AnimeID             string               `json:"animeId"`
+	Server              ServerData           `json:"server"`
+	DownloadURL         interface{}          `json:"downloadUrl"`
+	Sources             []StreamSourceResult `json:"sources"`
+}
+
 // LatestEpisode represents a recent episode update
 type LatestEpisode struct {
 	Title     string `json:"title"`
@@ -199,6 +223,8 @@ type ChapterResponse struct {
 	ChapterID string   `json:"chapterId"`
 	MangaID   string   `json:"mangaId"`
 	Images    []string `json:"images"` // Array of image URLs for the chapter
-	NextSlug  string   `json:"nextSlug,omitempty"`
-	PrevSlug  string   `json:"prevSlug,omitempty"`
+	// Same images as /api/proxy/image paths (signed when IMAGE_PROXY_SIGNING_KEY is set)
+	ProxyImages []string `json:"proxyImages,omitempty"`
+	NextSlug    string   `json:"nextSlug,omitempty"`
+	PrevSlug    string   `json:"prevSlug,omitempty"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// BookmarkUpdate is a partial update; nil fields are left unchanged.
+// Score 0 clears the score, an empty tags list clears the tags.
+type BookmarkUpdate struct {
+	Status *string   `json:"status"`
+	Score  *int      `json:"score"`
+	Notes  *string   `json:"notes"`
+	Tags   *[]string `json:"tags"`
+}
+
+// BookmarkFilter holds the list query parameters (empty fields don't filter)
+type BookmarkFilter struct {
+	Type         string
+	Status       string
+	Tag          string
+	CollectionID int
+	Query        string // Title substring
+	Sort         string // created, updated, title, score
+	Desc         bool
+	Page         int
+	Limit        int
+}
+
+type BookmarkPage struct {
+	Items      []Bookmark `json:"items"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	Total      int        `json:"total"`
+	TotalPages int        `json:"total_pages"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type BookmarkCollection struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CollectionItemRequest struct {
+	BookmarkID int `json:"bookmark_id"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+type EmailDigestPreferences struct {
+	Email              string     `json:"email"`
+	Frequency          string     `json:"frequency"`                   // off, daily, weekly
+	PendingFrequency   string     `json:"pending_frequency,omitempty"` // starts once the address is confirmed
+	Verified           bool       `json:"verified"`
+	VerificationSentAt *time.Time `json:"verification_sent_at"`
+	LastDigestAt       *time.Time `json:"last_digest_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type EmailDigestRequest struct {
+	Email     string `json:"email"`
+	Frequency string `json:"frequency"`
+}
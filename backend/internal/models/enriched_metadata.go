@@ -20,7 +20,85 @@ type EnrichedMetadata struct {
 	Synopsis    string `db:"synopsis" json:"synopsis,omitempty"`
 
 	// Metadata tracking
-	Source        string    `db:"source" json:"source"` // "gemini", "manual", or "api"
+	Source        string    `db:"source" json:"source"`         // enricher name ("gemini", "openai", "fake"), "manual", or "api"
+	Confidence    *float64  `db:"confidence" json:"confidence"` // nil for rows stored before scoring existed
+	NeedsReview   bool      `db:"needs_review" json:"needsReview"`
+	ReviewReason  string    `db:"review_reason" json:"reviewReason,omitempty"`
+	Locked        bool      `db:"locked" json:"locked"` // Never overwritten by the enricher
 	LastUpdatedAt time.Time `db:"last_updated_at" json:"lastUpdatedAt"`
 	CreatedAt     time.Time `db:"created_at" json:"createdAt"`
 }
+
+// EnrichedMetadataRequest creates a manual row, or edits the fields that are set.
+// Title and MediaType are only used on create.
+type EnrichedMetadataRequest struct {
+	Title       *string `json:"title"`
+	MediaType   *string `json:"mediaType"`
+	Slug        *string `json:"slug"`
+	Author      *string `json:"author"`
+	Genre       *string `json:"genre"`
+	Type        *string `json:"type"`
+	Rating      *string `json:"rating"`
+	Status      *string `json:"status"`
+	ReleaseYear *string `json:"releaseYear"`
+	Synopsis    *string `json:"synopsis"`
+	Locked      *bool   `json:"locked"` // Defaults to true: manual edits stick
+	NeedsReview *bool   `json:"needsReview"`
+}
+
+// EnrichedMetadataFilter holds the admin list query parameters (empty fields don't filter)
+type EnrichedMetadataFilter struct {
+	Query       string // Title substring
+	MediaType   string
+	Source      string
+	NeedsReview *bool
+	Locked      *bool
+	Page        int
+	Limit       int
+}
+
+// EnrichmentJob is a title queued for background enrichment
+type EnrichmentJob struct {
+	ID            int64     `json:"id"`
+	Title         string    `json:"title"`
+	MediaType     string    `json:"mediaType"`
+	Status        string    `json:"status"` // pending, running, done, rejected, failed
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	LastError     string    `json:"lastError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// EnrichmentQueueStats shows how far the enrichment queue has got
+type EnrichmentQueueStats struct {
+	Backend         string         `json:"backend"` // Empty when enrichment is disabled
+	Jobs            map[string]int `json:"jobs"`    // Count per status
+	OldestPendingAt *time.Time     `json:"oldestPendingAt,omitempty"`
+	CallsThisMinute int            `json:"callsThisMinute"`
+	RatePerMinute   int            `json:"ratePerMinute"` // 0 = unlimited
+	CallsToday      int            `json:"callsToday"`
+	DailyBudget     int            `json:"dailyBudget"` // 0 = unlimited
+}
+
+// FieldChange is one field of a revision diff
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// EnrichedMetadataRevision is one entry of a title's history
+type EnrichedMetadataRevision struct {
+	ID           int64                  `json:"id"`
+	MetadataID   int                    `json:"metadataId"`
+	Title        string                 `json:"title"`
+	MediaType    string                 `json:"mediaType"`
+	Action       string                 `json:"action"`  // baseline, create, update, delete, restore
+	Source       string                 `json:"source"`  // The row's source after the change
+	ActorID      *int                   `json:"actorId"` // nil for the enricher
+	Actor        string                 `json:"actor"`   // Username, or "enricher"
+	Diff         map[string]FieldChange `json:"diff"`
+	Snapshot     *EnrichedMetadata      `json:"snapshot,omitempty"`
+	RestoredFrom *int64                 `json:"restoredFrom,omitempty"`
+	CreatedAt    time.Time              `json:"createdAt"`
+}
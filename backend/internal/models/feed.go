@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// AtomFeed is an RFC 4287 feed
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []AtomLink  `xml:"link"`
+	Author  *AtomPerson `xml:"author,omitempty"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Links    []AtomLink   `xml:"link"`
+	Category []AtomTerm   `xml:"category,omitempty"`
+	Summary  *AtomContent `xml:"summary,omitempty"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type AtomContent struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+// AtomTime formats a timestamp the way Atom wants it
+func AtomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// FeedToken describes a user's private feed. URL is only returned right after the token is
+// created, since just its hash is stored.
+type FeedToken struct {
+	URL        string     `json:"url,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ListEntry is one series from an external list (MAL / AniList) or from our own export
+type ListEntry struct {
+	Type       string   `json:"type"` // 'anime' or 'manga'
+	Title      string   `json:"title"`
+	AltTitles  []string `json:"alt_titles,omitempty"`
+	Slug       string   `json:"slug,omitempty"` // Our slug (set on export, read back from our own exported files)
+	Status     string   `json:"status"`         // current, completed, paused, dropped, planning
+	Progress   int      `json:"progress"`       // Episodes watched / chapters read
+	Total      int      `json:"total,omitempty"`
+	Score      float64  `json:"score,omitempty"` // 0-10
+	ExternalID int      `json:"external_id,omitempty"`
+	CoverImage string   `json:"cover_image,omitempty"`
+}
+
+// Import outcomes per entry
+const (
+	ImportMatched   = "matched"
+	ImportAmbiguous = "ambiguous"
+	ImportUnmatched = "unmatched"
+	ImportFailed    = "failed"
+)
+
+// ImportCandidate is a search result offered for an ambiguous entry
+type ImportCandidate struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	Cover string `json:"cover,omitempty"`
+}
+
+// ImportEntryResult reports what happened to one imported entry
+type ImportEntryResult struct {
+	Type         string            `json:"type"`
+	Title        string            `json:"title"`
+	Result       string            `json:"result"`
+	Slug         string            `json:"slug,omitempty"`
+	MatchedTitle string            `json:"matched_title,omitempty"`
+	Candidates   []ImportCandidate `json:"candidates,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	Updated      bool              `json:"updated,omitempty"` // Already bookmarked: status and score were replaced
+	// Tachiyomi/Mihon backups only
+	Source       string   `json:"source,omitempty"`
+	Categories   []string `json:"categories,omitempty"`
+	ChaptersRead int      `json:"chapters_read,omitempty"`
+}
+
+// ImportReport is returned by the list import endpoint
+type ImportReport struct {
+	Format    string              `json:"format"`
+	DryRun    bool                `json:"dry_run"`
+	Total     int                 `json:"total"`
+	Matched   int                 `json:"matched"`
+	Ambiguous int                 `json:"ambiguous"`
+	Unmatched int                 `json:"unmatched"`
+	Failed    int                 `json:"failed"`
+	Entries   []ImportEntryResult `json:"entries"`
+}
+
+// BackupImportReport is returned by the Tachiyomi/Mihon backup import
+type BackupImportReport struct {
+	ImportReport
+	Categories   []string `json:"categories"`
+	Skipped      int      `json:"skipped"` // Non-library entries (history only)
+	ChaptersRead int      `json:"chapters_read"`
+}
+
+// ImportJob is an import being matched in the background; Report holds the ImportReport
+// (BackupImportReport for Tachiyomi backups) once it is done
+type ImportJob struct {
+	ID         int64           `json:"id"`
+	Format     string          `json:"format"`
+	DryRun     bool            `json:"dry_run"`
+	Status     string          `json:"status"` // queued, running, done, failed
+	Total      int             `json:"total"`
+	Processed  int             `json:"processed"`
+	Error      string          `json:"error,omitempty"`
+	Report     json.RawMessage `json:"report,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
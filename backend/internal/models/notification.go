@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Release is a new episode/chapter found in an upstream "latest" feed
+type Release struct {
+	Type       string `json:"type"` // 'anime' or 'manga'
+	Slug       string `json:"slug"` // Series slug
+	Title      string `json:"title"`
+	Release    string `json:"release"`               // e.g. "Episode 12", "Chapter 110"
+	TargetSlug string `json:"target_slug,omitempty"` // Episode/chapter slug when the feed has it
+	CoverImage string `json:"cover_image"`
+}
+
+// Notification tells one user about a release of a title they bookmarked
+type Notification struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Type       string     `json:"type"`
+	Slug       string     `json:"slug"`
+	Title      string     `json:"title"`
+	Release    string     `json:"release"`
+	TargetSlug string     `json:"target_slug,omitempty"`
+	CoverImage string     `json:"cover_image"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ReleaseEvent is one new release on the /api/events stream
+type ReleaseEvent struct {
+	ID int64 `json:"id"`
+	Release
+	URL       string    `json:"url"` // Frontend path of the episode/chapter
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Name is the SSE event name: episode.released or chapter.released
+func (e ReleaseEvent) Name() string {
+	if e.Type == "manga" {
+		return "chapter.released"
+	}
+	return "episode.released"
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PushSubscription is a browser's Web Push subscription
+type PushSubscription struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Endpoint   string     `json:"endpoint"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	DeviceName string     `json:"device_name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// PushSubscriptionRequest is PushSubscription.toJSON() from the browser plus an optional device name
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+	DeviceName string `json:"device_name"`
+}
+
+// PushPayload is the JSON the service worker receives
+type PushPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+	Icon  string `json:"icon,omitempty"`
+	Tag   string `json:"tag"` // Same tag replaces the previous notification of a series
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// ReadingProgress is where a user stopped in one manga
+type ReadingProgress struct {
+	MangaSlug   string    `json:"manga_slug"`
+	ChapterSlug string    `json:"chapter_slug"`
+	PageIndex   int       `json:"page_index"` // 0-based
+	TotalPages  int       `json:"total_pages"`
+	MangaTitle  string    `json:"manga_title"`
+	CoverImage  string    `json:"cover_image"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ReadingProgressRequest is sent by the reader when the visible page changes
+type ReadingProgressRequest struct {
+	MangaSlug   string `json:"manga_slug"`
+	ChapterSlug string `json:"chapter_slug"`
+	PageIndex   int    `json:"page_index"`
+	TotalPages  int    `json:"total_pages"`
+	MangaTitle  string `json:"manga_title"`
+	CoverImage  string `json:"cover_image"`
+}
+
+// ChapterReadRequest marks chapters read (Read=true) or unread
+type ChapterReadRequest struct {
+	Chapters []string `json:"chapters"`
+	Read     bool     `json:"read"`
+}
+
+// MarkPreviousRequest marks a chapter and every chapter before it as read
+type MarkPreviousRequest struct {
+	ChapterSlug string `json:"chapter_slug"`
+}
+
+// MangaReadState is a user's progress and read chapters for one manga
+type MangaReadState struct {
+	Progress     *ReadingProgress `json:"progress"`
+	ReadChapters []string         `json:"read_chapters"`
+}
+
+// ReadChapter is a Chapter annotated with the user's read state
+type ReadChapter struct {
+	Chapter
+	Read bool `json:"read"`
+}
+
+// MangaDetailWithProgress is GetMangaDetail for an authenticated user
+type MangaDetailWithProgress struct {
+	*MangaDetailResponse
+	Chapters    []ReadChapter    `json:"chapters"` // Shadows MangaDetailResponse.Chapters
+	UnreadCount int              `json:"unreadCount"`
+	Progress    *ReadingProgress `json:"progress"`
+}
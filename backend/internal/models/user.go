@@ -19,12 +19,30 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+// TokenPair is returned by login and refresh
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type Bookmark struct {
-	ID         int       `json:"id"`
-	UserID     int       `json:"user_id"`
-	Type       string    `json:"type"` // 'anime' or 'manga'
-	Slug       string    `json:"slug"`
-	Title      string    `json:"title"`
-	CoverImage string    `json:"cover_image"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Type        string    `json:"type"` // 'anime' or 'manga'
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	CoverImage  string    `json:"cover_image"`
+	Status      string    `json:"status"`          // planning, current, completed, paused, dropped
+	Score       *int      `json:"score,omitempty"` // 1-10, nil when unrated
+	Notes       string    `json:"notes"`
+	Tags        []string  `json:"tags"`
+	Collections []int     `json:"collections"` // IDs of the collections containing this bookmark
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// WatchProgress is one row of watch_history (a user's progress in one episode)
+type WatchProgress struct {
+	ID              int       `json:"id"`
+	UserID          int       `json:"user_id"`
+	AnimeSlug       string    `json:"anime_slug"`
+	EpisodeSlug     string    `json:"episode_slug"`
+	AnimeTitle      string    `json:"anime_title"`
+	EpisodeTitle    string    `json:"episode_title"`
+	CoverImage      string    `json:"cover_image"`
+	PositionSeconds int       `json:"position_seconds"`
+	DurationSeconds int       `json:"duration_seconds"`
+	Completed       bool      `json:"completed"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProgressRequest is sent by the player while an episode plays
+type ProgressRequest struct {
+	AnimeSlug       string `json:"anime_slug"`
+	EpisodeSlug     string `json:"episode_slug"`
+	AnimeTitle      string `json:"anime_title"`
+	EpisodeTitle    string `json:"episode_title"`
+	CoverImage      string `json:"cover_image"`
+	PositionSeconds int    `json:"position_seconds"`
+	DurationSeconds int    `json:"duration_seconds"`
+	Completed       bool   `json:"completed"` // Optional, otherwise derived from position/duration
+}
+
+// ContinueWatchingItem is the episode to play next for one series
+type ContinueWatchingItem struct {
+	AnimeSlug       string    `json:"anime_slug"`
+	AnimeTitle      string    `json:"anime_title"`
+	CoverImage      string    `json:"cover_image"`
+	EpisodeSlug     string    `json:"episode_slug"`
+	EpisodeTitle    string    `json:"episode_title"`
+	PositionSeconds int       `json:"position_seconds"` // Resume point (0 for a new episode)
+	DurationSeconds int       `json:"duration_seconds"`
+	Resume          bool      `json:"resume"` // true = unfinished episode, false = next episode
+	LastWatchedAt   time.Time `json:"last_watched_at"`
+}
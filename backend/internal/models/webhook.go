@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Webhook struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	URL            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"` // Only returned on create and secret rotation
+	Description    string    `json:"description"`
+	Types          []string  `json:"types"`
+	Genres         []string  `json:"genres"`
+	Slugs          []string  `json:"slugs"`
+	BookmarkedOnly bool      `json:"bookmarked_only"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WebhookRequest creates a webhook, or updates the fields that are set
+type WebhookRequest struct {
+	URL            *string   `json:"url"`
+	Description    *string   `json:"description"`
+	Types          *[]string `json:"types"`
+	Genres         *[]string `json:"genres"`
+	Slugs          *[]string `json:"slugs"`
+	BookmarkedOnly *bool     `json:"bookmarked_only"`
+	Active         *bool     `json:"active"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int             `json:"webhook_id"`
+	Event          string          `json:"event"`
+	ReleaseEventID *int64          `json:"release_event_id,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"` // pending, succeeded, failed
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	ReplayOf       *int64          `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+}
+
+// WebhookPayload is the JSON body POSTed to webhook URLs
+type WebhookPayload struct {
+	Event     string        `json:"event"` // episode.released, chapter.released or ping
+	CreatedAt time.Time     `json:"created_at"`
+	Data      *ReleaseEvent `json:"data,omitempty"`
+}
@@ -4,6 +4,9 @@ import (
 	"anime-tanyaayomi/internal/models"
 	"database/sql"
 	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 // EnrichmentRepository handles database operations for enriched metadata
@@ -16,19 +19,18 @@ func NewEnrichmentRepository(db *sql.DB) *EnrichmentRepository {
 	return &EnrichmentRepository{DB: db}
 }
 
-// GetByTitle retrieves enriched metadata by title and media type
-// Returns nil error if not found (allowing fallback to Gemini)
-func (r *EnrichmentRepository) GetByTitle(title string, mediaType string) (*models.EnrichedMetadata, error) {
-	query := `
-		SELECT id, title, media_type, slug, author, genre, type, rating, status, 
-		       release_year, synopsis, source, last_updated_at, created_at
-		FROM enriched_metadata
-		WHERE title = $1 AND media_type = $2
-		LIMIT 1
-	`
+const enrichedMetadataColumns = `id, title, media_type, COALESCE(slug, ''), COALESCE(author, ''), COALESCE(genre, ''),
+		       COALESCE(type, ''), COALESCE(rating, ''), COALESCE(status, ''), COALESCE(release_year, ''),
+		       COALESCE(synopsis, ''), source, confidence, needs_review, COALESCE(review_reason, ''), locked,
+		       last_updated_at, created_at`
 
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEnrichedMetadata(row rowScanner, extra ...interface{}) (*models.EnrichedMetadata, error) {
 	var data models.EnrichedMetadata
-	err := r.DB.QueryRow(query, title, mediaType).Scan(
+	dest := []interface{}{
 		&data.ID,
 		&data.Title,
 		&data.MediaType,
@@ -41,12 +43,32 @@ func (r *EnrichmentRepository) GetByTitle(title string, mediaType string) (*mode
 		&data.ReleaseYear,
 		&data.Synopsis,
 		&data.Source,
+		&data.Confidence,
+		&data.NeedsReview,
+		&data.ReviewReason,
+		&data.Locked,
 		&data.LastUpdatedAt,
 		&data.CreatedAt,
-	)
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetByTitle retrieves enriched metadata by title and media type
+// Returns nil error if not found (allowing fallback to the enricher)
+func (r *EnrichmentRepository) GetByTitle(title string, mediaType string) (*models.EnrichedMetadata, error) {
+	query := `
+		SELECT ` + enrichedMetadataColumns + `
+		FROM enriched_metadata
+		WHERE title = $1 AND media_type = $2
+		LIMIT 1
+	`
 
+	data, err := scanEnrichedMetadata(r.DB.QueryRow(query, title, mediaType))
 	if err == sql.ErrNoRows {
-		// Not found - return nil to allow fallback to Gemini
+		// Not found - return nil to allow fallback to the enricher
 		return nil, nil
 	}
 
@@ -54,17 +76,76 @@ func (r *EnrichmentRepository) GetByTitle(title string, mediaType string) (*mode
 		return nil, fmt.Errorf("error querying enriched metadata: %w", err)
 	}
 
-	return &data, nil
+	return data, nil
+}
+
+// GetByID retrieves one row; nil when it doesn't exist
+func (r *EnrichmentRepository) GetByID(id int) (*models.EnrichedMetadata, error) {
+	query := `SELECT ` + enrichedMetadataColumns + ` FROM enriched_metadata WHERE id = $1`
+
+	data, err := scanEnrichedMetadata(r.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying enriched metadata: %w", err)
+	}
+	return data, nil
+}
+
+// GetByTitles looks up many titles of one media type in a single query, for list pages.
+// The result is keyed by title; titles without a row are absent.
+func (r *EnrichmentRepository) GetByTitles(titles []string, mediaType string) (map[string]*models.EnrichedMetadata, error) {
+	results := make(map[string]*models.EnrichedMetadata)
+	if len(titles) == 0 {
+		return results, nil
+	}
+
+	query := `
+		SELECT ` + enrichedMetadataColumns + `
+		FROM enriched_metadata
+		WHERE media_type = $1 AND title = ANY($2)
+	`
+
+	rows, err := r.DB.Query(query, mediaType, pq.Array(titles))
+	if err != nil {
+		return nil, fmt.Errorf("error querying enriched metadata: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		data, err := scanEnrichedMetadata(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning enriched metadata: %w", err)
+		}
+		results[data.Title] = data
+	}
+
+	return results, rows.Err()
 }
 
 // Upsert inserts or updates enriched metadata
-// Uses PostgreSQL's ON CONFLICT to handle duplicates
+// Uses PostgreSQL's ON CONFLICT to handle duplicates. Locked rows are never touched, and a
+// result flagged for review never replaces one that wasn't; data.ID stays 0 when the row
+// was left alone. Changes are recorded as revisions by the enricher.
 func (r *EnrichmentRepository) Upsert(data *models.EnrichedMetadata) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error upserting enriched metadata: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockEnrichedMetadata(tx, "title = $1 AND media_type = $2", data.Title, data.MediaType)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO enriched_metadata 
-			(title, media_type, slug, author, genre, type, rating, status, release_year, synopsis, source)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		ON CONFLICT (title, media_type) 
+		INSERT INTO enriched_metadata
+			(title, media_type, slug, author, genre, type, rating, status, release_year, synopsis, source,
+			 confidence, needs_review, review_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''))
+		ON CONFLICT (title, media_type)
 		DO UPDATE SET
 			slug = EXCLUDED.slug,
 			author = COALESCE(NULLIF(EXCLUDED.author, ''), enriched_metadata.author),
@@ -75,12 +156,15 @@ func (r *EnrichmentRepository) Upsert(data *models.EnrichedMetadata) error {
 			release_year = COALESCE(NULLIF(EXCLUDED.release_year, ''), enriched_metadata.release_year),
 			synopsis = COALESCE(NULLIF(EXCLUDED.synopsis, ''), enriched_metadata.synopsis),
 			source = EXCLUDED.source,
+			confidence = EXCLUDED.confidence,
+			needs_review = EXCLUDED.needs_review,
+			review_reason = EXCLUDED.review_reason,
 			last_updated_at = NOW()
-		RETURNING id
-	`
+		WHERE NOT enriched_metadata.locked
+		  AND (enriched_metadata.needs_review OR NOT EXCLUDED.needs_review)
+		RETURNING ` + enrichedMetadataColumns
 
-	var id int
-	err := r.DB.QueryRow(
+	saved, err := scanEnrichedMetadata(tx.QueryRow(
 		query,
 		data.Title,
 		data.MediaType,
@@ -93,56 +177,184 @@ func (r *EnrichmentRepository) Upsert(data *models.EnrichedMetadata) error {
 		data.ReleaseYear,
 		data.Synopsis,
 		data.Source,
-	).Scan(&id)
+		data.Confidence,
+		data.NeedsReview,
+		data.ReviewReason,
+	))
 
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("error upserting enriched metadata: %w", err)
 	}
 
-	data.ID = id
+	action := RevisionUpdate
+	if before == nil {
+		action = RevisionCreate
+	}
+	if err := recordRevision(tx, action, before, saved, 0, 0); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error upserting enriched metadata: %w", err)
+	}
+
+	data.ID = saved.ID
 	return nil
 }
 
-// GetAll retrieves all enriched metadata (useful for admin/debugging)
-func (r *EnrichmentRepository) GetAll(limit int) ([]models.EnrichedMetadata, error) {
+// Create inserts a row written by a curator (actorID); nil when the title already has one
+func (r *EnrichmentRepository) Create(data *models.EnrichedMetadata, actorID int) (*models.EnrichedMetadata, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error creating enriched metadata: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		SELECT id, title, media_type, slug, author, genre, type, rating, status, 
-		       release_year, synopsis, source, last_updated_at, created_at
-		FROM enriched_metadata
-		ORDER BY last_updated_at DESC
-		LIMIT $1
-	`
+		INSERT INTO enriched_metadata
+			(title, media_type, slug, author, genre, type, rating, status, release_year, synopsis, source,
+			 needs_review, locked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (title, media_type) DO NOTHING
+		RETURNING ` + enrichedMetadataColumns
+
+	created, err := scanEnrichedMetadata(tx.QueryRow(query, data.Title, data.MediaType, data.Slug, data.Author,
+		data.Genre, data.Type, data.Rating, data.Status, data.ReleaseYear, data.Synopsis, data.Source,
+		data.NeedsReview, data.Locked))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating enriched metadata: %w", err)
+	}
+
+	if err := recordRevision(tx, RevisionCreate, nil, created, actorID, 0); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error creating enriched metadata: %w", err)
+	}
+	return created, nil
+}
+
+// Update overwrites every editable field of a row (unlike Upsert, empty values clear fields)
+// and records the change as made by actorID
+func (r *EnrichmentRepository) Update(data *models.EnrichedMetadata, actorID int) (*models.EnrichedMetadata, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error updating enriched metadata: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockEnrichedMetadata(tx, "id = $1", data.ID)
+	if err != nil || before == nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE enriched_metadata SET
+			slug = $2, author = $3, genre = $4, type = $5, rating = $6, status = $7, release_year = $8,
+			synopsis = $9, source = $10, confidence = $11, needs_review = $12, review_reason = NULLIF($13, ''),
+			locked = $14, last_updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + enrichedMetadataColumns
+
+	updated, err := scanEnrichedMetadata(tx.QueryRow(query, data.ID, data.Slug, data.Author, data.Genre, data.Type,
+		data.Rating, data.Status, data.ReleaseYear, data.Synopsis, data.Source, data.Confidence, data.NeedsReview,
+		data.ReviewReason, data.Locked))
+	if err != nil {
+		return nil, fmt.Errorf("error updating enriched metadata: %w", err)
+	}
+
+	if err := recordRevision(tx, RevisionUpdate, before, updated, actorID, 0); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error updating enriched metadata: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete removes a row and returns it; nil when it didn't exist. The deleted values stay
+// in the revision history.
+func (r *EnrichmentRepository) Delete(id int, actorID int) (*models.EnrichedMetadata, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error deleting enriched metadata: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM enriched_metadata WHERE id = $1 RETURNING ` + enrichedMetadataColumns
+
+	deleted, err := scanEnrichedMetadata(tx.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error deleting enriched metadata: %w", err)
+	}
 
-	rows, err := r.DB.Query(query, limit)
+	if err := recordRevision(tx, RevisionDelete, deleted, nil, actorID, 0); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error deleting enriched metadata: %w", err)
+	}
+	return deleted, nil
+}
+
+// List returns one page of rows matching filter, most recently updated first, and the
+// total number of matches
+func (r *EnrichmentRepository) List(filter models.EnrichedMetadataFilter) ([]models.EnrichedMetadata, int, error) {
+	var where []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.MediaType != "" {
+		where = append(where, "media_type = "+arg(filter.MediaType))
+	}
+	if filter.Source != "" {
+		where = append(where, "source = "+arg(filter.Source))
+	}
+	if filter.NeedsReview != nil {
+		where = append(where, "needs_review = "+arg(*filter.NeedsReview))
+	}
+	if filter.Locked != nil {
+		where = append(where, "locked = "+arg(*filter.Locked))
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+		where = append(where, "title ILIKE "+arg("%"+escaped+"%"))
+	}
+	condition := "TRUE"
+	if len(where) > 0 {
+		condition = strings.Join(where, " AND ")
+	}
+
+	query := fmt.Sprintf(`SELECT %s, COUNT(*) OVER() FROM enriched_metadata WHERE %s
+		ORDER BY last_updated_at DESC, id DESC LIMIT %s OFFSET %s`,
+		enrichedMetadataColumns, condition, arg(filter.Limit), arg((filter.Page-1)*filter.Limit))
+
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error querying all enriched metadata: %w", err)
+		return nil, 0, fmt.Errorf("error querying enriched metadata: %w", err)
 	}
 	defer rows.Close()
 
-	var results []models.EnrichedMetadata
+	results := []models.EnrichedMetadata{}
+	total := 0
 	for rows.Next() {
-		var data models.EnrichedMetadata
-		err := rows.Scan(
-			&data.ID,
-			&data.Title,
-			&data.MediaType,
-			&data.Slug,
-			&data.Author,
-			&data.Genre,
-			&data.Type,
-			&data.Rating,
-			&data.Status,
-			&data.ReleaseYear,
-			&data.Synopsis,
-			&data.Source,
-			&data.LastUpdatedAt,
-			&data.CreatedAt,
-		)
+		data, err := scanEnrichedMetadata(rows, &total)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning enriched metadata: %w", err)
+			return nil, 0, fmt.Errorf("error scanning enriched metadata: %w", err)
 		}
-		results = append(results, data)
+		results = append(results, *data)
 	}
 
-	return results, nil
+	return results, total, rows.Err()
 }
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// The revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+)
+
+// enrichedMetadataFields are the values a revision diff compares, keyed like the JSON
+// snapshot
+func enrichedMetadataFields(data *models.EnrichedMetadata) map[string]interface{} {
+	var confidence interface{}
+	if data.Confidence != nil {
+		confidence = *data.Confidence
+	}
+	return map[string]interface{}{
+		"slug":         data.Slug,
+		"author":       data.Author,
+		"genre":        data.Genre,
+		"type":         data.Type,
+		"rating":       data.Rating,
+		"status":       data.Status,
+		"releaseYear":  data.ReleaseYear,
+		"synopsis":     data.Synopsis,
+		"source":       data.Source,
+		"confidence":   confidence,
+		"needsReview":  data.NeedsReview,
+		"reviewReason": data.ReviewReason,
+		"locked":       data.Locked,
+	}
+}
+
+// diffEnrichedMetadata lists the fields that differ; a nil side is a row that didn't exist
+func diffEnrichedMetadata(before, after *models.EnrichedMetadata) map[string]models.FieldChange {
+	diff := make(map[string]models.FieldChange)
+	var oldFields, newFields map[string]interface{}
+	if before != nil {
+		oldFields = enrichedMetadataFields(before)
+	}
+	if after != nil {
+		newFields = enrichedMetadataFields(after)
+	}
+
+	for _, fields := range []map[string]interface{}{oldFields, newFields} {
+		for key := range fields {
+			if _, done := diff[key]; done {
+				continue
+			}
+			oldValue, newValue := oldFields[key], newFields[key]
+			if before == nil || after == nil {
+				// Creates and deletes only list the fields that had a value
+				if value := firstNonNil(oldValue, newValue); value == "" || value == false {
+					continue
+				}
+			}
+			if oldValue != newValue {
+				diff[key] = models.FieldChange{Old: oldValue, New: newValue}
+			}
+		}
+	}
+	return diff
+}
+
+func firstNonNil(values ...interface{}) interface{} {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// recordRevision appends a revision inside the write's transaction. Updates that changed
+// nothing aren't recorded. actorID 0 is the enricher.
+func recordRevision(tx *sql.Tx, action string, before, after *models.EnrichedMetadata, actorID int, restoredFrom int64) error {
+	diff := diffEnrichedMetadata(before, after)
+	if action == RevisionUpdate && len(diff) == 0 {
+		return nil
+	}
+
+	row := after
+	if action == RevisionDelete {
+		row = before
+	}
+	snapshot, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	var actor, restored interface{}
+	if actorID != 0 {
+		actor = actorID
+	}
+	if restoredFrom != 0 {
+		restored = restoredFrom
+	}
+	_, err = tx.Exec(`INSERT INTO enriched_metadata_revisions
+			(metadata_id, title, media_type, action, source, actor_id, diff, snapshot, restored_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		row.ID, row.Title, row.MediaType, action, row.Source, actor, string(diffJSON), string(snapshot), restored)
+	if err != nil {
+		return fmt.Errorf("error recording enriched metadata revision: %w", err)
+	}
+	return nil
+}
+
+const revisionColumns = `r.id, r.metadata_id, r.title, r.media_type, r.action, r.source, r.actor_id,
+		       CASE WHEN r.actor_id IS NULL THEN 'enricher' ELSE COALESCE(u.username, 'deleted user') END,
+		       r.diff, r.snapshot, r.restored_from, r.created_at`
+
+func scanRevision(row rowScanner, extra ...interface{}) (*models.EnrichedMetadataRevision, error) {
+	var revision models.EnrichedMetadataRevision
+	var actorID sql.NullInt64
+	var restoredFrom sql.NullInt64
+	var diff, snapshot []byte
+	dest := []interface{}{
+		&revision.ID,
+		&revision.MetadataID,
+		&revision.Title,
+		&revision.MediaType,
+		&revision.Action,
+		&revision.Source,
+		&actorID,
+		&revision.Actor,
+		&diff,
+		&snapshot,
+		&restoredFrom,
+		&revision.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		revision.ActorID = &id
+	}
+	if restoredFrom.Valid {
+		revision.RestoredFrom = &restoredFrom.Int64
+	}
+	if err := json.Unmarshal(diff, &revision.Diff); err != nil {
+		return nil, fmt.Errorf("error decoding revision diff: %w", err)
+	}
+	revision.Snapshot = &models.EnrichedMetadata{}
+	if err := json.Unmarshal(snapshot, revision.Snapshot); err != nil {
+		return nil, fmt.Errorf("error decoding revision snapshot: %w", err)
+	}
+	return &revision, nil
+}
+
+// Revisions returns one page of a title's history, newest first, and the total count
+func (r *EnrichmentRepository) Revisions(title string, mediaType string, page int, limit int) ([]models.EnrichedMetadataRevision, int, error) {
+	query := `SELECT ` + revisionColumns + `, COUNT(*) OVER()
+		FROM enriched_metadata_revisions r LEFT JOIN users u ON u.id = r.actor_id
+		WHERE r.title = $1 AND r.media_type = $2
+		ORDER BY r.id DESC LIMIT $3 OFFSET $4`
+
+	rows, err := r.DB.Query(query, title, mediaType, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying enriched metadata revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.EnrichedMetadataRevision{}
+	total := 0
+	for rows.Next() {
+		revision, err := scanRevision(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning enriched metadata revision: %w", err)
+		}
+		revisions = append(revisions, *revision)
+	}
+	return revisions, total, rows.Err()
+}
+
+// GetRevision retrieves one revision; nil when it doesn't exist
+func (r *EnrichmentRepository) GetRevision(id int64) (*models.EnrichedMetadataRevision, error) {
+	query := `SELECT ` + revisionColumns + `
+		FROM enriched_metadata_revisions r LEFT JOIN users u ON u.id = r.actor_id
+		WHERE r.id = $1`
+
+	revision, err := scanRevision(r.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying enriched metadata revision: %w", err)
+	}
+	return revision, nil
+}
+
+// Restore writes a revision's snapshot back (recreating the row if it was deleted) and
+// records it as a restore revision
+func (r *EnrichmentRepository) Restore(revision *models.EnrichedMetadataRevision, actorID int) (*models.EnrichedMetadata, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snapshot := revision.Snapshot
+	before, err := lockEnrichedMetadata(tx, "title = $1 AND media_type = $2", revision.Title, revision.MediaType)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO enriched_metadata
+			(title, media_type, slug, author, genre, type, rating, status, release_year, synopsis, source,
+			 confidence, needs_review, review_reason, locked)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15)
+		ON CONFLICT (title, media_type)
+		DO UPDATE SET
+			slug = EXCLUDED.slug, author = EXCLUDED.author, genre = EXCLUDED.genre, type = EXCLUDED.type,
+			rating = EXCLUDED.rating, status = EXCLUDED.status, release_year = EXCLUDED.release_year,
+			synopsis = EXCLUDED.synopsis, source = EXCLUDED.source, confidence = EXCLUDED.confidence,
+			needs_review = EXCLUDED.needs_review, review_reason = EXCLUDED.review_reason,
+			locked = EXCLUDED.locked, last_updated_at = NOW()
+		RETURNING ` + enrichedMetadataColumns
+
+	restored, err := scanEnrichedMetadata(tx.QueryRow(query, revision.Title, revision.MediaType, snapshot.Slug,
+		snapshot.Author, snapshot.Genre, snapshot.Type, snapshot.Rating, snapshot.Status, snapshot.ReleaseYear,
+		snapshot.Synopsis, snapshot.Source, snapshot.Confidence, snapshot.NeedsReview, snapshot.ReviewReason,
+		snapshot.Locked))
+	if err != nil {
+		return nil, fmt.Errorf("error restoring enriched metadata: %w", err)
+	}
+
+	if err := recordRevision(tx, RevisionRestore, before, restored, actorID, revision.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// lockEnrichedMetadata reads the row matching condition FOR UPDATE, so the revision diff is
+// against what the write replaces; nil when there is none
+func lockEnrichedMetadata(tx *sql.Tx, condition string, args ...interface{}) (*models.EnrichedMetadata, error) {
+	data, err := scanEnrichedMetadata(tx.QueryRow(`SELECT `+enrichedMetadataColumns+`
+		FROM enriched_metadata WHERE `+condition+` FOR UPDATE`, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying enriched metadata: %w", err)
+	}
+	return data, nil
+}
@@ -2,6 +2,8 @@ package routes
 
 import (
 	"anime-tanyaayomi/internal/controllers"
+	"anime-tanyaayomi/internal/middleware"
+	"anime-tanyaayomi/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -9,7 +11,11 @@ import (
 func SetupRoutes(app *fiber.App) {
 	api := app.Group("/api")
 
-	animeController := controllers.NewAnimeController()
+	// Named anime/manga sources (default picked via ANIME_PROVIDER / MANGA_PROVIDER)
+	providers := services.NewDefaultProviderRegistry()
+
+	animeController := controllers.NewAnimeController(providers)
+	api.Get("/providers", animeController.GetProviders)
 
 	anime := api.Group("/anime")
 
@@ -29,29 +35,162 @@ func SetupRoutes(app *fiber.App) {
 	anime.Get("/:slug", animeController.GetDetail)
 	anime.Get("/episode/:slug", animeController.GetStream)
 
-	mangaController := controllers.NewMangaController()
+	// === Provider-selected Endpoints (e.g. /anime/animeindo/search?q=...) ===
+	// Registered after the legacy routes so fixed paths like /genre/:slug keep priority
+	anime.Get("/:provider/search", animeController.SearchByProvider)
+	anime.Get("/:provider/detail/:slug", animeController.GetDetailByProvider)
+	anime.Get("/:provider/episode/:slug", animeController.GetStreamByProvider)
+
+	// Puts the user on the context when a token is sent, anonymous otherwise
+	optionalAuth := middleware.OptionalAuth()
+
+	mangaController := controllers.NewMangaController(providers)
 	manga := api.Group("/manga")
 	manga.Get("/home", mangaController.GetHome)         // NEW: Home endpoint
 	manga.Get("/trending", mangaController.GetTrending) // NEW: Trending endpoint
 	manga.Get("/ongoing", mangaController.GetOngoing)   // NEW: Ongoing endpoint
 	manga.Get("/search", mangaController.Search)
-	manga.Get("/genres/:slug", mangaController.GetGenre) // NEW: Genres endpoint (plural)
-	manga.Get("/genre/:slug", mangaController.GetGenre)  // Alias for consistency
-	manga.Get("/:slug", mangaController.GetDetail)
+	manga.Get("/genres/:slug", mangaController.GetGenre)         // NEW: Genres endpoint (plural)
+	manga.Get("/genre/:slug", mangaController.GetGenre)          // Alias for consistency
+	manga.Get("/:slug", optionalAuth, mangaController.GetDetail) // Adds read state when logged in
 	manga.Get("/chapter/:chapterId", mangaController.GetChapter)
 
+	// === Provider-selected Endpoints (e.g. /manga/komikindo/search?q=...) ===
+	manga.Get("/:provider/search", mangaController.SearchByProvider)
+	manga.Get("/:provider/detail/:slug", mangaController.GetDetailByProvider)
+	manga.Get("/:provider/chapter/:chapterId", mangaController.GetChapterByProvider)
+
+	// Verifies the JWT access token and puts the user on the context
+	requireAuth := middleware.RequireAuth()
+
 	userController := controllers.NewUserController()
 	auth := api.Group("/auth")
 	auth.Post("/register", userController.Register)
 	auth.Post("/login", userController.Login)
+	auth.Post("/refresh", userController.Refresh)
+	auth.Post("/logout", requireAuth, userController.Logout)
 
+	// === Per-user routes (JWT required) ===
 	bookmarkController := controllers.NewBookmarkController()
-	bookmarks := api.Group("/bookmarks")
+	bookmarks := api.Group("/bookmarks", requireAuth)
 	bookmarks.Post("/", bookmarkController.AddBookmark)
 	bookmarks.Get("/", bookmarkController.GetBookmarks)
+	bookmarks.Get("/tags", bookmarkController.GetTags)
+	bookmarks.Get("/collections", bookmarkController.GetCollections)
+	bookmarks.Post("/collections", bookmarkController.CreateCollection)
+	bookmarks.Patch("/collections/:id", bookmarkController.UpdateCollection)
+	bookmarks.Delete("/collections/:id", bookmarkController.DeleteCollection)
+	bookmarks.Post("/collections/:id/items", bookmarkController.AddToCollection)
+	bookmarks.Delete("/collections/:id/items/:bookmarkId", bookmarkController.RemoveFromCollection)
+	bookmarks.Get("/:id", bookmarkController.GetBookmark)
+	bookmarks.Patch("/:id", bookmarkController.UpdateBookmark)
 	bookmarks.Delete("/:id", bookmarkController.RemoveBookmark)
 
+	watchHistoryController := controllers.NewWatchHistoryController()
+	me := api.Group("/me", requireAuth)
+	me.Post("/progress", watchHistoryController.SaveProgress)
+	me.Get("/progress/:animeSlug", watchHistoryController.GetAnimeProgress)
+	me.Get("/history", watchHistoryController.GetHistory)
+	me.Delete("/history/:episodeSlug", watchHistoryController.RemoveProgress)
+	me.Get("/continue-watching", watchHistoryController.ContinueWatching)
+
+	readingController := controllers.NewReadingProgressController()
+	me.Post("/reading-progress", readingController.SaveProgress)
+	me.Get("/reading-progress", readingController.GetRecent)
+	me.Get("/reading-progress/:mangaSlug", readingController.GetReadState)
+	me.Post("/manga/:mangaSlug/read", readingController.SetChaptersRead)
+	me.Post("/manga/:mangaSlug/read-previous", readingController.MarkPreviousRead)
+
+	// MyAnimeList XML / AniList JSON; imports are matched in the background
+	listTransferController := controllers.NewListTransferController(providers)
+	me.Post("/import", listTransferController.Import)
+	me.Post("/import/tachiyomi", listTransferController.ImportTachiyomi)
+	me.Get("/imports", listTransferController.GetImports)
+	me.Get("/imports/:id", listTransferController.GetImport)
+	me.Get("/export", listTransferController.Export)
+
+	notificationController := controllers.NewNotificationController()
+	me.Get("/notifications", notificationController.GetNotifications)
+	me.Get("/notifications/unread-count", notificationController.GetUnreadCount)
+	me.Post("/notifications/read-all", notificationController.MarkAllRead)
+	me.Post("/notifications/:id/read", notificationController.MarkRead)
+
+	// Web Push (VAPID)
+	pushController := controllers.NewPushController()
+	api.Get("/push/vapid-public-key", pushController.GetPublicKey)
+	me.Get("/push/subscriptions", pushController.GetSubscriptions)
+	me.Post("/push/subscriptions", pushController.Subscribe)
+	me.Delete("/push/subscriptions", pushController.Unsubscribe)
+	me.Delete("/push/subscriptions/:id", pushController.Unsubscribe)
+	me.Post("/push/test", pushController.SendTest)
+
+	// Email digests (SMTP)
+	emailDigestController := controllers.NewEmailDigestController()
+	me.Get("/email-digest", emailDigestController.GetPreferences)
+	me.Put("/email-digest", emailDigestController.UpdatePreferences)
+	me.Post("/email-digest/test", emailDigestController.SendTest)
+	me.Post("/email-digest/verify", emailDigestController.ResendVerification)
+	api.Get("/email/verify", emailDigestController.VerifyPage)
+	api.Post("/email/verify", emailDigestController.Verify)
+	api.Get("/email/unsubscribe", emailDigestController.UnsubscribePage)
+	api.Post("/email/unsubscribe", emailDigestController.Unsubscribe) // Also RFC 8058 one-click
+
+	// Outbound webhooks for release events
+	webhookController := controllers.NewWebhookController(false)
+	me.Get("/webhooks", webhookController.GetWebhooks)
+	me.Post("/webhooks", webhookController.CreateWebhook)
+	me.Get("/webhooks/:id", webhookController.GetWebhook)
+	me.Patch("/webhooks/:id", webhookController.UpdateWebhook)
+	me.Delete("/webhooks/:id", webhookController.DeleteWebhook)
+	me.Post("/webhooks/:id/rotate-secret", webhookController.RotateSecret)
+	me.Post("/webhooks/:id/ping", webhookController.Ping)
+	me.Get("/webhooks/:id/deliveries", webhookController.GetDeliveries)
+	me.Post("/webhooks/:id/deliveries/:deliveryId/replay", webhookController.ReplayDelivery)
+
+	// === Admin routes (users.is_admin) ===
+	admin := api.Group("/admin", requireAuth, middleware.RequireAdmin())
+	adminWebhookController := controllers.NewWebhookController(true)
+	admin.Get("/webhooks", adminWebhookController.GetWebhooks)
+	admin.Get("/webhooks/:id", adminWebhookController.GetWebhook)
+	admin.Patch("/webhooks/:id", adminWebhookController.UpdateWebhook)
+	admin.Delete("/webhooks/:id", adminWebhookController.DeleteWebhook)
+	admin.Get("/webhooks/:id/deliveries", adminWebhookController.GetDeliveries)
+	admin.Post("/webhooks/:id/deliveries/:deliveryId/replay", adminWebhookController.ReplayDelivery)
+
+	enrichmentController := controllers.NewEnrichmentController()
+	admin.Get("/enrichment/queue", enrichmentController.GetQueue)
+	admin.Get("/enrichment/jobs", enrichmentController.GetJobs)
+	admin.Post("/enrichment/jobs/:id/retry", enrichmentController.RetryJob)
+	admin.Get("/enrichment/metadata", enrichmentController.GetMetadata)
+	admin.Post("/enrichment/metadata", enrichmentController.CreateMetadata)
+	admin.Get("/enrichment/metadata/:id", enrichmentController.GetMetadataRow)
+	admin.Patch("/enrichment/metadata/:id", enrichmentController.UpdateMetadata)
+	admin.Delete("/enrichment/metadata/:id", enrichmentController.DeleteMetadata)
+	admin.Post("/enrichment/metadata/:id/lock", enrichmentController.LockMetadata)
+	admin.Delete("/enrichment/metadata/:id/lock", enrichmentController.UnlockMetadata)
+	admin.Get("/enrichment/metadata/:id/revisions", enrichmentController.GetRowRevisions)
+	admin.Get("/enrichment/revisions", enrichmentController.GetRevisions)
+	admin.Get("/enrichment/revisions/:revisionId", enrichmentController.GetRevision)
+	admin.Post("/enrichment/revisions/:revisionId/restore", enrichmentController.RestoreRevision)
+
+	// Live release events (SSE); EventSource can't send headers, so ?access_token= works too
+	eventController := controllers.NewEventController()
+	api.Get("/events", middleware.OptionalStreamAuth(), eventController.Stream)
+
+	// Atom feeds (outside /api: feed readers get a plain URL, private feeds authenticate by token)
+	feedController := controllers.NewFeedController()
+	feeds := app.Group("/feeds")
+	feeds.Get("/anime/latest.atom", feedController.LatestAnime)
+	feeds.Get("/manga/latest.atom", feedController.LatestManga)
+	feeds.Get("/anime/genre/:slug.atom", feedController.AnimeGenre)
+	feeds.Get("/manga/genre/:slug.atom", feedController.MangaGenre)
+	feeds.Get("/users/:token.atom", feedController.UserFeed)
+	me.Get("/feed-token", feedController.GetFeedToken)
+	me.Post("/feed-token", feedController.RotateFeedToken)
+	me.Delete("/feed-token", feedController.RevokeFeedToken)
+
 	// === Proxy for Images ===
 	proxyController := controllers.NewProxyController()
 	api.Get("/proxy/image", proxyController.GetImage)
+	api.Get("/proxy/hls", proxyController.GetHLS) // HLS playlists/segments with upstream Referer
 }
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// Package-level signing secret (shared by every AuthService instance)
+var (
+	jwtSecret     []byte
+	jwtSecretOnce sync.Once
+)
+
+// Tokens are HS256: the secret should be at least as long as the hash
+const minJWTSecretLength = 32
+
+// getJWTSecret loads JWT_SECRET. It panics (at startup, when the routes are set up) if the
+// secret is missing or weak: a per-process secret would break tokens across replicas and restarts.
+func getJWTSecret() []byte {
+	jwtSecretOnce.Do(func() {
+		secret := os.Getenv("JWT_SECRET")
+		switch {
+		case secret == "":
+			panic("JWT_SECRET is not set, generate a random secret (e.g. openssl rand -hex 32)")
+		// The value docker-compose used to default to; anyone could sign tokens with it
+		case secret == "change-me-in-production":
+			panic("JWT_SECRET is set to the old public default, generate a random secret")
+		case len(secret) < minJWTSecretLength:
+			panic(fmt.Sprintf("JWT_SECRET must be at least %d bytes long", minJWTSecretLength))
+		}
+		jwtSecret = []byte(secret)
+	})
+	return jwtSecret
+}
+
+// TokenClaims is the JWT payload used for both access and refresh tokens
+type TokenClaims struct {
+	Subject   string `json:"sub"`
+	Username  string `json:"username"`
+	SessionID string `json:"sid"`
+	TokenType string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UserID returns the numeric user id carried in the subject claim
+func (c *TokenClaims) UserID() int {
+	id, _ := strconv.Atoi(c.Subject)
+	return id
+}
+
+type AuthService struct {
+	secret []byte
+}
+
+func NewAuthService() *AuthService {
+	return &AuthService{
+		secret: getJWTSecret(),
+	}
+}
+
+// IssueTokens starts a new session for the user and returns a signed token pair
+func (s *AuthService) IssueTokens(user *models.User) (*models.TokenPair, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refreshExpiresAt := now.Add(refreshTokenTTL)
+
+	query := `INSERT INTO auth_sessions (user_id, token_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := database.DB.Exec(query, user.ID, sessionID, refreshExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	base := TokenClaims{
+		Subject:   strconv.Itoa(user.ID),
+		Username:  user.Username,
+		SessionID: sessionID,
+		IssuedAt:  now.Unix(),
+	}
+
+	access := base
+	access.TokenType = tokenTypeAccess
+	access.ExpiresAt = now.Add(accessTokenTTL).Unix()
+
+	refresh := base
+	refresh.TokenType = tokenTypeRefresh
+	refresh.ExpiresAt = refreshExpiresAt.Unix()
+
+	accessToken, err := s.sign(access)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.sign(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresAt:    time.Unix(access.ExpiresAt, 0),
+	}, nil
+}
+
+// ValidateAccessToken checks signature, expiry and that the session is still active
+func (s *AuthService) ValidateAccessToken(token string) (*TokenClaims, error) {
+	claims, err := s.parse(token, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkSession(claims.SessionID, claims.UserID()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Refresh rotates a refresh token: the old session is revoked and a new pair is issued.
+// The revoke is the check, so of two requests racing with the same token only one wins.
+// Presenting an already revoked refresh token revokes every session of that user,
+// since it means the token has been replayed.
+func (s *AuthService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	claims, err := s.parse(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE auth_sessions SET revoked_at = NOW()
+		WHERE token_id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id`
+	var id int
+	err = database.DB.QueryRow(query, claims.SessionID, claims.UserID()).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Missing and expired sessions are just invalid; anything else lost the race or
+		// was revoked already, i.e. the token is being reused
+		err = s.checkSession(claims.SessionID, claims.UserID())
+		if err == nil || errors.Is(err, ErrTokenRevoked) {
+			fmt.Printf("[Auth] ⚠️  Refresh token reuse detected for user %s, revoking all sessions\n", claims.Subject)
+			if err := s.RevokeAllSessions(claims.UserID()); err != nil {
+				// The replayed token must not pass for an ordinary revoked one while
+				// the other sessions are still alive
+				fmt.Printf("[Auth] ⚠️  Failed to revoke sessions of user %s: %v\n", claims.Subject, err)
+				return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+			}
+			return nil, ErrTokenRevoked
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	user := &models.User{ID: claims.UserID(), Username: claims.Username}
+	return s.IssueTokens(user)
+}
+
+// RevokeSession marks a session as revoked (logout)
+func (s *AuthService) RevokeSession(sessionID string) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE token_id = $1 AND revoked_at IS NULL`
+	_, err := database.DB.Exec(query, sessionID)
+	return err
+}
+
+// RevokeAllSessions revokes every active session of a user
+func (s *AuthService) RevokeAllSessions(userID int) error {
+	query := `UPDATE auth_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := database.DB.Exec(query, userID)
+	return err
+}
+
+// checkSession verifies the session row exists, belongs to the token's user, is not
+// revoked and not expired
+func (s *AuthService) checkSession(sessionID string, userID int) error {
+	query := `SELECT expires_at, revoked_at FROM auth_sessions WHERE token_id = $1 AND user_id = $2`
+
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := database.DB.QueryRow(query, sessionID, userID).Scan(&expiresAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+
+	if revokedAt.Valid {
+		return ErrTokenRevoked
+	}
+	if time.Now().After(expiresAt) {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// ========== JWT (HS256) Helpers ==========
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (s *AuthService) sign(claims TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + s.signature(signingInput), nil
+}
+
+func (s *AuthService) parse(token string, expectedType string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	expected := s.signature(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.TokenType != expectedType || claims.UserID() == 0 || claims.SessionID == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func (s *AuthService) signature(signingInput string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
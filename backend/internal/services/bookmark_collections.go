@@ -0,0 +1,157 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxCollectionNameLength = 100
+	maxCollectionsPerUser   = 100
+)
+
+var (
+	ErrInvalidCollection  = errors.New("invalid collection")
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrCollectionExists   = errors.New("a collection with this name already exists")
+)
+
+// ListCollections returns the user's collections ordered by name
+func (s *BookmarkService) ListCollections(userID int) ([]models.BookmarkCollection, error) {
+	query := `SELECT c.id, c.name, c.description, c.created_at,
+                  (SELECT COUNT(*) FROM bookmark_collection_items i WHERE i.collection_id = c.id)
+              FROM bookmark_collections c WHERE c.user_id = $1 ORDER BY LOWER(c.name)`
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	collections := []models.BookmarkCollection{}
+	for rows.Next() {
+		var c models.BookmarkCollection
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.ItemCount); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+func (s *BookmarkService) CreateCollection(userID int, req models.CollectionRequest) (*models.BookmarkCollection, error) {
+	if err := validateCollection(&req); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM bookmark_collections WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxCollectionsPerUser {
+		return nil, fmt.Errorf("%w: at most %d collections per user", ErrInvalidCollection, maxCollectionsPerUser)
+	}
+
+	query := `INSERT INTO bookmark_collections (user_id, name, description) VALUES ($1, $2, $3)
+              ON CONFLICT (user_id, name) DO NOTHING
+              RETURNING id, name, description, created_at`
+	var c models.BookmarkCollection
+	err := database.DB.QueryRow(query, userID, req.Name, req.Description).Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// UpdateCollection renames a collection and/or changes its description
+func (s *BookmarkService) UpdateCollection(userID int, collectionID int, req models.CollectionRequest) (*models.BookmarkCollection, error) {
+	if err := validateCollection(&req); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE bookmark_collections SET name = $3, description = $4 WHERE id = $1 AND user_id = $2
+              RETURNING id, name, description, created_at,
+                  (SELECT COUNT(*) FROM bookmark_collection_items i WHERE i.collection_id = $1)`
+	var c models.BookmarkCollection
+	err := database.DB.QueryRow(query, collectionID, userID, req.Name, req.Description).
+		Scan(&c.ID, &c.Name, &c.Description, &c.CreatedAt, &c.ItemCount)
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ErrCollectionNotFound
+	case errors.As(err, &pqErr) && pqErr.Code == "23505": // unique_violation
+		return nil, ErrCollectionExists
+	case err != nil:
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteCollection removes a collection; its bookmarks are kept
+func (s *BookmarkService) DeleteCollection(userID int, collectionID int) error {
+	result, err := database.DB.Exec(`DELETE FROM bookmark_collections WHERE id = $1 AND user_id = $2`, collectionID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrCollectionNotFound
+	}
+	return nil
+}
+
+// AddToCollection puts one of the user's bookmarks in one of their collections
+func (s *BookmarkService) AddToCollection(userID int, collectionID int, bookmarkID int) error {
+	var collectionOwned, bookmarkOwned bool
+	err := database.DB.QueryRow(`SELECT
+                  EXISTS (SELECT 1 FROM bookmark_collections WHERE id = $1 AND user_id = $3),
+                  EXISTS (SELECT 1 FROM bookmarks WHERE id = $2 AND user_id = $3)`,
+		collectionID, bookmarkID, userID).Scan(&collectionOwned, &bookmarkOwned)
+	switch {
+	case err != nil:
+		return err
+	case !collectionOwned:
+		return ErrCollectionNotFound
+	case !bookmarkOwned:
+		return ErrBookmarkNotFound
+	}
+
+	_, err = database.DB.Exec(`INSERT INTO bookmark_collection_items (collection_id, bookmark_id) VALUES ($1, $2)
+              ON CONFLICT DO NOTHING`, collectionID, bookmarkID)
+	return err
+}
+
+func (s *BookmarkService) RemoveFromCollection(userID int, collectionID int, bookmarkID int) error {
+	query := `DELETE FROM bookmark_collection_items i USING bookmark_collections c
+              WHERE i.collection_id = c.id AND c.id = $1 AND c.user_id = $2 AND i.bookmark_id = $3`
+	result, err := database.DB.Exec(query, collectionID, userID, bookmarkID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+func validateCollection(req *models.CollectionRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCollection)
+	}
+	if len([]rune(req.Name)) > maxCollectionNameLength {
+		return fmt.Errorf("%w: name is limited to %d characters", ErrInvalidCollection, maxCollectionNameLength)
+	}
+	if len(req.Description) > maxNotesLength {
+		return fmt.Errorf("%w: description is limited to %d characters", ErrInvalidCollection, maxNotesLength)
+	}
+	return nil
+}
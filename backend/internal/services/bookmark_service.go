@@ -3,39 +3,263 @@ package services
 import (
 	"anime-tanyaayomi/internal/database"
 	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultBookmarkLimit = 50
+	maxBookmarkLimit     = 200
+	maxBookmarkTags      = 20
+	maxTagLength         = 40
+	maxNotesLength       = 5000
 )
 
+var (
+	ErrInvalidBookmark  = errors.New("invalid bookmark")
+	ErrBookmarkNotFound = errors.New("bookmark not found")
+)
+
+// Status aliases accepted from clients; stored values are the list statuses
+var bookmarkStatusAliases = map[string]string{
+	ListStatusPlanning:  ListStatusPlanning,
+	ListStatusCurrent:   ListStatusCurrent,
+	ListStatusCompleted: ListStatusCompleted,
+	ListStatusPaused:    ListStatusPaused,
+	ListStatusDropped:   ListStatusDropped,
+	"watching":          ListStatusCurrent,
+	"reading":           ListStatusCurrent,
+	"on_hold":           ListStatusPaused,
+	"on-hold":           ListStatusPaused,
+}
+
+// Sort keys -> columns (never interpolate user input into ORDER BY)
+var bookmarkSortColumns = map[string]string{
+	"created": "b.created_at",
+	"updated": "b.updated_at",
+	"title":   "LOWER(b.title)",
+	"score":   "b.score",
+}
+
+const bookmarkColumns = `b.id, b.user_id, b.type, b.slug, b.title, COALESCE(b.cover_image, ''), b.status, b.score,
+                  b.notes, b.tags, ARRAY(SELECT i.collection_id FROM bookmark_collection_items i
+                      WHERE i.bookmark_id = b.id ORDER BY i.collection_id),
+                  b.created_at, COALESCE(b.updated_at, b.created_at)`
+
 type BookmarkService struct{}
 
 func NewBookmarkService() *BookmarkService {
 	return &BookmarkService{}
 }
 
+// AddBookmark creates a bookmark; an existing bookmark for the same title is left untouched
 func (s *BookmarkService) AddBookmark(b models.Bookmark) error {
-	query := `INSERT INTO bookmarks (user_id, type, slug, title, cover_image) 
-              VALUES ($1, $2, $3, $4, $5) 
+	status, update, err := normalizeNewBookmark(b)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO bookmarks (user_id, type, slug, title, cover_image, status, score, notes, tags)
+              VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::int, 0), $8, $9)
               ON CONFLICT (user_id, type, slug) DO NOTHING`
-	_, err := database.DB.Exec(query, b.UserID, b.Type, b.Slug, b.Title, b.CoverImage)
+	_, err = database.DB.Exec(query, b.UserID, b.Type, b.Slug, b.Title, b.CoverImage, status,
+		scoreValue(update.Score), *update.Notes, pq.Array(*update.Tags))
 	return err
 }
 
+// ImportBookmark creates a bookmark, or gives an existing one the imported status and score
+// (a missing score keeps the current one; notes and tags are kept). It reports whether the
+// bookmark already existed.
+func (s *BookmarkService) ImportBookmark(b models.Bookmark) (bool, error) {
+	status, update, err := normalizeNewBookmark(b)
+	if err != nil {
+		return false, err
+	}
+
+	query := `INSERT INTO bookmarks (user_id, type, slug, title, cover_image, status, score, notes, tags)
+              VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7::int, 0), $8, $9)
+              ON CONFLICT (user_id, type, slug) DO UPDATE SET
+                  status = EXCLUDED.status,
+                  score = COALESCE(EXCLUDED.score, bookmarks.score),
+                  updated_at = NOW()
+              RETURNING xmax <> 0`
+	var existed bool
+	err = database.DB.QueryRow(query, b.UserID, b.Type, b.Slug, b.Title, b.CoverImage, status,
+		scoreValue(update.Score), *update.Notes, pq.Array(*update.Tags)).Scan(&existed)
+	return existed, err
+}
+
+// normalizeNewBookmark validates a bookmark to insert and returns its status (planning by
+// default) with the normalized score, notes and tags
+func normalizeNewBookmark(b models.Bookmark) (string, models.BookmarkUpdate, error) {
+	if b.Type != "anime" && b.Type != "manga" {
+		return "", models.BookmarkUpdate{}, fmt.Errorf("%w: type must be 'anime' or 'manga'", ErrInvalidBookmark)
+	}
+	if b.Slug == "" || b.Title == "" {
+		return "", models.BookmarkUpdate{}, fmt.Errorf("%w: slug and title are required", ErrInvalidBookmark)
+	}
+
+	update := models.BookmarkUpdate{Notes: &b.Notes, Tags: &b.Tags, Score: b.Score}
+	if b.Status != "" {
+		update.Status = &b.Status
+	}
+	if err := normalizeBookmarkUpdate(&update); err != nil {
+		return "", models.BookmarkUpdate{}, err
+	}
+	status := ListStatusPlanning
+	if update.Status != nil {
+		status = *update.Status
+	}
+	return status, update, nil
+}
+
+// GetBookmarks returns all bookmarks of a user, newest first
 func (s *BookmarkService) GetBookmarks(userID int) ([]models.Bookmark, error) {
-	query := `SELECT id, user_id, type, slug, title, cover_image, created_at FROM bookmarks WHERE user_id = $1`
+	query := `SELECT ` + bookmarkColumns + ` FROM bookmarks b WHERE b.user_id = $1 ORDER BY b.created_at DESC, b.id DESC`
 	rows, err := database.DB.Query(query, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var bookmarks []models.Bookmark
+	bookmarks := []models.Bookmark{}
+	for rows.Next() {
+		b, err := scanBookmark(rows)
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, *b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// ListBookmarks returns one page of filtered, sorted bookmarks
+func (s *BookmarkService) ListBookmarks(userID int, filter models.BookmarkFilter) (*models.BookmarkPage, error) {
+	where := []string{"b.user_id = $1"}
+	args := []interface{}{userID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Type != "" {
+		if filter.Type != "anime" && filter.Type != "manga" {
+			return nil, fmt.Errorf("%w: type must be 'anime' or 'manga'", ErrInvalidBookmark)
+		}
+		where = append(where, "b.type = "+arg(filter.Type))
+	}
+	if filter.Status != "" {
+		status, ok := bookmarkStatusAliases[strings.ToLower(filter.Status)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown status '%s'", ErrInvalidBookmark, filter.Status)
+		}
+		where = append(where, "b.status = "+arg(status))
+	}
+	if filter.Tag != "" {
+		where = append(where, arg(normalizeTag(filter.Tag))+" = ANY(b.tags)")
+	}
+	if filter.CollectionID != 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM bookmark_collection_items i WHERE i.bookmark_id = b.id AND i.collection_id = "+
+			arg(filter.CollectionID)+")")
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+		where = append(where, "b.title ILIKE "+arg("%"+escaped+"%"))
+	}
+
+	sortColumn := bookmarkSortColumns["created"]
+	if filter.Sort != "" {
+		column, ok := bookmarkSortColumns[filter.Sort]
+		if !ok {
+			return nil, fmt.Errorf("%w: sort must be one of created, updated, title, score", ErrInvalidBookmark)
+		}
+		sortColumn = column
+	}
+	direction := "ASC"
+	if filter.Desc {
+		direction = "DESC"
+	}
+
+	if filter.Limit < 1 || filter.Limit > maxBookmarkLimit {
+		filter.Limit = defaultBookmarkLimit
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+
+	// Unrated bookmarks always go last; id keeps pages stable for equal values
+	query := fmt.Sprintf(`SELECT %s, COUNT(*) OVER() FROM bookmarks b WHERE %s
+              ORDER BY %s %s NULLS LAST, b.id %s LIMIT %s OFFSET %s`,
+		bookmarkColumns, strings.Join(where, " AND "), sortColumn, direction, direction,
+		arg(filter.Limit), arg((filter.Page-1)*filter.Limit))
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.BookmarkPage{Items: []models.Bookmark{}, Page: filter.Page, Limit: filter.Limit}
 	for rows.Next() {
-		var b models.Bookmark
-		if err := rows.Scan(&b.ID, &b.UserID, &b.Type, &b.Slug, &b.Title, &b.CoverImage, &b.CreatedAt); err != nil {
+		b, err := scanBookmark(rows, &page.Total)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, *b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if page.Total == 0 && filter.Page > 1 {
+		// Past the last page: COUNT(*) OVER() has no row to report on
+		if err := database.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM bookmarks b WHERE %s`, strings.Join(where, " AND ")),
+			args[:len(args)-2]...).Scan(&page.Total); err != nil {
 			return nil, err
 		}
-		bookmarks = append(bookmarks, b)
 	}
-	return bookmarks, nil
+	page.TotalPages = (page.Total + page.Limit - 1) / page.Limit
+	return page, nil
+}
+
+func (s *BookmarkService) GetBookmark(userID int, bookmarkID int) (*models.Bookmark, error) {
+	query := `SELECT ` + bookmarkColumns + ` FROM bookmarks b WHERE b.id = $1 AND b.user_id = $2`
+	b, err := scanBookmark(database.DB.QueryRow(query, bookmarkID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBookmarkNotFound
+	}
+	return b, err
+}
+
+// UpdateBookmark changes status / score / notes / tags of one bookmark
+func (s *BookmarkService) UpdateBookmark(userID int, bookmarkID int, update models.BookmarkUpdate) (*models.Bookmark, error) {
+	if err := normalizeBookmarkUpdate(&update); err != nil {
+		return nil, err
+	}
+
+	var tags interface{}
+	if update.Tags != nil {
+		tags = pq.Array(*update.Tags)
+	}
+	query := `UPDATE bookmarks SET
+                  status = COALESCE($3, status),
+                  score = CASE WHEN $4::int IS NULL THEN score ELSE NULLIF($4::int, 0) END,
+                  notes = COALESCE($5, notes),
+                  tags = COALESCE($6::text[], tags),
+                  updated_at = NOW()
+              WHERE id = $1 AND user_id = $2`
+	result, err := database.DB.Exec(query, bookmarkID, userID, update.Status, scoreValue(update.Score), update.Notes, tags)
+	if err != nil {
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, ErrBookmarkNotFound
+	}
+	return s.GetBookmark(userID, bookmarkID)
 }
 
 func (s *BookmarkService) RemoveBookmark(userID int, bookmarkID int) error {
@@ -43,3 +267,109 @@ func (s *BookmarkService) RemoveBookmark(userID int, bookmarkID int) error {
 	_, err := database.DB.Exec(query, bookmarkID, userID)
 	return err
 }
+
+// GetTags lists the user's tags with how many bookmarks use each
+func (s *BookmarkService) GetTags(userID int) ([]models.TagCount, error) {
+	query := `SELECT tag, COUNT(*) FROM bookmarks, unnest(tags) AS tag
+              WHERE user_id = $1 GROUP BY tag ORDER BY COUNT(*) DESC, tag`
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var t models.TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBookmark reads bookmarkColumns (plus any extra trailing columns into extra)
+func scanBookmark(row rowScanner, extra ...interface{}) (*models.Bookmark, error) {
+	var b models.Bookmark
+	var score sql.NullInt64
+	var tags pq.StringArray
+	var collections pq.Int64Array
+
+	dest := append([]interface{}{&b.ID, &b.UserID, &b.Type, &b.Slug, &b.Title, &b.CoverImage, &b.Status, &score,
+		&b.Notes, &tags, &collections, &b.CreatedAt, &b.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	if score.Valid {
+		value := int(score.Int64)
+		b.Score = &value
+	}
+	b.Tags = []string(tags)
+	if b.Tags == nil {
+		b.Tags = []string{}
+	}
+	b.Collections = make([]int, len(collections))
+	for i, id := range collections {
+		b.Collections[i] = int(id)
+	}
+	return &b, nil
+}
+
+// normalizeBookmarkUpdate validates the update and canonicalizes status and tags in place
+func normalizeBookmarkUpdate(update *models.BookmarkUpdate) error {
+	if update.Status != nil {
+		status, ok := bookmarkStatusAliases[strings.ToLower(strings.TrimSpace(*update.Status))]
+		if !ok {
+			return fmt.Errorf("%w: status must be one of planning, current (watching/reading), completed, paused (on-hold), dropped",
+				ErrInvalidBookmark)
+		}
+		update.Status = &status
+	}
+	if update.Score != nil && (*update.Score < 0 || *update.Score > 10) {
+		return fmt.Errorf("%w: score must be between 1 and 10 (0 clears it)", ErrInvalidBookmark)
+	}
+	if update.Notes != nil && len(*update.Notes) > maxNotesLength {
+		return fmt.Errorf("%w: notes are limited to %d characters", ErrInvalidBookmark, maxNotesLength)
+	}
+
+	if update.Tags != nil {
+		tags := []string{}
+		seen := make(map[string]bool)
+		for _, tag := range *update.Tags {
+			tag = normalizeTag(tag)
+			if tag == "" || seen[tag] {
+				continue
+			}
+			if len(tag) > maxTagLength {
+				return fmt.Errorf("%w: tags are limited to %d characters", ErrInvalidBookmark, maxTagLength)
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+		if len(tags) > maxBookmarkTags {
+			return fmt.Errorf("%w: at most %d tags per bookmark", ErrInvalidBookmark, maxBookmarkTags)
+		}
+		update.Tags = &tags
+	}
+	return nil
+}
+
+// Tags are case-insensitive: "Isekai " and "isekai" are the same tag
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// scoreValue turns an optional score into a query parameter (nil stays NULL)
+func scoreValue(score *int) interface{} {
+	if score == nil {
+		return nil
+	}
+	return *score
+}
@@ -5,14 +5,55 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// CacheStore is the shared cache tier. Values are stored as JSON and decoded
+// into target on Get, so both implementations behave the same way.
+type CacheStore interface {
+	Get(key string, target interface{}) bool
+	Set(key string, value interface{}, expiration time.Duration)
+	Delete(key string)
+}
+
+var (
+	sharedCache     CacheStore
+	sharedCacheOnce sync.Once
+)
+
+// SharedCache returns the process-wide cache selected by CACHE_BACKEND:
+//   - "redis":  Redis only (still returned if the ping fails, errors are logged)
+//   - "memory": in-process map (not shared across replicas)
+//   - "" (default): Redis when reachable, otherwise in-memory
+func SharedCache() CacheStore {
+	sharedCacheOnce.Do(func() {
+		switch os.Getenv("CACHE_BACKEND") {
+		case "memory":
+			sharedCache = NewCache()
+		case "redis":
+			sharedCache = NewCacheService()
+		default:
+			redisCache := NewCacheService()
+			if redisCache.Available() {
+				sharedCache = redisCache
+			} else {
+				log.Println("[Cache] Redis unavailable, falling back to in-memory cache")
+				sharedCache = NewCache()
+			}
+		}
+	})
+	return sharedCache
+}
+
+// ========== Redis Implementation ==========
+
 type CacheService struct {
-	client *redis.Client
-	ctx    context.Context
+	client    *redis.Client
+	ctx       context.Context
+	available bool
 }
 
 var cacheInstance *CacheService
@@ -29,8 +70,8 @@ func NewCacheService() *CacheService {
 
 	client := redis.NewClient(&redis.Options{
 		Addr:     redisAddr,
-		Password: "", // no password set
-		DB:       0,  // use default DB
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       0, // use default DB
 	})
 
 	ctx := context.Background()
@@ -38,18 +79,23 @@ func NewCacheService() *CacheService {
 	if err != nil {
 		log.Printf("Failed to connect to Redis: %v", err)
 		// We don't return nil, so the app can still run without cache
-		// But ideally we should handle this gracefully
 	} else {
 		log.Println("Connected to Redis successfully")
 	}
 
 	cacheInstance = &CacheService{
-		client: client,
-		ctx:    ctx,
+		client:    client,
+		ctx:       ctx,
+		available: err == nil,
 	}
 	return cacheInstance
 }
 
+// Available reports whether the initial Redis ping succeeded
+func (s *CacheService) Available() bool {
+	return s.available
+}
+
 func (s *CacheService) Get(key string, target interface{}) bool {
 	if s.client == nil {
 		return false
@@ -88,3 +134,91 @@ func (s *CacheService) Set(key string, value interface{}, expiration time.Durati
 		log.Printf("[Cache] ERROR Set: %v for key %s", err, key)
 	}
 }
+
+func (s *CacheService) Delete(key string) {
+	if s.client == nil {
+		return
+	}
+
+	if err := s.client.Del(s.ctx, key).Err(); err != nil {
+		log.Printf("[Cache] ERROR Delete: %v for key %s", err, key)
+	}
+}
+
+// ========== In-Memory Implementation ==========
+
+// Cache entry
+type CacheEntry struct {
+	Data      []byte
+	ExpiresAt time.Time
+}
+
+// Simple in-memory cache
+type Cache struct {
+	store map[string]*CacheEntry
+	mu    sync.RWMutex
+}
+
+func NewCache() *Cache {
+	cache := &Cache{
+		store: make(map[string]*CacheEntry),
+	}
+	// Start cleanup goroutine
+	go cache.cleanup()
+	return cache
+}
+
+func (c *Cache) Get(key string, target interface{}) bool {
+	c.mu.RLock()
+	entry, exists := c.store[key]
+	c.mu.RUnlock()
+
+	if !exists || time.Now().After(entry.ExpiresAt) {
+		return false
+	}
+
+	if err := json.Unmarshal(entry.Data, target); err != nil {
+		log.Printf("[Cache] ERROR Unmarshal: %v", err)
+		return false
+	}
+	return true
+}
+
+func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[Cache] ERROR Marshal: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store[key] = &CacheEntry{
+		Data:      data,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+}
+
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.store, key)
+}
+
+func (c *Cache) cleanup() {
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		now := time.Now()
+		for key, entry := range c.store {
+			if now.After(entry.ExpiresAt) {
+				delete(c.store, key)
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,591 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+
+	defaultDigestInterval = time.Hour
+	maxDigestReleases     = 200
+
+	emailVerificationTTL      = 48 * time.Hour
+	emailVerificationCooldown = 10 * time.Minute
+	emailTestCooldown         = 10 * time.Minute
+)
+
+var (
+	ErrInvalidDigestPreferences = errors.New("invalid email digest preferences")
+	ErrInvalidUnsubscribeToken  = errors.New("invalid unsubscribe link")
+	ErrInvalidVerificationToken = errors.New("invalid or expired confirmation link")
+	ErrEmailThrottled           = errors.New("an email was sent recently, try again in a few minutes")
+	ErrMailerNotConfigured      = errors.New("email is not configured on this server")
+	ErrDigestEmpty              = errors.New("no new releases for this digest")
+)
+
+//go:embed templates/digest.html templates/digest.txt templates/verify.html templates/verify.txt
+var digestTemplateFiles embed.FS
+
+var (
+	digestHTMLTemplate = htmltemplate.Must(htmltemplate.ParseFS(digestTemplateFiles, "templates/digest.html"))
+	digestTextTemplate = texttemplate.Must(texttemplate.ParseFS(digestTemplateFiles, "templates/digest.txt"))
+	verifyHTMLTemplate = htmltemplate.Must(htmltemplate.ParseFS(digestTemplateFiles, "templates/verify.html"))
+	verifyTextTemplate = texttemplate.Must(texttemplate.ParseFS(digestTemplateFiles, "templates/verify.txt"))
+)
+
+// digestData is what the digest templates render
+type digestData struct {
+	Subject        string
+	Username       string
+	Frequency      string
+	PeriodLabel    string
+	ReleaseCount   int
+	MoreReleases   int // Releases past maxDigestReleases, counted but not listed
+	Series         []digestSeries
+	UnsubscribeURL string
+}
+
+// verifyData is what the confirmation templates render
+type verifyData struct {
+	Username   string
+	Frequency  string
+	ConfirmURL string
+}
+
+type digestSeries struct {
+	Type       string
+	Title      string
+	URL        string
+	CoverImage string
+	Releases   []digestRelease
+}
+
+type digestRelease struct {
+	Label string
+	URL   string
+}
+
+type EmailDigestService struct {
+	Mailer     *Mailer // nil when SMTP is not configured
+	BaseURL    string  // Public site URL used in links
+	SigningKey []byte  // Unsubscribe token key; empty (and Mailer nil) when it couldn't be loaded
+}
+
+var (
+	emailDigestService     *EmailDigestService
+	emailDigestServiceOnce sync.Once
+)
+
+// GetEmailDigestService returns the shared service. PUBLIC_BASE_URL is the site URL for links
+// (default http://localhost:4321); EMAIL_SIGNING_KEY signs unsubscribe links (a key generated
+// into email_signing_keys when unset). Without a signing key no email is sent.
+func GetEmailDigestService() *EmailDigestService {
+	emailDigestServiceOnce.Do(func() {
+		emailDigestService = &EmailDigestService{
+			Mailer:  GetMailer(),
+			BaseURL: publicBaseURL(),
+		}
+
+		// Loaded even without SMTP, so links in emails sent before it was turned off still work
+		key := []byte(os.Getenv("EMAIL_SIGNING_KEY"))
+		if len(key) == 0 {
+			var err error
+			if key, err = loadStoredEmailSigningKey(); err != nil {
+				fmt.Printf("[EmailDigest] ⚠️  No email signing key, email digests disabled: %v\n", err)
+				emailDigestService.Mailer = nil
+			}
+		}
+		emailDigestService.SigningKey = key
+	})
+	return emailDigestService
+}
+
+// loadStoredEmailSigningKey reads the key from email_signing_keys, creating it on first use.
+// Concurrent first starts agree on whichever row was inserted first.
+func loadStoredEmailSigningKey() ([]byte, error) {
+	generated := make([]byte, 32)
+	if _, err := rand.Read(generated); err != nil {
+		return nil, err
+	}
+	if _, err := database.DB.Exec(`INSERT INTO email_signing_keys (id, signing_key) VALUES (TRUE, $1)
+              ON CONFLICT (id) DO NOTHING`, base64.RawURLEncoding.EncodeToString(generated)); err != nil {
+		return nil, err
+	}
+
+	var stored string
+	if err := database.DB.QueryRow(`SELECT signing_key FROM email_signing_keys WHERE id`).Scan(&stored); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(stored)
+}
+
+func publicBaseURL() string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimSuffix(base, "/")
+	}
+	return "http://localhost:4321"
+}
+
+func (s *EmailDigestService) GetPreferences(userID int) (*models.EmailDigestPreferences, error) {
+	var p models.EmailDigestPreferences
+	var lastDigest, verifiedAt, verificationSent sql.NullTime
+	err := database.DB.QueryRow(`SELECT email, frequency, COALESCE(pending_frequency, ''), last_digest_at, verified_at,
+                  verification_sent_at, updated_at
+              FROM email_digest_preferences WHERE user_id = $1`, userID).Scan(&p.Email, &p.Frequency, &p.PendingFrequency,
+		&lastDigest, &verifiedAt, &verificationSent, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.EmailDigestPreferences{Frequency: DigestOff}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastDigest.Valid {
+		p.LastDigestAt = &lastDigest.Time
+	}
+	if verificationSent.Valid {
+		p.VerificationSentAt = &verificationSent.Time
+	}
+	p.Verified = verifiedAt.Valid
+	return &p, nil
+}
+
+// SetPreferences saves address and frequency. Turning digests on starts the first period now,
+// so the first email covers a full day/week. Digests only start once the address is confirmed:
+// until then the frequency is kept as pending and a confirmation email is sent (at most one
+// per emailVerificationCooldown).
+func (s *EmailDigestService) SetPreferences(userID int, req models.EmailDigestRequest) (*models.EmailDigestPreferences, error) {
+	req.Frequency = strings.ToLower(strings.TrimSpace(req.Frequency))
+	if req.Frequency != DigestOff && req.Frequency != DigestDaily && req.Frequency != DigestWeekly {
+		return nil, fmt.Errorf("%w: frequency must be off, daily or weekly", ErrInvalidDigestPreferences)
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || len(address.Address) > 255 {
+		return nil, fmt.Errorf("%w: invalid email address", ErrInvalidDigestPreferences)
+	}
+
+	current, err := s.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	frequency, pending := req.Frequency, ""
+	if frequency != DigestOff && !(current.Verified && current.Email == address.Address) {
+		frequency, pending = DigestOff, req.Frequency
+	}
+
+	query := `INSERT INTO email_digest_preferences (user_id, email, frequency, pending_frequency, last_digest_at)
+              VALUES ($1, $2, $3, NULLIF($4, ''), CASE WHEN $5 THEN NOW() END)
+              ON CONFLICT (user_id) DO UPDATE SET
+                  email = EXCLUDED.email,
+                  frequency = EXCLUDED.frequency,
+                  pending_frequency = EXCLUDED.pending_frequency,
+                  verified_at = CASE WHEN email_digest_preferences.email = EXCLUDED.email
+                                     THEN email_digest_preferences.verified_at END,
+                  last_digest_at = CASE WHEN EXCLUDED.frequency = 'off' THEN email_digest_preferences.last_digest_at
+                                        ELSE COALESCE(email_digest_preferences.last_digest_at, NOW()) END,
+                  updated_at = NOW()`
+	if _, err := database.DB.Exec(query, userID, address.Address, frequency, pending, frequency != DigestOff); err != nil {
+		return nil, err
+	}
+
+	if pending != "" {
+		if err := s.SendVerification(userID); err != nil && !errors.Is(err, ErrEmailThrottled) && !errors.Is(err, ErrMailerNotConfigured) {
+			fmt.Printf("[EmailDigest] ⚠️  Confirmation email for user %d failed: %v\n", userID, err)
+		}
+	}
+	return s.GetPreferences(userID)
+}
+
+// SendVerification emails a confirmation link for the saved, unconfirmed address. It returns
+// ErrEmailThrottled when one was sent less than emailVerificationCooldown ago.
+func (s *EmailDigestService) SendVerification(userID int) error {
+	if s.Mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	var email, username, frequency string
+	err := database.DB.QueryRow(`UPDATE email_digest_preferences p SET verification_sent_at = NOW()
+              FROM users u
+              WHERE p.user_id = $1 AND u.id = p.user_id AND p.verified_at IS NULL
+                AND (p.verification_sent_at IS NULL OR p.verification_sent_at < NOW() - make_interval(secs => $2))
+              RETURNING p.email, u.username, COALESCE(p.pending_frequency, 'weekly')`,
+		userID, emailVerificationCooldown.Seconds()).Scan(&email, &username, &frequency)
+	if errors.Is(err, sql.ErrNoRows) {
+		prefs, err := s.GetPreferences(userID)
+		switch {
+		case err != nil:
+			return err
+		case prefs.Email == "":
+			return fmt.Errorf("%w: set an email address first", ErrInvalidDigestPreferences)
+		case prefs.Verified:
+			return fmt.Errorf("%w: the address is already confirmed", ErrInvalidDigestPreferences)
+		}
+		return ErrEmailThrottled
+	}
+	if err != nil {
+		return err
+	}
+
+	token := s.VerificationToken(userID, email, time.Now().Add(emailVerificationTTL))
+	data := verifyData{
+		Username:   username,
+		Frequency:  frequency,
+		ConfirmURL: s.BaseURL + "/api/email/verify?token=" + url.QueryEscape(token),
+	}
+	var html, text bytes.Buffer
+	if err := verifyHTMLTemplate.Execute(&html, data); err != nil {
+		return err
+	}
+	if err := verifyTextTemplate.Execute(&text, data); err != nil {
+		return err
+	}
+	return s.Mailer.Send(EmailMessage{
+		To:      email,
+		Subject: "Confirm your email address for release digests",
+		Text:    text.String(),
+		HTML:    html.String(),
+	})
+}
+
+// VerificationToken signs the user id, the address being confirmed and an expiry
+func (s *EmailDigestService) VerificationToken(userID int, email string, expires time.Time) string {
+	value := strconv.Itoa(userID) + ":" + strconv.FormatInt(expires.Unix(), 10) + ":" + email
+	return base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + s.sign("verify", value)
+}
+
+// Verify confirms the address in a signed token and starts the pending digest. The token
+// only works while that address is still the saved one.
+func (s *EmailDigestService) Verify(token string) error {
+	value, ok := s.verifyToken("verify", token)
+	if !ok {
+		return ErrInvalidVerificationToken
+	}
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return ErrInvalidVerificationToken
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return ErrInvalidVerificationToken
+	}
+
+	result, err := database.DB.Exec(`UPDATE email_digest_preferences SET
+                  verified_at = COALESCE(verified_at, NOW()),
+                  frequency = COALESCE(pending_frequency, frequency),
+                  pending_frequency = NULL,
+                  last_digest_at = CASE WHEN pending_frequency IS NOT NULL THEN COALESCE(last_digest_at, NOW())
+                                        ELSE last_digest_at END,
+                  updated_at = NOW()
+              WHERE user_id = $1 AND email = $2`, userID, parts[2])
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+// UnsubscribeToken signs the user id; links stay valid until the signing key changes
+func (s *EmailDigestService) UnsubscribeToken(userID int) string {
+	id := strconv.Itoa(userID)
+	return base64.RawURLEncoding.EncodeToString([]byte(id)) + "." + s.sign("unsubscribe", id)
+}
+
+func (s *EmailDigestService) sign(purpose string, value string) string {
+	mac := hmac.New(sha256.New, s.SigningKey)
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyToken returns the signed value of a "<base64 value>.<signature>" token
+func (s *EmailDigestService) verifyToken(purpose string, token string) (string, bool) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || len(s.SigningKey) == 0 {
+		return "", false
+	}
+	value, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || !hmac.Equal([]byte(s.sign(purpose, string(value))), []byte(signature)) {
+		return "", false
+	}
+	return string(value), true
+}
+
+// Unsubscribe turns digests off for the user in a signed token
+func (s *EmailDigestService) Unsubscribe(token string) error {
+	id, ok := s.verifyToken("unsubscribe", token)
+	if !ok {
+		return ErrInvalidUnsubscribeToken
+	}
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return ErrInvalidUnsubscribeToken
+	}
+
+	_, err = database.DB.Exec(`UPDATE email_digest_preferences SET frequency = 'off', pending_frequency = NULL,
+              updated_at = NOW() WHERE user_id = $1`, userID)
+	return err
+}
+
+// SendDue sends every digest whose period has ended and returns how many were sent.
+// Each user is claimed by moving last_digest_at first, so several instances can run the job.
+func (s *EmailDigestService) SendDue() (int, error) {
+	if s.Mailer == nil {
+		return 0, ErrMailerNotConfigured
+	}
+
+	// A few minutes of slack keeps an hourly job from drifting a whole hour each period
+	rows, err := database.DB.Query(`SELECT p.user_id, u.username, p.email, p.frequency, p.last_digest_at
+              FROM email_digest_preferences p JOIN users u ON u.id = p.user_id
+              WHERE p.frequency <> 'off' AND p.verified_at IS NOT NULL AND (p.last_digest_at IS NULL OR p.last_digest_at <= NOW()
+                  - CASE p.frequency WHEN 'daily' THEN INTERVAL '1 day' ELSE INTERVAL '7 days' END + INTERVAL '5 minutes')`)
+	if err != nil {
+		return 0, err
+	}
+
+	type dueDigest struct {
+		userID                     int
+		username, email, frequency string
+		since                      sql.NullTime
+	}
+	var due []dueDigest
+	for rows.Next() {
+		var d dueDigest
+		if err := rows.Scan(&d.userID, &d.username, &d.email, &d.frequency, &d.since); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, d := range due {
+		var until time.Time
+		err := database.DB.QueryRow(`UPDATE email_digest_preferences SET last_digest_at = NOW()
+              WHERE user_id = $1 AND last_digest_at IS NOT DISTINCT FROM $2 RETURNING last_digest_at`,
+			d.userID, d.since).Scan(&until)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Another instance took it
+		}
+		if err != nil {
+			return sent, err
+		}
+
+		since := until.Add(-digestPeriod(d.frequency))
+		if d.since.Valid {
+			since = d.since.Time
+		}
+		err = s.sendDigest(d.userID, d.username, d.email, d.frequency, since, until)
+		switch {
+		case err == nil:
+			sent++
+		case errors.Is(err, ErrDigestEmpty):
+			// Nothing new: the period still counts as done
+		default:
+			fmt.Printf("[EmailDigest] ⚠️  Digest for user %d failed: %v\n", d.userID, err)
+			// Give the period back so the next run retries it
+			database.DB.Exec(`UPDATE email_digest_preferences SET last_digest_at = $2 WHERE user_id = $1 AND last_digest_at = $3`,
+				d.userID, d.since, until)
+		}
+	}
+	return sent, nil
+}
+
+// SendPreview sends the user a digest of the last 7 days right away (period unchanged).
+// Only to a confirmed address, and at most once per emailTestCooldown.
+func (s *EmailDigestService) SendPreview(userID int) error {
+	if s.Mailer == nil {
+		return ErrMailerNotConfigured
+	}
+	prefs, err := s.GetPreferences(userID)
+	if err != nil {
+		return err
+	}
+	if prefs.Email == "" {
+		return fmt.Errorf("%w: set an email address first", ErrInvalidDigestPreferences)
+	}
+	if !prefs.Verified {
+		return fmt.Errorf("%w: confirm your email address first", ErrInvalidDigestPreferences)
+	}
+
+	result, err := database.DB.Exec(`UPDATE email_digest_preferences SET test_sent_at = NOW()
+              WHERE user_id = $1 AND (test_sent_at IS NULL OR test_sent_at < NOW() - make_interval(secs => $2))`,
+		userID, emailTestCooldown.Seconds())
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrEmailThrottled
+	}
+
+	var username string
+	if err := database.DB.QueryRow(`SELECT username FROM users WHERE id = $1`, userID).Scan(&username); err != nil {
+		return err
+	}
+	now := time.Now()
+	return s.sendDigest(userID, username, prefs.Email, DigestWeekly, now.Add(-digestPeriod(DigestWeekly)), now)
+}
+
+func (s *EmailDigestService) sendDigest(userID int, username, email, frequency string, since, until time.Time) error {
+	notifications, total, err := digestNotifications(userID, since, until)
+	if err != nil {
+		return err
+	}
+	if len(notifications) == 0 {
+		return ErrDigestEmpty
+	}
+
+	data := s.buildDigest(username, frequency, notifications, total)
+	data.UnsubscribeURL = s.BaseURL + "/api/email/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken(userID))
+
+	var html, text bytes.Buffer
+	if err := digestHTMLTemplate.Execute(&html, data); err != nil {
+		return err
+	}
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(EmailMessage{
+		To:      email,
+		Subject: data.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Headers: map[string]string{
+			// RFC 8058 one-click unsubscribe
+			"List-Unsubscribe":      "<" + data.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	})
+}
+
+// buildDigest groups releases by series, in the order the series first got a release.
+// total is the number of releases in the period, which may exceed the ones listed.
+func (s *EmailDigestService) buildDigest(username, frequency string, notifications []models.Notification, total int) digestData {
+	data := digestData{Username: username, Frequency: frequency, ReleaseCount: total, MoreReleases: total - len(notifications)}
+
+	index := make(map[string]int)
+	for _, n := range notifications {
+		key := n.Type + "/" + n.Slug
+		i, ok := index[key]
+		if !ok {
+			i = len(data.Series)
+			index[key] = i
+			data.Series = append(data.Series, digestSeries{
+				Type:       n.Type,
+				Title:      n.Title,
+				URL:        s.BaseURL + "/" + n.Type + "/" + url.PathEscape(n.Slug),
+				CoverImage: n.CoverImage,
+			})
+		}
+		data.Series[i].Releases = append(data.Series[i].Releases, digestRelease{Label: n.Release, URL: s.BaseURL + NotificationPath(n)})
+	}
+
+	switch frequency {
+	case DigestDaily:
+		data.PeriodLabel = "today"
+	default:
+		data.PeriodLabel = "this week"
+	}
+	plural := "s"
+	if data.ReleaseCount == 1 {
+		plural = ""
+	}
+	data.Subject = fmt.Sprintf("%d new release%s %s: %s", data.ReleaseCount, plural, data.PeriodLabel, data.Series[0].Title)
+	if len(data.Series) > 1 {
+		data.Subject += fmt.Sprintf(" and %d more", len(data.Series)-1)
+	}
+	return data
+}
+
+// digestNotifications returns the first maxDigestReleases notifications of the period and
+// how many the period has in total; the window count is taken before the LIMIT applies
+func digestNotifications(userID int, since, until time.Time) ([]models.Notification, int, error) {
+	rows, err := database.DB.Query(`SELECT type, slug, title, release, COALESCE(target_slug, ''), COALESCE(cover_image, ''),
+                     COUNT(*) OVER ()
+              FROM notifications WHERE user_id = $1 AND created_at > $2 AND created_at <= $3
+              ORDER BY created_at, id LIMIT $4`, userID, since, until, maxDigestReleases)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	var total int
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.Type, &n.Slug, &n.Title, &n.Release, &n.TargetSlug, &n.CoverImage, &total); err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+func digestPeriod(frequency string) time.Duration {
+	if frequency == DigestDaily {
+		return 24 * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// StartDigestScheduler runs SendDue every EMAIL_DIGEST_INTERVAL (default 1h) when SMTP is configured
+func StartDigestScheduler() {
+	service := GetEmailDigestService()
+	if service.Mailer == nil {
+		fmt.Println("[EmailDigest] SMTP_HOST not set, email digests disabled")
+		return
+	}
+
+	interval := defaultDigestInterval
+	if value := os.Getenv("EMAIL_DIGEST_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			interval = parsed
+		} else {
+			fmt.Printf("[EmailDigest] ⚠️  Invalid EMAIL_DIGEST_INTERVAL %q, using %s\n", value, interval)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if sent, err := service.SendDue(); err != nil {
+				fmt.Printf("[EmailDigest] ⚠️  Digest run failed: %v\n", err)
+			} else if sent > 0 {
+				fmt.Printf("[EmailDigest] Sent %d digests\n", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
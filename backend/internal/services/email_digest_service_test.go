@@ -0,0 +1,45 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildDigestCountsUnlistedReleases(t *testing.T) {
+	s := &EmailDigestService{BaseURL: "https://example.com"}
+	notifications := []models.Notification{
+		{Type: "anime", Slug: "frieren", Title: "Frieren", Release: "Episode 12"},
+		{Type: "manga", Slug: "one-piece", Title: "One Piece", Release: "Chapter 1100"},
+		{Type: "anime", Slug: "frieren", Title: "Frieren", Release: "Episode 13"},
+	}
+
+	data := s.buildDigest("alice", DigestDaily, notifications, 5)
+	if data.ReleaseCount != 5 || data.MoreReleases != 2 {
+		t.Errorf("ReleaseCount, MoreReleases = %d, %d, want 5, 2", data.ReleaseCount, data.MoreReleases)
+	}
+	if len(data.Series) != 2 || len(data.Series[0].Releases) != 2 {
+		t.Errorf("series = %+v, want Frieren with 2 releases and One Piece", data.Series)
+	}
+	if want := "5 new releases today: Frieren and 1 more"; data.Subject != want {
+		t.Errorf("subject = %q, want %q", data.Subject, want)
+	}
+
+	var text bytes.Buffer
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "...and 2 more releases not listed here.") {
+		t.Errorf("text digest does not mention the unlisted releases:\n%s", text.String())
+	}
+
+	data = s.buildDigest("alice", DigestDaily, notifications, len(notifications))
+	text.Reset()
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(text.String(), "more release") {
+		t.Errorf("text digest mentions unlisted releases when all are listed:\n%s", text.String())
+	}
+}
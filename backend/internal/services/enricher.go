@@ -0,0 +1,182 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Enricher fills in metadata (year, rating, genres, ...) for a title, usually with an LLM
+type Enricher interface {
+	// Name is stored as enriched_metadata.source
+	Name() string
+	Enrich(title string, mediaType string) (EnrichedData, error)
+}
+
+var (
+	enricher     Enricher
+	enricherOnce sync.Once
+	enricherMu   sync.RWMutex
+)
+
+// GetEnricher returns the configured backend, or nil when enrichment is disabled.
+// ENRICHER picks it: "gemini" (GEMINI_API_KEY, GEMINI_MODEL), "openai" for any
+// OpenAI-compatible server such as Ollama or llama.cpp (OPENAI_BASE_URL, OPENAI_MODEL,
+// OPENAI_API_KEY), "fake" for offline testing, or "none". When unset, Gemini is used if
+// GEMINI_API_KEY is set.
+func GetEnricher() Enricher {
+	enricherOnce.Do(func() {
+		configured, err := enricherFromEnv()
+		if err != nil {
+			fmt.Printf("[Enricher] ⚠️  %v, metadata enrichment disabled\n", err)
+		} else if configured == nil {
+			fmt.Println("[Enricher] No backend configured, metadata enrichment disabled")
+		} else {
+			fmt.Printf("[Enricher] Using %s backend\n", configured.Name())
+		}
+
+		enricherMu.Lock()
+		if enricher == nil {
+			enricher = configured
+		}
+		enricherMu.Unlock()
+	})
+
+	enricherMu.RLock()
+	defer enricherMu.RUnlock()
+	return enricher
+}
+
+// SetEnricher replaces the backend, e.g. with a FakeEnricher in tests
+func SetEnricher(e Enricher) {
+	enricherOnce.Do(func() {}) // Don't let a later GetEnricher overwrite it
+	enricherMu.Lock()
+	enricher = e
+	enricherMu.Unlock()
+}
+
+func enricherFromEnv() (Enricher, error) {
+	backend := strings.ToLower(strings.TrimSpace(os.Getenv("ENRICHER")))
+	if backend == "" && os.Getenv("GEMINI_API_KEY") != "" {
+		backend = "gemini"
+	}
+
+	switch backend {
+	case "", "none":
+		return nil, nil
+	case "gemini":
+		return NewGeminiEnricher(os.Getenv("GEMINI_API_KEY"), os.Getenv("GEMINI_MODEL"))
+	case "openai":
+		return NewOpenAIEnricher(os.Getenv("OPENAI_BASE_URL"), os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_MODEL"))
+	case "fake":
+		return FakeEnricher{}, nil
+	}
+	return nil, fmt.Errorf("unknown ENRICHER %q", backend)
+}
+
+// enrichmentPrompt is the instruction shared by the LLM backends
+func enrichmentPrompt(title string, mediaType string) string {
+	return fmt.Sprintf(`Identify the %s "%s".
+    Return a strictly valid JSON object (no markdown formatting) with these fields:
+    - "year": (string) Release year (e.g. "2023").
+    - "rating": (string) Average score 0-10 (e.g. "8.5").
+    - "status": (string) "Ongoing" or "Completed".
+    - "author": (string) Original creator/mangaka.
+    - "genre": (string) Comma-separated genres (e.g. "Action, Adventure").
+    - "synopsis": (string) A very short, engaging 1-sentence summary.
+    If unknown, return generic/empty values but valid JSON.`, mediaType, title)
+}
+
+// parseEnrichmentJSON reads the model's answer, which may be wrapped in a markdown code block
+func parseEnrichmentJSON(text string) (EnrichedData, error) {
+	raw := strings.TrimSpace(text)
+	raw = strings.TrimPrefix(raw, "```json")
+	raw = strings.TrimPrefix(raw, "```")
+	raw = strings.TrimSuffix(raw, "```")
+	raw = strings.TrimSpace(raw)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return EnrichedData{}, fmt.Errorf("model returned invalid JSON: %w", err)
+	}
+
+	// Models don't reliably follow the "(string)" in the prompt, so numbers and genre arrays
+	// are accepted too; ValidateEnrichment checks the values
+	result := EnrichedData{LastUpdated: time.Now().Unix()}
+	targets := map[string]*string{
+		"year":     &result.Year,
+		"rating":   &result.Rating,
+		"synopsis": &result.Synopsis,
+		"status":   &result.Status,
+		"author":   &result.Author,
+		"genre":    &result.Genre,
+	}
+	for key, target := range targets {
+		value, err := enrichmentString(fields[key])
+		if err != nil {
+			return EnrichedData{}, fmt.Errorf("model returned invalid %q: %w", key, err)
+		}
+		*target = value
+	}
+	return result, nil
+}
+
+func enrichmentString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("expected a list of strings")
+			}
+			parts = append(parts, text)
+		}
+		return strings.Join(parts, ", "), nil
+	}
+	return "", fmt.Errorf("unexpected %T", value)
+}
+
+var errEmptyCompletion = errors.New("model returned no content")
+
+// FakeEnricher derives stable metadata from the title without any network access
+type FakeEnricher struct{}
+
+func (FakeEnricher) Name() string {
+	return "fake"
+}
+
+func (FakeEnricher) Enrich(title string, mediaType string) (EnrichedData, error) {
+	h := fnv.New32a()
+	h.Write([]byte(mediaType + ":" + title))
+	sum := h.Sum32()
+
+	genres := []string{"Action", "Adventure", "Comedy", "Drama", "Fantasy", "Romance", "Slice of Life", "Sci-Fi"}
+	n := uint32(len(genres))
+	first := sum % n
+	status := "Ongoing"
+	if sum%2 == 0 {
+		status = "Completed"
+	}
+	return EnrichedData{
+		Year:        fmt.Sprintf("%d", 1990+sum%35),
+		Rating:      fmt.Sprintf("%d.%d", 5+sum%5, sum/7%10),
+		Synopsis:    fmt.Sprintf("Placeholder synopsis for the %s %s.", mediaType, title),
+		Status:      status,
+		Author:      fmt.Sprintf("Author %d", sum%1000),
+		Genre:       genres[first] + ", " + genres[(first+1+sum/11%(n-1))%n],
+		LastUpdated: time.Now().Unix(),
+	}, nil
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultEnrichedMetadataLimit = 30
+	maxEnrichedMetadataLimit     = 100
+)
+
+var (
+	ErrInvalidEnrichedMetadata  = errors.New("invalid enriched metadata")
+	ErrEnrichedMetadataNotFound = errors.New("enriched metadata not found")
+	ErrEnrichedMetadataExists   = errors.New("enriched metadata already exists for this title")
+	ErrRevisionNotFound         = errors.New("revision not found")
+)
+
+// EnrichmentAdminService lets curators review and override enriched_metadata. Edits set
+// source to "manual" and lock the row unless asked not to, so the enricher can't undo them.
+// Every write is recorded in the row's revision history under the admin's user id.
+type EnrichmentAdminService struct {
+	Repo *EnrichmentRepository
+}
+
+func NewEnrichmentAdminService() *EnrichmentAdminService {
+	return &EnrichmentAdminService{Repo: getEnrichmentRepo()}
+}
+
+// List returns one page of rows; filter values are checked here
+func (s *EnrichmentAdminService) List(filter models.EnrichedMetadataFilter) ([]models.EnrichedMetadata, int, error) {
+	if filter.MediaType != "" && filter.MediaType != "anime" && filter.MediaType != "manga" {
+		return nil, 0, fmt.Errorf("%w: mediaType must be 'anime' or 'manga'", ErrInvalidEnrichedMetadata)
+	}
+	if filter.Limit < 1 || filter.Limit > maxEnrichedMetadataLimit {
+		filter.Limit = defaultEnrichedMetadataLimit
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	return s.Repo.List(filter)
+}
+
+func (s *EnrichmentAdminService) Get(id int) (*models.EnrichedMetadata, error) {
+	data, err := s.Repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, ErrEnrichedMetadataNotFound
+	}
+	return data, nil
+}
+
+// Create adds a manual row for a title the enricher hasn't got (or got nothing for)
+func (s *EnrichmentAdminService) Create(actorID int, req models.EnrichedMetadataRequest) (*models.EnrichedMetadata, error) {
+	data := &models.EnrichedMetadata{Type: "Anime", Source: "manual", Locked: true}
+	if req.Title != nil {
+		data.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.MediaType != nil {
+		data.MediaType = *req.MediaType
+	}
+	if data.Title == "" || len(data.Title) > maxEnrichmentTitleLength {
+		return nil, fmt.Errorf("%w: title is required (at most %d characters)", ErrInvalidEnrichedMetadata, maxEnrichmentTitleLength)
+	}
+	if data.MediaType != "anime" && data.MediaType != "manga" {
+		return nil, fmt.Errorf("%w: mediaType must be 'anime' or 'manga'", ErrInvalidEnrichedMetadata)
+	}
+	if err := applyEnrichedMetadata(data, req); err != nil {
+		return nil, err
+	}
+
+	created, err := s.Repo.Create(data, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if created == nil {
+		return nil, ErrEnrichedMetadataExists
+	}
+	s.forget(created)
+	return created, nil
+}
+
+// Update changes the fields present in req. Field edits make the row manual; a request
+// that only sets locked/needsReview (e.g. approving a flagged row) keeps the source.
+func (s *EnrichmentAdminService) Update(actorID int, id int, req models.EnrichedMetadataRequest) (*models.EnrichedMetadata, error) {
+	data, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	edited := req.Slug != nil || req.Author != nil || req.Genre != nil || req.Type != nil || req.Rating != nil ||
+		req.Status != nil || req.ReleaseYear != nil || req.Synopsis != nil
+	if edited {
+		data.Source = "manual"
+		data.Confidence = nil
+		data.NeedsReview = false
+		data.ReviewReason = ""
+		data.Locked = true
+	}
+	if err := applyEnrichedMetadata(data, req); err != nil {
+		return nil, err
+	}
+	if !data.NeedsReview {
+		data.ReviewReason = ""
+	}
+
+	updated, err := s.Repo.Update(data, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrEnrichedMetadataNotFound
+	}
+	s.forget(updated)
+	return updated, nil
+}
+
+// SetLocked locks or unlocks a row without editing it
+func (s *EnrichmentAdminService) SetLocked(actorID int, id int, locked bool) (*models.EnrichedMetadata, error) {
+	return s.Update(actorID, id, models.EnrichedMetadataRequest{Locked: &locked})
+}
+
+// Delete removes a row; the title is enriched again the next time a page shows it
+func (s *EnrichmentAdminService) Delete(actorID int, id int) error {
+	deleted, err := s.Repo.Delete(id, actorID)
+	if err != nil {
+		return err
+	}
+	if deleted == nil {
+		return ErrEnrichedMetadataNotFound
+	}
+	s.forget(deleted)
+
+	// Drop the finished job too, or the queue would treat the title as done for a week
+	if _, err := database.DB.Exec(`DELETE FROM enrichment_jobs WHERE title = $1 AND media_type = $2 AND status <> 'running'`,
+		deleted.Title, deleted.MediaType); err != nil {
+		fmt.Printf("[EnrichmentAdmin] ⚠️  Failed to reset enrichment job for '%s': %v\n", deleted.Title, err)
+	}
+	return nil
+}
+
+// Revisions returns one page of a title's history, newest first. It includes revisions of
+// deleted rows, which is how a deleted title is restored.
+func (s *EnrichmentAdminService) Revisions(title string, mediaType string, page int, limit int) ([]models.EnrichedMetadataRevision, int, error) {
+	if title == "" {
+		return nil, 0, fmt.Errorf("%w: title is required", ErrInvalidEnrichedMetadata)
+	}
+	if mediaType != "anime" && mediaType != "manga" {
+		return nil, 0, fmt.Errorf("%w: mediaType must be 'anime' or 'manga'", ErrInvalidEnrichedMetadata)
+	}
+	return s.Repo.Revisions(title, mediaType, page, limit)
+}
+
+// RowRevisions is Revisions for the title of an existing row
+func (s *EnrichmentAdminService) RowRevisions(id int, page int, limit int) ([]models.EnrichedMetadataRevision, int, error) {
+	data, err := s.Get(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.Repo.Revisions(data.Title, data.MediaType, page, limit)
+}
+
+func (s *EnrichmentAdminService) GetRevision(id int64) (*models.EnrichedMetadataRevision, error) {
+	revision, err := s.Repo.GetRevision(id)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, nil
+}
+
+// Restore puts the row back the way it was in a revision (for a delete revision: the
+// values it was deleted with), recreating it if needed. The restore is a new revision.
+func (s *EnrichmentAdminService) Restore(actorID int, revisionID int64) (*models.EnrichedMetadata, error) {
+	revision, err := s.GetRevision(revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := s.Repo.Restore(revision, actorID)
+	if err != nil {
+		return nil, err
+	}
+	s.forget(restored)
+	return restored, nil
+}
+
+// forget drops the cached enrichment so the change shows up right away
+func (s *EnrichmentAdminService) forget(data *models.EnrichedMetadata) {
+	SharedCache().Delete(enrichmentCacheKey(data.Title, data.MediaType))
+}
+
+// applyEnrichedMetadata copies the set fields of req onto data. Values are checked like
+// enricher output (year range, 0-10 rating, status, genre taxonomy) but any problem is an
+// error instead of a lower confidence; empty strings clear a field.
+func applyEnrichedMetadata(data *models.EnrichedMetadata, req models.EnrichedMetadataRequest) error {
+	if req.Slug != nil {
+		data.Slug = strings.TrimSpace(*req.Slug)
+	}
+	if req.Author != nil {
+		author := strings.Join(strings.Fields(*req.Author), " ")
+		if len(author) > maxEnrichedAuthorLength {
+			return fmt.Errorf("%w: author is too long", ErrInvalidEnrichedMetadata)
+		}
+		data.Author = author
+	}
+	if req.Genre != nil {
+		genres, unknown := normalizeGenres(*req.Genre)
+		if len(unknown) > 0 {
+			return fmt.Errorf("%w: unknown genres: %s", ErrInvalidEnrichedMetadata, strings.Join(unknown, ", "))
+		}
+		data.Genre = strings.Join(genres, ", ")
+	}
+	if req.Type != nil {
+		data.Type = strings.TrimSpace(*req.Type)
+		if len(data.Type) > 50 {
+			return fmt.Errorf("%w: type is too long", ErrInvalidEnrichedMetadata)
+		}
+	}
+	if req.Rating != nil {
+		rating, issue := normalizeRating(*req.Rating)
+		if issue != "" {
+			return fmt.Errorf("%w: %s", ErrInvalidEnrichedMetadata, issue)
+		}
+		data.Rating = rating
+	}
+	if req.Status != nil {
+		status, issue := normalizeStatus(*req.Status)
+		if issue != "" {
+			return fmt.Errorf("%w: %s", ErrInvalidEnrichedMetadata, issue)
+		}
+		data.Status = status
+	}
+	if req.ReleaseYear != nil {
+		year, issue := normalizeReleaseYear(*req.ReleaseYear)
+		if issue != "" {
+			return fmt.Errorf("%w: %s", ErrInvalidEnrichedMetadata, issue)
+		}
+		data.ReleaseYear = year
+	}
+	if req.Synopsis != nil {
+		synopsis := strings.TrimSpace(*req.Synopsis)
+		if len(synopsis) > maxEnrichedSynopsisLength {
+			return fmt.Errorf("%w: synopsis is too long", ErrInvalidEnrichedMetadata)
+		}
+		data.Synopsis = synopsis
+	}
+	if req.Locked != nil {
+		data.Locked = *req.Locked
+	}
+	if req.NeedsReview != nil {
+		data.NeedsReview = *req.NeedsReview
+	}
+	return nil
+}
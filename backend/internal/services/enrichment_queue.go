@@ -0,0 +1,449 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultEnrichmentInterval      = 5 * time.Second
+	defaultEnrichmentWorkers       = 2
+	defaultEnrichmentRatePerMinute = 20
+	defaultEnrichmentDailyBudget   = 1000
+	maxEnrichmentAttempts          = 5
+	enrichmentBaseBackoff          = time.Minute
+	enrichmentLease                = 5 * time.Minute
+	enrichmentRetryAfter           = 7 // days before a rejected/failed title is queued again
+	enrichmentJobRetention         = 30
+	maxEnrichmentTitleLength       = 500
+)
+
+var (
+	ErrEnrichmentRateLimited     = errors.New("enrichment rate limit reached")
+	ErrEnrichmentBudgetExhausted = errors.New("daily enrichment budget used up")
+	ErrEnrichmentJobNotFound     = errors.New("enrichment job not found or not retryable")
+)
+
+// EnrichmentQueue fills in enriched_metadata in the background. List pages show whatever is
+// already stored and queue the rest (enrichment_jobs); workers claim jobs with SKIP LOCKED,
+// so every instance can run them. All LLM calls, including the inline ones on detail pages,
+// share a per-minute rate and a daily budget counted in enrichment_usage.
+type EnrichmentQueue struct {
+	Interval      time.Duration
+	Workers       int
+	RatePerMinute int // 0 = unlimited
+	DailyBudget   int // 0 = unlimited
+
+	startOnce   sync.Once
+	mu          sync.Mutex
+	pausedUntil time.Time
+	lastPrune   time.Time
+}
+
+var (
+	enrichmentQueue     *EnrichmentQueue
+	enrichmentQueueOnce sync.Once
+)
+
+// GetEnrichmentQueue returns the shared queue. ENRICHMENT_POLL_INTERVAL (default 5s),
+// ENRICHMENT_WORKERS (default 2), ENRICHMENT_RATE_PER_MINUTE (default 20) and
+// ENRICHMENT_DAILY_BUDGET (default 1000 LLM calls, 0 for no limit) configure it.
+func GetEnrichmentQueue() *EnrichmentQueue {
+	enrichmentQueueOnce.Do(func() {
+		interval := defaultEnrichmentInterval
+		if value := os.Getenv("ENRICHMENT_POLL_INTERVAL"); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				interval = parsed
+			} else {
+				fmt.Printf("[EnrichmentQueue] ⚠️  Invalid ENRICHMENT_POLL_INTERVAL %q, using %s\n", value, interval)
+			}
+		}
+		enrichmentQueue = &EnrichmentQueue{
+			Interval:      interval,
+			Workers:       enrichmentIntFromEnv("ENRICHMENT_WORKERS", defaultEnrichmentWorkers, 1),
+			RatePerMinute: enrichmentIntFromEnv("ENRICHMENT_RATE_PER_MINUTE", defaultEnrichmentRatePerMinute, 0),
+			DailyBudget:   enrichmentIntFromEnv("ENRICHMENT_DAILY_BUDGET", defaultEnrichmentDailyBudget, 0),
+		}
+	})
+	return enrichmentQueue
+}
+
+func enrichmentIntFromEnv(name string, fallback int, minimum int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < minimum {
+		fmt.Printf("[EnrichmentQueue] ⚠️  Invalid %s %q, using %d\n", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func (q *EnrichmentQueue) Start() {
+	q.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(q.Interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if err := q.run(); err != nil {
+					fmt.Printf("[EnrichmentQueue] ⚠️  Run failed: %v\n", err)
+				}
+			}
+		}()
+	})
+}
+
+// Enqueue queues titles for enrichment. Titles already queued are left alone; ones that were
+// rejected or failed are queued again after a week.
+func (q *EnrichmentQueue) Enqueue(mediaType string, titles []string) error {
+	valid := make([]string, 0, len(titles))
+	for _, title := range titles {
+		if title != "" && len(title) <= maxEnrichmentTitleLength {
+			valid = append(valid, title)
+		}
+	}
+	if len(valid) == 0 || database.DB == nil {
+		return nil
+	}
+
+	_, err := database.DB.Exec(`INSERT INTO enrichment_jobs (title, media_type)
+              SELECT DISTINCT unnest($1::text[]), $2::varchar
+              ON CONFLICT (title, media_type) DO UPDATE
+              SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = NULL, updated_at = NOW()
+              WHERE enrichment_jobs.status IN ('done', 'rejected', 'failed')
+                AND enrichment_jobs.updated_at < NOW() - make_interval(days => $3)`,
+		pq.Array(valid), mediaType, enrichmentRetryAfter)
+	return err
+}
+
+// Reject records a title the enricher answered for outside the queue (a detail page) whose
+// result failed validation, so it shows up with the queue's own rejections and isn't queued
+// again for a week. A job that's running is left to its worker.
+func (q *EnrichmentQueue) Reject(mediaType string, title string, reason string) error {
+	if title == "" || len(title) > maxEnrichmentTitleLength || database.DB == nil {
+		return nil
+	}
+
+	_, err := database.DB.Exec(`INSERT INTO enrichment_jobs (title, media_type, status, attempts, last_error)
+              VALUES ($1, $2, 'rejected', 1, $3)
+              ON CONFLICT (title, media_type) DO UPDATE
+              SET status = 'rejected', last_error = EXCLUDED.last_error, locked_until = NULL, updated_at = NOW()
+              WHERE enrichment_jobs.status <> 'running'`, title, mediaType, reason)
+	return err
+}
+
+// StoredEnrichments returns what enriched_metadata already holds for titles, keyed by title,
+// without calling the enricher. Titles it has nothing for are queued.
+func StoredEnrichments(titles []string, mediaType string) map[string]EnrichedData {
+	results := make(map[string]EnrichedData)
+	repo := getEnrichmentRepo()
+	if repo == nil || len(titles) == 0 {
+		return results
+	}
+
+	stored, err := repo.GetByTitles(titles, mediaType)
+	if err != nil {
+		fmt.Printf("[EnrichmentQueue] ⚠️  Lookup failed: %v\n", err)
+		return results
+	}
+
+	var missing []string
+	for _, title := range titles {
+		if data, ok := stored[title]; ok {
+			results[title] = convertDBToEnrichedData(data)
+		} else {
+			missing = append(missing, title)
+		}
+	}
+	if len(missing) > 0 && GetEnricher() != nil {
+		if err := GetEnrichmentQueue().Enqueue(mediaType, missing); err != nil {
+			fmt.Printf("[EnrichmentQueue] ⚠️  Failed to queue %d titles: %v\n", len(missing), err)
+		}
+	}
+	return results
+}
+
+// Reserve takes one LLM call from the shared rate and budget. It returns
+// ErrEnrichmentRateLimited or ErrEnrichmentBudgetExhausted when there is none left.
+func (q *EnrichmentQueue) Reserve() error {
+	if database.DB == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	if q.RatePerMinute > 0 {
+		if err := reserveEnrichmentCall("minute:"+now.Format("2006-01-02T15:04"), q.RatePerMinute, 2*time.Minute); err != nil {
+			if errors.Is(err, errEnrichmentBucketFull) {
+				return ErrEnrichmentRateLimited
+			}
+			return err
+		}
+	}
+	if q.DailyBudget > 0 {
+		if err := reserveEnrichmentCall("day:"+now.Format("2006-01-02"), q.DailyBudget, 48*time.Hour); err != nil {
+			if errors.Is(err, errEnrichmentBucketFull) {
+				return ErrEnrichmentBudgetExhausted
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+var errEnrichmentBucketFull = errors.New("enrichment usage bucket full")
+
+func reserveEnrichmentCall(bucket string, limit int, keep time.Duration) error {
+	var calls int
+	err := database.DB.QueryRow(`INSERT INTO enrichment_usage (bucket, calls, expires_at)
+              VALUES ($1, 1, NOW() + make_interval(secs => $3))
+              ON CONFLICT (bucket) DO UPDATE SET calls = enrichment_usage.calls + 1
+              WHERE enrichment_usage.calls < $2
+              RETURNING calls`, bucket, limit, keep.Seconds()).Scan(&calls)
+	if err == sql.ErrNoRows {
+		return errEnrichmentBucketFull
+	}
+	return err
+}
+
+// run works through due jobs until none are left or the rate/budget runs out
+func (q *EnrichmentQueue) run() error {
+	backend := GetEnricher()
+	q.mu.Lock()
+	paused := time.Now().Before(q.pausedUntil)
+	q.mu.Unlock()
+
+	if backend != nil && !paused {
+		for {
+			jobs, err := q.claim(q.Workers)
+			if err != nil {
+				return err
+			}
+
+			var wg sync.WaitGroup
+			for _, job := range jobs {
+				wg.Add(1)
+				go func(job models.EnrichmentJob) {
+					defer wg.Done()
+					q.process(backend, job)
+				}(job)
+			}
+			wg.Wait()
+
+			q.mu.Lock()
+			paused = time.Now().Before(q.pausedUntil)
+			q.mu.Unlock()
+			if len(jobs) < q.Workers || paused {
+				break
+			}
+		}
+	}
+
+	if time.Since(q.lastPrune) > time.Hour {
+		q.lastPrune = time.Now()
+		if _, err := database.DB.Exec(`DELETE FROM enrichment_usage WHERE expires_at < NOW()`); err != nil {
+			return err
+		}
+		if _, err := database.DB.Exec(`DELETE FROM enrichment_jobs WHERE status = 'done'
+              AND updated_at < NOW() - make_interval(days => $1)`, enrichmentJobRetention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claim takes up to limit due jobs (and running ones whose worker died), leasing them
+func (q *EnrichmentQueue) claim(limit int) ([]models.EnrichmentJob, error) {
+	rows, err := database.DB.Query(`UPDATE enrichment_jobs
+              SET status = 'running', attempts = attempts + 1, locked_until = NOW() + make_interval(secs => $2),
+                  updated_at = NOW()
+              WHERE id IN (
+                  SELECT id FROM enrichment_jobs
+                  WHERE (status = 'pending' AND next_attempt_at <= NOW()) OR (status = 'running' AND locked_until < NOW())
+                  ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED)
+              RETURNING id, title, media_type, attempts`, limit, enrichmentLease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.EnrichmentJob
+	for rows.Next() {
+		var job models.EnrichmentJob
+		if err := rows.Scan(&job.ID, &job.Title, &job.MediaType, &job.Attempts); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (q *EnrichmentQueue) process(backend Enricher, job models.EnrichmentJob) {
+	repo := getEnrichmentRepo()
+
+	// A detail page may have filled it in since it was queued
+	if existing, err := repo.GetByTitle(job.Title, job.MediaType); err == nil && existing != nil {
+		q.finish(job, "done", "")
+		return
+	}
+
+	if err := q.Reserve(); err != nil {
+		// Put the job back untouched and stop until the limit resets
+		if _, dbErr := database.DB.Exec(`UPDATE enrichment_jobs SET status = 'pending', attempts = attempts - 1,
+                  locked_until = NULL, updated_at = NOW() WHERE id = $1`, job.ID); dbErr != nil {
+			fmt.Printf("[EnrichmentQueue] ⚠️  Failed to release job %d: %v\n", job.ID, dbErr)
+		}
+		q.pause(err)
+		return
+	}
+
+	verdict, err := callEnricher(backend, job.Title, job.MediaType)
+	switch {
+	case err != nil:
+		if job.Attempts >= maxEnrichmentAttempts {
+			q.finish(job, "failed", err.Error())
+			return
+		}
+		if _, dbErr := database.DB.Exec(`UPDATE enrichment_jobs SET status = 'pending', locked_until = NULL,
+                  next_attempt_at = NOW() + make_interval(secs => $2), last_error = $3, updated_at = NOW()
+              WHERE id = $1`, job.ID, enrichmentBackoff(job.Attempts).Seconds(), err.Error()); dbErr != nil {
+			fmt.Printf("[EnrichmentQueue] ⚠️  Failed to reschedule job %d: %v\n", job.ID, dbErr)
+		}
+	case verdict.Rejected:
+		q.finish(job, "rejected", verdict.Reason())
+	default:
+		storeEnrichment(repo, job.Title, job.MediaType, verdict, backend.Name())
+		q.finish(job, "done", "")
+	}
+}
+
+func (q *EnrichmentQueue) finish(job models.EnrichmentJob, status string, lastError string) {
+	if _, err := database.DB.Exec(`UPDATE enrichment_jobs SET status = $2, last_error = NULLIF($3, ''),
+              locked_until = NULL, updated_at = NOW() WHERE id = $1`, job.ID, status, lastError); err != nil {
+		fmt.Printf("[EnrichmentQueue] ⚠️  Failed to update job %d: %v\n", job.ID, err)
+	}
+}
+
+// pause stops claiming jobs until the rate limit (next minute) or budget (next UTC day) resets
+func (q *EnrichmentQueue) pause(reason error) {
+	now := time.Now().UTC()
+	until := now.Truncate(time.Minute).Add(time.Minute)
+	if errors.Is(reason, ErrEnrichmentBudgetExhausted) {
+		until = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	} else if !errors.Is(reason, ErrEnrichmentRateLimited) {
+		fmt.Printf("[EnrichmentQueue] ⚠️  Failed to reserve an LLM call: %v\n", reason)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if until.After(q.pausedUntil) {
+		if errors.Is(reason, ErrEnrichmentBudgetExhausted) {
+			fmt.Printf("[EnrichmentQueue] Daily budget used up, pausing until %s\n", until.Format(time.RFC3339))
+		}
+		q.pausedUntil = until
+	}
+}
+
+// enrichmentBackoff is 1m, 2m, 4m, ... after each failed attempt
+func enrichmentBackoff(attempts int) time.Duration {
+	return enrichmentBaseBackoff << (attempts - 1)
+}
+
+// Stats counts jobs per status and shows how much of the rate and budget is used
+func (q *EnrichmentQueue) Stats() (*models.EnrichmentQueueStats, error) {
+	stats := &models.EnrichmentQueueStats{
+		Jobs:          map[string]int{"pending": 0, "running": 0, "done": 0, "rejected": 0, "failed": 0},
+		RatePerMinute: q.RatePerMinute,
+		DailyBudget:   q.DailyBudget,
+	}
+	if backend := GetEnricher(); backend != nil {
+		stats.Backend = backend.Name()
+	}
+
+	rows, err := database.DB.Query(`SELECT status, COUNT(*) FROM enrichment_jobs GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.Jobs[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var oldest sql.NullTime
+	if err := database.DB.QueryRow(`SELECT MIN(created_at) FROM enrichment_jobs WHERE status = 'pending'`).Scan(&oldest); err != nil {
+		return nil, err
+	}
+	if oldest.Valid {
+		stats.OldestPendingAt = &oldest.Time
+	}
+
+	now := time.Now().UTC()
+	err = database.DB.QueryRow(`SELECT
+              COALESCE((SELECT calls FROM enrichment_usage WHERE bucket = $1), 0),
+              COALESCE((SELECT calls FROM enrichment_usage WHERE bucket = $2), 0)`,
+		"minute:"+now.Format("2006-01-02T15:04"), "day:"+now.Format("2006-01-02")).Scan(&stats.CallsThisMinute, &stats.CallsToday)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Jobs lists jobs, most recently updated first; status filters when non-empty
+func (q *EnrichmentQueue) Jobs(status string, page int, limit int) ([]models.EnrichmentJob, int, error) {
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM enrichment_jobs WHERE $1 = '' OR status = $1`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(`SELECT id, title, media_type, status, attempts, next_attempt_at, COALESCE(last_error, ''),
+                  created_at, updated_at
+              FROM enrichment_jobs WHERE $1 = '' OR status = $1
+              ORDER BY updated_at DESC, id DESC LIMIT $2 OFFSET $3`, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []models.EnrichmentJob{}
+	for rows.Next() {
+		var job models.EnrichmentJob
+		if err := rows.Scan(&job.ID, &job.Title, &job.MediaType, &job.Status, &job.Attempts, &job.NextAttemptAt,
+			&job.LastError, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, total, rows.Err()
+}
+
+// Retry queues a rejected or failed job again right away
+func (q *EnrichmentQueue) Retry(id int64) error {
+	result, err := database.DB.Exec(`UPDATE enrichment_jobs SET status = 'pending', attempts = 0, next_attempt_at = NOW(),
+              last_error = NULL, updated_at = NOW()
+              WHERE id = $1 AND status IN ('rejected', 'failed')`, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrEnrichmentJobNotFound
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEnrichmentAcceptConfidence = 0.7
+	defaultEnrichmentReviewConfidence = 0.4
+	maxEnrichedAuthorLength           = 255
+	maxEnrichedSynopsisLength         = 1000
+	earliestReleaseYear               = 1900
+)
+
+// How much each field counts towards the confidence score (sums to 1)
+var enrichmentFieldWeights = map[string]float64{
+	"year":     0.25,
+	"rating":   0.15,
+	"status":   0.15,
+	"genre":    0.25,
+	"author":   0.1,
+	"synopsis": 0.1,
+}
+
+// genreTaxonomy is the set of genres enriched metadata may use, keyed by genreKey. Values
+// are the display names stored in enriched_metadata.genre.
+var genreTaxonomy = map[string]string{}
+
+// genreAliases maps other spellings the models use onto the taxonomy
+var genreAliases = map[string]string{
+	"science-fiction":  "Sci-Fi",
+	"scifi":            "Sci-Fi",
+	"sf":               "Sci-Fi",
+	"superpower":       "Super Power",
+	"super-powers":     "Super Power",
+	"martial-art":      "Martial Arts",
+	"shojo":            "Shoujo",
+	"shonen":           "Shounen",
+	"shojo-ai":         "Shoujo Ai",
+	"shonen-ai":        "Shounen Ai",
+	"girls-love":       "Yuri",
+	"boys-love":        "Yaoi",
+	"demon":            "Demons",
+	"games":            "Game",
+	"history":          "Historical",
+	"magical":          "Magic",
+	"mystery-thriller": "Mystery",
+	"psychology":       "Psychological",
+	"sport":            "Sports",
+	"vampires":         "Vampire",
+	"slice-of-live":    "Slice of Life",
+	"romcom":           "Romance",
+	"gourmet":          "Cooking",
+	"reincarnation":    "Isekai",
+}
+
+func init() {
+	for _, genre := range []string{
+		"Action", "Adventure", "Comedy", "Cooking", "Demons", "Drama", "Ecchi", "Fantasy", "Game",
+		"Harem", "Historical", "Horror", "Isekai", "Josei", "Magic", "Martial Arts", "Mecha",
+		"Military", "Music", "Mystery", "Parody", "Police", "Psychological", "Romance", "School",
+		"Sci-Fi", "Seinen", "Shoujo", "Shoujo Ai", "Shounen", "Shounen Ai", "Slice of Life", "Space",
+		"Sports", "Super Power", "Supernatural", "Thriller", "Vampire", "Yaoi", "Yuri",
+	} {
+		genreTaxonomy[genreKey(genre)] = genre
+	}
+}
+
+// EnrichmentVerdict is the outcome of checking an enricher response against the schema
+type EnrichmentVerdict struct {
+	// Data has every field normalized; fields that failed validation are cleared
+	Data       EnrichedData
+	Confidence float64
+	// Issues lists what was wrong, e.g. `rating "11" is not between 0 and 10`
+	Issues []string
+	// NeedsReview: stored, but flagged for an admin to check
+	NeedsReview bool
+	// Rejected: not stored at all
+	Rejected bool
+}
+
+// Reason is the rejection/review reason stored with the row
+func (v EnrichmentVerdict) Reason() string {
+	return strings.Join(v.Issues, "; ")
+}
+
+var (
+	enrichmentAcceptConfidence float64
+	enrichmentReviewConfidence float64
+	enrichmentThresholdsOnce   sync.Once
+)
+
+// enrichmentThresholds reads ENRICHMENT_MIN_CONFIDENCE (default 0.7, below it a result is
+// flagged for review) and ENRICHMENT_REVIEW_CONFIDENCE (default 0.4, below it a result is
+// rejected and never stored)
+func enrichmentThresholds() (float64, float64) {
+	enrichmentThresholdsOnce.Do(func() {
+		enrichmentAcceptConfidence = confidenceFromEnv("ENRICHMENT_MIN_CONFIDENCE", defaultEnrichmentAcceptConfidence)
+		enrichmentReviewConfidence = confidenceFromEnv("ENRICHMENT_REVIEW_CONFIDENCE", defaultEnrichmentReviewConfidence)
+		if enrichmentReviewConfidence > enrichmentAcceptConfidence {
+			fmt.Printf("[Enricher] ⚠️  ENRICHMENT_REVIEW_CONFIDENCE is above ENRICHMENT_MIN_CONFIDENCE, nothing will be flagged for review\n")
+			enrichmentReviewConfidence = enrichmentAcceptConfidence
+		}
+	})
+	return enrichmentAcceptConfidence, enrichmentReviewConfidence
+}
+
+func confidenceFromEnv(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		fmt.Printf("[Enricher] ⚠️  Invalid %s %q, using %.2f\n", name, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// ValidateEnrichment checks a response field by field: year in a plausible range, rating a
+// number from 0 to 10, status Ongoing/Completed, genres from genreTaxonomy. The confidence
+// is the weighted share of fields that passed (genres count in proportion to how many were
+// recognised).
+func ValidateEnrichment(data EnrichedData) EnrichmentVerdict {
+	verdict := EnrichmentVerdict{Data: EnrichedData{LastUpdated: data.LastUpdated}}
+	score := 0.0
+	var missing []string
+
+	if year, issue := normalizeReleaseYear(data.Year); issue != "" {
+		verdict.Issues = append(verdict.Issues, issue)
+	} else if year != "" {
+		verdict.Data.Year = year
+		score += enrichmentFieldWeights["year"]
+	} else {
+		missing = append(missing, "year")
+	}
+
+	if rating, issue := normalizeRating(data.Rating); issue != "" {
+		verdict.Issues = append(verdict.Issues, issue)
+	} else if rating != "" {
+		verdict.Data.Rating = rating
+		score += enrichmentFieldWeights["rating"]
+	} else {
+		missing = append(missing, "rating")
+	}
+
+	if status, issue := normalizeStatus(data.Status); issue != "" {
+		verdict.Issues = append(verdict.Issues, issue)
+	} else if status != "" {
+		verdict.Data.Status = status
+		score += enrichmentFieldWeights["status"]
+	} else {
+		missing = append(missing, "status")
+	}
+
+	genres, unknown := normalizeGenres(data.Genre)
+	if len(unknown) > 0 {
+		verdict.Issues = append(verdict.Issues, fmt.Sprintf("unknown genres: %s", strings.Join(unknown, ", ")))
+	}
+	if len(genres) > 0 {
+		verdict.Data.Genre = strings.Join(genres, ", ")
+		score += enrichmentFieldWeights["genre"] * float64(len(genres)) / float64(len(genres)+len(unknown))
+	} else if len(unknown) == 0 {
+		missing = append(missing, "genre")
+	}
+
+	if author := strings.Join(strings.Fields(data.Author), " "); len(author) > maxEnrichedAuthorLength {
+		verdict.Issues = append(verdict.Issues, "author is too long")
+	} else if author != "" && !isUnknownValue(author) {
+		verdict.Data.Author = author
+		score += enrichmentFieldWeights["author"]
+	} else {
+		missing = append(missing, "author")
+	}
+
+	if synopsis := strings.TrimSpace(data.Synopsis); len(synopsis) > maxEnrichedSynopsisLength {
+		verdict.Issues = append(verdict.Issues, "synopsis is too long")
+	} else if synopsis != "" && !isUnknownValue(synopsis) {
+		verdict.Data.Synopsis = synopsis
+		score += enrichmentFieldWeights["synopsis"]
+	} else {
+		missing = append(missing, "synopsis")
+	}
+
+	if len(missing) > 0 {
+		verdict.Issues = append(verdict.Issues, "missing "+strings.Join(missing, ", "))
+	}
+
+	verdict.Confidence = float64(int(score*100+0.5)) / 100
+	accept, review := enrichmentThresholds()
+	switch {
+	case verdict.Confidence < review:
+		verdict.Rejected = true
+		verdict.Issues = append(verdict.Issues, fmt.Sprintf("confidence %.2f is below %.2f", verdict.Confidence, review))
+	case verdict.Confidence < accept:
+		verdict.NeedsReview = true
+	}
+	return verdict
+}
+
+// rejectedEnrichment is the verdict for a response that couldn't be used at all
+func rejectedEnrichment(reason string) EnrichmentVerdict {
+	return EnrichmentVerdict{Issues: []string{reason}, Rejected: true}
+}
+
+func normalizeReleaseYear(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if value == "" || isUnknownValue(value) {
+		return "", ""
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil {
+		return "", fmt.Sprintf("year %q is not a number", value)
+	}
+	// Announced titles can be a couple of years out
+	if year < earliestReleaseYear || year > time.Now().Year()+2 {
+		return "", fmt.Sprintf("year %d is out of range", year)
+	}
+	return strconv.Itoa(year), ""
+}
+
+func normalizeRating(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	if value == "" || isUnknownValue(value) {
+		return "", ""
+	}
+	rating, err := strconv.ParseFloat(strings.TrimSuffix(value, "/10"), 64)
+	if err != nil || math.IsNaN(rating) || math.IsInf(rating, 0) {
+		return "", fmt.Sprintf("rating %q is not a number", value)
+	}
+	if rating < 0 || rating > 10 {
+		return "", fmt.Sprintf("rating %q is not between 0 and 10", value)
+	}
+	return strconv.FormatFloat(rating, 'f', 1, 64), ""
+}
+
+func normalizeStatus(value string) (string, string) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return "", ""
+	case "ongoing", "airing", "currently airing", "publishing", "releasing", "on going":
+		return "Ongoing", ""
+	case "completed", "complete", "finished", "finished airing", "ended", "tamat":
+		return "Completed", ""
+	}
+	if isUnknownValue(value) {
+		return "", ""
+	}
+	return "", fmt.Sprintf("status %q is not Ongoing or Completed", value)
+}
+
+// normalizeGenres maps a comma-separated list onto genreTaxonomy; duplicates are dropped
+func normalizeGenres(value string) ([]string, []string) {
+	var genres, unknown []string
+	seen := make(map[string]bool)
+	for _, raw := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == '/' }) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" || isUnknownValue(raw) {
+			continue
+		}
+		key := genreKey(raw)
+		genre, ok := genreTaxonomy[key]
+		if !ok {
+			genre, ok = genreAliases[key]
+		}
+		if !ok {
+			unknown = append(unknown, raw)
+			continue
+		}
+		if !seen[genre] {
+			seen[genre] = true
+			genres = append(genres, genre)
+		}
+	}
+	return genres, unknown
+}
+
+// isUnknownValue catches the placeholders models answer with when they don't know
+func isUnknownValue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "unknown", "n/a", "na", "none", "null", "-", "?", "tbd", "tba":
+		return true
+	}
+	return false
+}
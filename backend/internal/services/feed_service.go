@@ -0,0 +1,370 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	userFeedEntries    = 50
+	feedEntryRetention = 30 // days an entry no longer listed keeps its first-seen time
+)
+
+var (
+	ErrInvalidFeedToken  = errors.New("invalid feed token")
+	ErrFeedTokenNotFound = errors.New("no private feed yet")
+)
+
+// FeedService builds Atom feeds of new releases for feed readers
+type FeedService struct {
+	AnimeIndo *AnimeIndoService
+	Manga     *SankavollereiService
+	BaseURL   string // Public site URL the entries link to
+}
+
+func NewFeedService() *FeedService {
+	return &FeedService{
+		AnimeIndo: NewAnimeIndoService(),
+		Manga:     NewSankavollereiService(""),
+		BaseURL:   publicBaseURL(),
+	}
+}
+
+// LatestAnime is the Anime Indo latest-episodes list, one entry per episode
+func (s *FeedService) LatestAnime() (*models.AtomFeed, error) {
+	episodes, err := s.AnimeIndo.GetLatestEpisodes(1)
+	if err != nil {
+		return nil, err
+	}
+	return s.releaseFeed("/feeds/anime/latest.atom", "Latest anime episodes", releaseSourceAnimeIndo, episodeReleases(episodes))
+}
+
+// LatestManga is the manga home list (latest Komikindo updates), one entry per chapter
+func (s *FeedService) LatestManga() (*models.AtomFeed, error) {
+	result, err := s.Manga.GetMangaHome()
+	if err != nil {
+		return nil, err
+	}
+	return s.releaseFeed("/feeds/manga/latest.atom", "Latest manga chapters", releaseSourceKomikindo, chapterReleases(result.Data.MangaList))
+}
+
+// AnimeGenre lists the series of a genre; the entry changes when the episode count does
+func (s *FeedService) AnimeGenre(slug string) (*models.AtomFeed, error) {
+	result, err := s.Manga.GetGenre(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := s.newFeed("/feeds/anime/genre/"+url.PathEscape(slug)+".atom", "Anime genre: "+slug, time.Now())
+	for _, anime := range result.Data.AnimeList {
+		animeSlug := firstNonEmpty(anime.Slug, anime.AnimeID)
+		if animeSlug == "" {
+			continue
+		}
+		link := s.BaseURL + "/anime/" + url.PathEscape(animeSlug)
+		var details []string
+		for _, detail := range []string{anime.Status, anime.TotalEpisodes, anime.Rating} {
+			if detail != "" {
+				details = append(details, detail)
+			}
+		}
+		feed.Entries = append(feed.Entries, models.AtomEntry{
+			ID:       entryID(link, anime.TotalEpisodes),
+			Title:    anime.Title,
+			Links:    []models.AtomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Category: []models.AtomTerm{{Term: "anime"}},
+			Summary:  entrySummary(firstNonEmpty(anime.Poster, anime.Cover, anime.Thumbnail, anime.Image), strings.Join(details, " · ")),
+		})
+	}
+	return feed, stampFirstSeen(feed)
+}
+
+// MangaGenre lists the manga of a genre with their latest chapter
+func (s *FeedService) MangaGenre(slug string) (*models.AtomFeed, error) {
+	result, err := s.Manga.GetMangaGenre(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := s.newFeed("/feeds/manga/genre/"+url.PathEscape(slug)+".atom", "Manga genre: "+slug, time.Now())
+	for _, manga := range result.Data.MangaList {
+		slug := mangaSlug(manga)
+		if slug == "" {
+			continue
+		}
+		link := s.BaseURL + "/manga/" + url.PathEscape(slug)
+		title := manga.Title
+		if manga.Chapter != "" {
+			title += " - " + manga.Chapter
+		}
+		feed.Entries = append(feed.Entries, models.AtomEntry{
+			ID:       entryID(link, manga.Chapter),
+			Title:    title,
+			Links:    []models.AtomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Category: []models.AtomTerm{{Term: "manga"}},
+			Summary:  entrySummary(firstNonEmpty(manga.Image, manga.Cover, manga.Poster, manga.Thumbnail), manga.Status),
+		})
+	}
+	return feed, stampFirstSeen(feed)
+}
+
+// UserFeed is the private feed of a token: the new releases of the user's bookmarked titles
+func (s *FeedService) UserFeed(token string) (*models.AtomFeed, error) {
+	if len(token) != 64 {
+		return nil, ErrInvalidFeedToken
+	}
+	var userID int
+	var username string
+	err := database.DB.QueryRow(`SELECT t.user_id, u.username FROM feed_tokens t JOIN users u ON u.id = t.user_id
+              WHERE t.token_hash = $1`, hashFeedToken(token)).Scan(&userID, &username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidFeedToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Readers poll often; an hour is precise enough to see which tokens are in use
+	database.DB.Exec(`UPDATE feed_tokens SET last_used_at = NOW()
+              WHERE user_id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 hour')`, userID)
+
+	rows, err := database.DB.Query(`SELECT type, slug, title, release, COALESCE(target_slug, ''), COALESCE(cover_image, ''), created_at
+              FROM notifications WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, userFeedEntries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releases []models.Release
+	seenAt := make(map[string]time.Time)
+	for rows.Next() {
+		var r models.Release
+		var createdAt time.Time
+		if err := rows.Scan(&r.Type, &r.Slug, &r.Title, &r.Release, &r.TargetSlug, &r.CoverImage, &createdAt); err != nil {
+			return nil, err
+		}
+		releases = append(releases, r)
+		seenAt[r.Type+"/"+r.Slug+"/"+r.Release] = createdAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	feed := s.newFeed("/feeds/users/"+token+".atom", "New releases for "+username, time.Now())
+	s.addReleases(feed, releases, func(r models.Release) time.Time { return seenAt[r.Type+"/"+r.Slug+"/"+r.Release] })
+	return feed, nil
+}
+
+// GetFeedToken returns when the user's private feed was created (without the URL)
+func (s *FeedService) GetFeedToken(userID int) (*models.FeedToken, error) {
+	var token models.FeedToken
+	var lastUsed sql.NullTime
+	err := database.DB.QueryRow(`SELECT created_at, last_used_at FROM feed_tokens WHERE user_id = $1`, userID).
+		Scan(&token.CreatedAt, &lastUsed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeedTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		token.LastUsedAt = &lastUsed.Time
+	}
+	return &token, nil
+}
+
+// RotateFeedToken creates the private feed, or replaces its URL so the old one stops working
+func (s *FeedService) RotateFeedToken(userID int) (*models.FeedToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate feed token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	feedToken := models.FeedToken{URL: s.BaseURL + "/feeds/users/" + token + ".atom"}
+	err := database.DB.QueryRow(`INSERT INTO feed_tokens (user_id, token_hash) VALUES ($1, $2)
+              ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_used_at = NULL
+              RETURNING created_at`, userID, hashFeedToken(token)).Scan(&feedToken.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+func (s *FeedService) RevokeFeedToken(userID int) error {
+	result, err := database.DB.Exec(`DELETE FROM feed_tokens WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrFeedTokenNotFound
+	}
+	return nil
+}
+
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *FeedService) newFeed(selfPath string, title string, updated time.Time) *models.AtomFeed {
+	return &models.AtomFeed{
+		ID:      s.BaseURL + selfPath,
+		Title:   "AnimeTanyaAyomi - " + title,
+		Updated: models.AtomTime(updated),
+		Links: []models.AtomLink{
+			{Href: s.BaseURL + selfPath, Rel: "self", Type: "application/atom+xml"},
+			{Href: s.BaseURL + "/", Rel: "alternate", Type: "text/html"},
+		},
+		Author:  &models.AtomPerson{Name: "AnimeTanyaAyomi"},
+		Entries: []models.AtomEntry{},
+	}
+}
+
+// releaseFeed dates each release by when the release poller first saw it, which upstream
+// lists don't tell us; releases the poller hasn't seen yet get the current time
+func (s *FeedService) releaseFeed(selfPath string, title string, source string, releases []models.Release) (*models.AtomFeed, error) {
+	slugs := make([]string, 0, len(releases))
+	for _, release := range releases {
+		slugs = append(slugs, release.Slug)
+	}
+	rows, err := database.DB.Query(`SELECT series_slug, latest_release, updated_at FROM release_feed_state
+              WHERE source = $1 AND series_slug = ANY($2)`, source, pq.Array(slugs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seenAt := make(map[string]time.Time)
+	for rows.Next() {
+		var slug, latest string
+		var updatedAt time.Time
+		if err := rows.Scan(&slug, &latest, &updatedAt); err != nil {
+			return nil, err
+		}
+		seenAt[slug+"/"+latest] = updatedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	feed := s.newFeed(selfPath, title, now)
+	s.addReleases(feed, releases, func(r models.Release) time.Time {
+		if t, ok := seenAt[r.Slug+"/"+r.Release]; ok {
+			return t
+		}
+		return now
+	})
+	return feed, nil
+}
+
+func (s *FeedService) addReleases(feed *models.AtomFeed, releases []models.Release, seenAt func(models.Release) time.Time) {
+	var newest time.Time
+	for _, release := range releases {
+		updated := seenAt(release)
+		if updated.After(newest) {
+			newest = updated
+		}
+		link := s.BaseURL + NotificationPath(models.Notification{Type: release.Type, Slug: release.Slug, TargetSlug: release.TargetSlug})
+		feed.Entries = append(feed.Entries, models.AtomEntry{
+			ID:       entryID(link, release.Release),
+			Title:    release.Title + " - " + release.Release,
+			Updated:  models.AtomTime(updated),
+			Links:    []models.AtomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Category: []models.AtomTerm{{Term: release.Type}},
+			Summary:  entrySummary(release.CoverImage, release.Release),
+		})
+	}
+	if !newest.IsZero() {
+		feed.Updated = models.AtomTime(newest)
+	}
+}
+
+// stampFirstSeen dates each entry by when its id was first served, so readers don't see
+// every entry as updated on each fetch, and the feed by its newest entry. last_seen_at
+// only moves once a day to keep frequent polling from rewriting every row.
+func stampFirstSeen(feed *models.AtomFeed) error {
+	if len(feed.Entries) == 0 {
+		return nil
+	}
+	ids := make([]string, len(feed.Entries))
+	for i, entry := range feed.Entries {
+		ids[i] = entry.ID
+	}
+	_, err := database.DB.Exec(`INSERT INTO feed_entries (entry_id) SELECT DISTINCT unnest($1::text[])
+              ON CONFLICT (entry_id) DO UPDATE SET last_seen_at = NOW()
+              WHERE feed_entries.last_seen_at < NOW() - INTERVAL '1 day'`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+
+	rows, err := database.DB.Query(`SELECT entry_id, first_seen_at FROM feed_entries WHERE entry_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	seenAt := make(map[string]time.Time, len(ids))
+	for rows.Next() {
+		var id string
+		var firstSeen time.Time
+		if err := rows.Scan(&id, &firstSeen); err != nil {
+			return err
+		}
+		seenAt[id] = firstSeen
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var newest time.Time
+	for i := range feed.Entries {
+		updated, ok := seenAt[feed.Entries[i].ID]
+		if !ok {
+			updated = time.Now()
+		}
+		feed.Entries[i].Updated = models.AtomTime(updated)
+		if updated.After(newest) {
+			newest = updated
+		}
+	}
+	feed.Updated = models.AtomTime(newest)
+	return nil
+}
+
+func pruneFeedEntries() error {
+	_, err := database.DB.Exec(`DELETE FROM feed_entries WHERE last_seen_at < NOW() - make_interval(days => $1)`,
+		feedEntryRetention)
+	return err
+}
+
+// entryID keeps ids stable across fetches and distinct per release of the same page
+func entryID(link string, release string) string {
+	if release == "" {
+		return link
+	}
+	return link + "#" + url.QueryEscape(release)
+}
+
+func entrySummary(coverImage string, text string) *models.AtomContent {
+	body := html.EscapeString(text)
+	if coverImage != "" {
+		body = `<img src="` + html.EscapeString(coverImage) + `" alt="" width="120"><br>` + body
+	}
+	if body == "" {
+		return nil
+	}
+	return &models.AtomContent{Type: "html", Body: body}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// countingEnricher is a FakeEnricher that counts its calls, or returns a fixed response
+type countingEnricher struct {
+	FakeEnricher
+	calls    atomic.Int32
+	response *EnrichedData
+}
+
+func (e *countingEnricher) Enrich(title string, mediaType string) (EnrichedData, error) {
+	e.calls.Add(1)
+	if e.response != nil {
+		return *e.response, nil
+	}
+	return e.FakeEnricher.Enrich(title, mediaType)
+}
+
+func useFakeEnricher(t *testing.T, e Enricher) {
+	t.Helper()
+	t.Setenv("CACHE_BACKEND", "memory")
+	SetEnricher(e)
+	t.Cleanup(func() { SetEnricher(nil) })
+}
+
+func TestEnrichDataWithDBCachesEnricherResult(t *testing.T) {
+	enricher := &countingEnricher{}
+	useFakeEnricher(t, enricher)
+
+	title := "Cache Path " + t.Name()
+	first := EnrichDataWithDB(title, "anime", nil)
+	if first.Synopsis == "" || first.Genre == "" {
+		t.Fatalf("first call returned no data: %+v", first)
+	}
+
+	raw, _ := FakeEnricher{}.Enrich(title, "anime")
+	want := ValidateEnrichment(raw).Data
+	want.LastUpdated = first.LastUpdated
+	if first != want {
+		t.Fatalf("first call = %+v, want the validated FakeEnricher data %+v", first, want)
+	}
+
+	second := EnrichDataWithDB(title, "anime", nil)
+	if calls := enricher.calls.Load(); calls != 1 {
+		t.Fatalf("enricher called %d times, want 1 (second lookup should hit the cache)", calls)
+	}
+	second.LastUpdated = first.LastUpdated // json:"-", not cached
+	if second != first {
+		t.Fatalf("cached call = %+v, want %+v", second, first)
+	}
+
+	// Other media types are cached separately
+	EnrichDataWithDB(title, "manga", nil)
+	if calls := enricher.calls.Load(); calls != 2 {
+		t.Fatalf("enricher called %d times, want 2", calls)
+	}
+}
+
+func TestEnrichDataWithDBCachesRejectedResultAsEmpty(t *testing.T) {
+	enricher := &countingEnricher{response: &EnrichedData{Year: "1066", Rating: "42", Genre: "Nonsense"}}
+	useFakeEnricher(t, enricher)
+
+	title := "Rejected " + t.Name()
+	for i := 0; i < 2; i++ {
+		if data := EnrichDataWithDB(title, "anime", nil); data != (EnrichedData{}) {
+			t.Fatalf("call %d = %+v, want empty data for a rejected result", i, data)
+		}
+	}
+	if calls := enricher.calls.Load(); calls != 1 {
+		t.Fatalf("enricher called %d times, want 1", calls)
+	}
+}
+
+func TestValidateEnrichmentBands(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        EnrichedData
+		confidence  float64
+		needsReview bool
+		rejected    bool
+		issue       string
+	}{
+		{
+			name: "accept",
+			data: EnrichedData{
+				Year: "2019", Rating: "8.6/10", Status: "finished airing", Author: "Koyoharu  Gotouge",
+				Genre: "Action, shonen, Supernatural", Synopsis: "A boy fights demons.",
+			},
+			confidence: 1,
+		},
+		{
+			name:        "review",
+			data:        EnrichedData{Year: "2019", Rating: "8.6", Status: "Ongoing", Author: "Unknown"},
+			confidence:  0.55,
+			needsReview: true,
+			issue:       "missing genre, author, synopsis",
+		},
+		{
+			name:        "review with unknown genre",
+			data:        EnrichedData{Year: "2019", Status: "Ongoing", Genre: "Action, Cyberpunk", Synopsis: "B"},
+			confidence:  0.63,
+			needsReview: true,
+			issue:       "unknown genres: Cyberpunk",
+		},
+		{
+			name:       "reject",
+			data:       EnrichedData{Year: "1066", Rating: "11", Status: "hiatus", Synopsis: "N/A", Author: "Someone"},
+			confidence: 0.1,
+			rejected:   true,
+			issue:      "confidence 0.10 is below 0.40",
+		},
+		{
+			name: "accept without NaN rating",
+			data: EnrichedData{
+				Year: "2019", Rating: "NaN", Status: "Ongoing", Author: "Koyoharu Gotouge",
+				Genre: "Action", Synopsis: "A boy fights demons.",
+			},
+			confidence: 0.85,
+			issue:      `rating "NaN" is not a number`,
+		},
+		{
+			name: "accept without infinite rating",
+			data: EnrichedData{
+				Year: "2019", Rating: "-Inf/10", Status: "Ongoing", Author: "Koyoharu Gotouge",
+				Genre: "Action", Synopsis: "A boy fights demons.",
+			},
+			confidence: 0.85,
+			issue:      `rating "-Inf/10" is not a number`,
+		},
+		{
+			name:     "reject empty",
+			data:     EnrichedData{},
+			rejected: true,
+			issue:    "missing year, rating, status, genre, author, synopsis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := ValidateEnrichment(tt.data)
+			if verdict.Confidence != tt.confidence {
+				t.Errorf("confidence = %.2f, want %.2f", verdict.Confidence, tt.confidence)
+			}
+			if verdict.NeedsReview != tt.needsReview || verdict.Rejected != tt.rejected {
+				t.Errorf("needsReview, rejected = %v, %v, want %v, %v (%s)",
+					verdict.NeedsReview, verdict.Rejected, tt.needsReview, tt.rejected, verdict.Reason())
+			}
+			if tt.issue != "" && !strings.Contains(verdict.Reason(), tt.issue) {
+				t.Errorf("reason %q does not mention %q", verdict.Reason(), tt.issue)
+			}
+			if strings.Contains(tt.issue, "rating") && verdict.Data.Rating != "" {
+				t.Errorf("invalid rating stored as %q", verdict.Data.Rating)
+			}
+		})
+	}
+}
+
+func TestValidateEnrichmentNormalizes(t *testing.T) {
+	verdict := ValidateEnrichment(EnrichedData{
+		Year: " 2019 ", Rating: "8.66/10", Status: "Currently Airing", Author: "  Koyoharu   Gotouge ",
+		Genre: "shonen; Action / sci-fi, Action", Synopsis: "  A boy fights demons. ",
+	})
+	want := EnrichedData{
+		Year: "2019", Rating: "8.7", Status: "Ongoing", Author: "Koyoharu Gotouge",
+		Genre: "Shounen, Action, Sci-Fi", Synopsis: "A boy fights demons.",
+	}
+	if verdict.Data != want {
+		t.Fatalf("data = %+v, want %+v", verdict.Data, want)
+	}
+}
+
+func TestEnrichDataWithDBRecordsRejection(t *testing.T) {
+	enricher := &countingEnricher{response: &EnrichedData{Year: "1066", Rating: "42", Genre: "Nonsense"}}
+	useFakeEnricher(t, enricher)
+	db := useRecordingDB(t)
+
+	// No rate/budget buckets: the recording database has no rows to count them in
+	queue := GetEnrichmentQueue()
+	rate, budget := queue.RatePerMinute, queue.DailyBudget
+	queue.RatePerMinute, queue.DailyBudget = 0, 0
+	t.Cleanup(func() { queue.RatePerMinute, queue.DailyBudget = rate, budget })
+
+	title := "Rejected " + t.Name()
+	if data := EnrichDataWithDB(title, "anime", nil); data != (EnrichedData{}) {
+		t.Fatalf("EnrichDataWithDB = %+v, want empty data for a rejected result", data)
+	}
+
+	execs := db.execs()
+	if len(execs) != 1 || !strings.HasPrefix(execs[0].query, "INSERT INTO enrichment_jobs") ||
+		!strings.Contains(execs[0].query, "'rejected'") {
+		t.Fatalf("statements = %v, want one rejected enrichment job", execs)
+	}
+	args := execs[0].args
+	if len(args) != 3 || args[0] != title || args[1] != "anime" {
+		t.Fatalf("args = %v, want the title and media type", args)
+	}
+	reason, _ := args[2].(string)
+	for _, want := range []string{"year 1066 is out of range", `rating "42" is not between 0 and 10`, "confidence 0.00 is below 0.40"} {
+		if !strings.Contains(reason, want) {
+			t.Errorf("last_error %q does not mention %q", reason, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -64,6 +65,16 @@ type ZoroSearchResponse struct {
 	Results     []ZoroAnimeInfo `json:"results"`
 }
 
+// ZoroAnimeDetail represents the response from the Zoro info endpoint
+type ZoroAnimeDetail struct {
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Image       string        `json:"image"`
+	Description string        `json:"description"`
+	Type        string        `json:"type"`
+	Episodes    []ZoroEpisode `json:"episodes"`
+}
+
 // GetEpisodeStream fetches streaming URLs for a specific anime episode
 // Parameters:
 //   - animeTitle: Title of the anime (e.g., "One Piece")
@@ -71,24 +82,33 @@ type ZoroSearchResponse struct {
 //
 // Returns: Map of quality → URL
 func (s *ZoroService) GetEpisodeStream(animeTitle string, episodeNumber int) (map[string]string, error) {
-	// Step 1: Search for anime on Zoro to get anime ID
-	searchQuery := strings.ToLower(strings.ReplaceAll(animeTitle, " ", "-"))
-	searchURL := fmt.Sprintf("%s/anime/zoro/%s?page=1", s.BaseURL, searchQuery)
-
-	searchResp, err := s.Client.Get(searchURL)
+	streamData, err := s.GetEpisodeSources(animeTitle, episodeNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search Zoro: %w", err)
+		return nil, err
 	}
-	defer searchResp.Body.Close()
 
-	if searchResp.StatusCode != 200 {
-		return nil, fmt.Errorf("zoro search returned status %d", searchResp.StatusCode)
+	// Extract quality → URL mapping
+	qualityMap := make(map[string]string)
+	for _, source := range streamData.Sources {
+		if source.URL != "" {
+			qualityMap[source.Quality] = source.URL
+		}
 	}
 
-	searchBody, _ := io.ReadAll(searchResp.Body)
-	var searchResults ZoroSearchResponse
-	if err := json.Unmarshal(searchBody, &searchResults); err != nil {
-		return nil, fmt.Errorf("failed to parse Zoro search: %w", err)
+	if len(qualityMap) == 0 {
+		return nil, fmt.Errorf("no stream sources found")
+	}
+
+	return qualityMap, nil
+}
+
+// GetEpisodeSources resolves title + episode number to the raw Zoro watch response
+// (keeps the IsM3U8 flag and required Referer header)
+func (s *ZoroService) GetEpisodeSources(animeTitle string, episodeNumber int) (*ZoroStreamResponse, error) {
+	// Step 1: Search for anime on Zoro to get anime ID
+	searchResults, err := s.Search(animeTitle)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(searchResults.Results) == 0 {
@@ -99,25 +119,9 @@ func (s *ZoroService) GetEpisodeStream(animeTitle string, episodeNumber int) (ma
 	animeID := searchResults.Results[0].ID
 
 	// Step 2: Get anime info to find episode ID
-	infoURL := fmt.Sprintf("%s/anime/zoro/info?id=%s", s.BaseURL, animeID)
-	infoResp, err := s.Client.Get(infoURL)
+	animeInfo, err := s.GetAnimeInfo(animeID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get Zoro info: %w", err)
-	}
-	defer infoResp.Body.Close()
-
-	if infoResp.StatusCode != 200 {
-		return nil, fmt.Errorf("zoro info returned status %d", infoResp.StatusCode)
-	}
-
-	infoBody, _ := io.ReadAll(infoResp.Body)
-
-	// Parse to get episodes list
-	var animeInfo struct {
-		Episodes []ZoroEpisode `json:"episodes"`
-	}
-	if err := json.Unmarshal(infoBody, &animeInfo); err != nil {
-		return nil, fmt.Errorf("failed to parse Zoro info: %w", err)
+		return nil, err
 	}
 
 	// Find the specific episode
@@ -134,36 +138,60 @@ func (s *ZoroService) GetEpisodeStream(animeTitle string, episodeNumber int) (ma
 	}
 
 	// Step 3: Get stream URLs for the episode
-	watchURL := fmt.Sprintf("%s/anime/zoro/watch?episodeId=%s", s.BaseURL, episodeID)
-	watchResp, err := s.Client.Get(watchURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch Zoro stream: %w", err)
+	return s.Watch(episodeID)
+}
+
+// Search searches Zoro for anime matching the title
+func (s *ZoroService) Search(animeTitle string) (*ZoroSearchResponse, error) {
+	searchQuery := strings.ToLower(strings.ReplaceAll(animeTitle, " ", "-"))
+	searchURL := fmt.Sprintf("%s/anime/zoro/%s?page=1", s.BaseURL, searchQuery)
+
+	var searchResults ZoroSearchResponse
+	if err := s.getJSON(searchURL, &searchResults); err != nil {
+		return nil, fmt.Errorf("zoro search failed: %w", err)
 	}
-	defer watchResp.Body.Close()
+	return &searchResults, nil
+}
 
-	if watchResp.StatusCode != 200 {
-		return nil, fmt.Errorf("zoro watch returned status %d", watchResp.StatusCode)
+// GetAnimeInfo fetches anime info (including the episode list) by Zoro anime ID
+func (s *ZoroService) GetAnimeInfo(animeID string) (*ZoroAnimeDetail, error) {
+	infoURL := fmt.Sprintf("%s/anime/zoro/info?id=%s", s.BaseURL, url.QueryEscape(animeID))
+
+	var animeInfo ZoroAnimeDetail
+	if err := s.getJSON(infoURL, &animeInfo); err != nil {
+		return nil, fmt.Errorf("zoro info failed: %w", err)
 	}
+	return &animeInfo, nil
+}
+
+// Watch fetches the stream sources for a Zoro episode ID
+func (s *ZoroService) Watch(episodeID string) (*ZoroStreamResponse, error) {
+	watchURL := fmt.Sprintf("%s/anime/zoro/watch?episodeId=%s", s.BaseURL, url.QueryEscape(episodeID))
 
-	watchBody, _ := io.ReadAll(watchResp.Body)
 	var streamData ZoroStreamResponse
-	if err := json.Unmarshal(watchBody, &streamData); err != nil {
-		return nil, fmt.Errorf("failed to parse Zoro stream: %w", err)
+	if err := s.getJSON(watchURL, &streamData); err != nil {
+		return nil, fmt.Errorf("zoro watch failed: %w", err)
 	}
+	return &streamData, nil
+}
 
-	// Step 4: Extract quality → URL mapping
-	qualityMap := make(map[string]string)
-	for _, source := range streamData.Sources {
-		if source.URL != "" {
-			qualityMap[source.Quality] = source.URL
-		}
+// getJSON performs a GET request against the Consumet API and decodes the body
+func (s *ZoroService) getJSON(endpoint string, target interface{}) error {
+	resp, err := s.Client.Get(endpoint)
+	if err != nil {
+		return err
 	}
+	defer resp.Body.Close()
 
-	if len(qualityMap) == 0 {
-		return nil, fmt.Errorf("no stream sources found")
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("status %d", resp.StatusCode)
 	}
 
-	return qualityMap, nil
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, target)
 }
 
 // ParseOtakudesuSlug extracts anime title and episode number from Otakudesu slug
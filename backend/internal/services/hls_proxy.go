@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Playlists are small text files; anything bigger is not a playlist we want to rewrite
+const MaxHLSPlaylistBytes = 2 << 20 // 2 MB
+
+// Matches URI="..." attributes in EXT-X-KEY, EXT-X-MAP, EXT-X-MEDIA, EXT-X-I-FRAME-STREAM-INF, ...
+var hlsURIAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// HLSProxy serves header-protected HLS streams through /api/proxy/hls.
+// Every proxied URL is signed together with its Referer, so the endpoint only
+// fetches URLs the backend itself emitted (stream hosts are not allowlisted).
+type HLSProxy struct {
+	SigningKey []byte
+	Client     *http.Client
+}
+
+var (
+	hlsProxy     *HLSProxy
+	hlsProxyOnce sync.Once
+)
+
+// GetHLSProxy returns the shared proxy. The key comes from HLS_PROXY_SIGNING_KEY, then
+// IMAGE_PROXY_SIGNING_KEY. Without either it panics, unless APP_ENV=development: a random
+// per-process key breaks stream links across replicas and on every restart mid-playback.
+func GetHLSProxy() *HLSProxy {
+	hlsProxyOnce.Do(func() {
+		key := []byte(os.Getenv("HLS_PROXY_SIGNING_KEY"))
+		if len(key) == 0 {
+			key = []byte(os.Getenv("IMAGE_PROXY_SIGNING_KEY"))
+		}
+		if len(key) == 0 {
+			if os.Getenv("APP_ENV") != "development" {
+				panic("HLS_PROXY_SIGNING_KEY is not set, generate a random key (or set APP_ENV=development)")
+			}
+			key = make([]byte, 32)
+			if _, err := rand.Read(key); err != nil {
+				panic(fmt.Sprintf("failed to generate HLS signing key: %v", err))
+			}
+			fmt.Println("[HLSProxy] ⚠️  HLS_PROXY_SIGNING_KEY not set, using a random key (stream links will not survive a restart)")
+		}
+
+		hlsProxy = &HLSProxy{
+			SigningKey: key,
+			Client:     newPublicHTTPClient(2 * time.Minute),
+		}
+	})
+	return hlsProxy
+}
+
+// newPublicHTTPClient only connects to (and follows redirects to) public addresses
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           safeDialContext(proxyHostsFromEnvironment()),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConnsPerHost:   20,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if err := CheckPublicURL(req.URL); err != nil {
+				return err
+			}
+			return ResolvePublicHost(req.Context(), req.URL.Hostname())
+		},
+	}
+}
+
+// Sign returns the HMAC signature for a stream URL and the Referer it needs
+func (p *HLSProxy) Sign(rawURL string, referer string) string {
+	mac := hmac.New(sha256.New, p.SigningKey)
+	mac.Write([]byte(rawURL + "\n" + referer))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks sig for rawURL + referer (signatures are always required)
+func (p *HLSProxy) VerifySignature(rawURL string, referer string, sig string) bool {
+	if sig == "" {
+		return false
+	}
+	return hmac.Equal([]byte(p.Sign(rawURL, referer)), []byte(sig))
+}
+
+// ProxyURL builds the signed /api/proxy/hls path for a playlist, segment or key
+func (p *HLSProxy) ProxyURL(rawURL string, referer string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	query := url.Values{}
+	query.Set("url", rawURL)
+	if referer != "" {
+		query.Set("ref", referer)
+	}
+	query.Set("sig", p.Sign(rawURL, referer))
+	return "/api/proxy/hls?" + query.Encode()
+}
+
+// NewRequest builds an upstream request carrying the Referer/Origin the stream host expects
+func (p *HLSProxy) NewRequest(ctx context.Context, target *url.URL, referer string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", browserUserAgent)
+	req.Header.Set("Accept", "*/*")
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+		if refURL, err := url.Parse(referer); err == nil && refURL.Host != "" {
+			req.Header.Set("Origin", refURL.Scheme+"://"+refURL.Host)
+		}
+	}
+	return req, nil
+}
+
+// IsHLSPlaylist detects a playlist by content type, .m3u8 extension or the #EXTM3U header
+func IsHLSPlaylist(target *url.URL, contentType string, head []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "mpegurl") {
+		return true
+	}
+	if strings.EqualFold(path.Ext(target.Path), ".m3u8") {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimLeft(head, "\ufeff \t\r\n"), []byte("#EXTM3U"))
+}
+
+// RewritePlaylist points every variant, segment and key URI of a master or media
+// playlist back through the proxy. base is the (post-redirect) playlist URL.
+func (p *HLSProxy) RewritePlaylist(body []byte, base *url.URL, referer string) []byte {
+	var out bytes.Buffer
+	out.Grow(len(body) * 2)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			line = hlsURIAttribute.ReplaceAllStringFunc(line, func(attr string) string {
+				uri := hlsURIAttribute.FindStringSubmatch(attr)[1]
+				return `URI="` + p.proxyReference(base, uri, referer) + `"`
+			})
+		default:
+			line = p.proxyReference(base, trimmed, referer)
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// proxyReference resolves uri against the playlist URL and signs it.
+// Non-http URIs (data:, skd:// for DRM keys) are left alone.
+func (p *HLSProxy) proxyReference(base *url.URL, uri string, referer string) string {
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return uri
+	}
+	return p.ProxyURL(resolved.String(), referer)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bufio"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultImageCacheMaxBytes = 1 << 30 // 1 GB
+
+// CachedImage is one entry of the on-disk image cache
+type CachedImage struct {
+	Body        []byte
+	ContentType string
+	ETag        string
+}
+
+// ImageDiskCache is a content-addressed image cache with size-based LRU eviction.
+// Files live at <dir>/<first 2 hex chars>/<sha256 of key>; the first line holds the Content-Type.
+type ImageDiskCache struct {
+	Dir      string
+	MaxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // front = most recently used
+	entries map[string]*list.Element
+}
+
+type imageCacheEntry struct {
+	hash string
+	size int64
+}
+
+var (
+	imageDiskCache     *ImageDiskCache
+	imageDiskCacheOnce sync.Once
+)
+
+// GetImageDiskCache returns the shared cache configured by IMAGE_CACHE_DIR / IMAGE_CACHE_MAX_BYTES
+func GetImageDiskCache() *ImageDiskCache {
+	imageDiskCacheOnce.Do(func() {
+		dir := os.Getenv("IMAGE_CACHE_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "anime-image-cache")
+		}
+
+		maxBytes := int64(defaultImageCacheMaxBytes)
+		if value, err := strconv.ParseInt(os.Getenv("IMAGE_CACHE_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+			maxBytes = value
+		}
+
+		imageDiskCache = NewImageDiskCache(dir, maxBytes)
+	})
+	return imageDiskCache
+}
+
+func NewImageDiskCache(dir string, maxBytes int64) *ImageDiskCache {
+	c := &ImageDiskCache{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Printf("[ImageCache] ⚠️  Cannot create cache dir %s: %v\n", dir, err)
+		return c
+	}
+	c.loadIndex()
+	return c
+}
+
+// ImageCacheKey addresses one upstream URL + transformation
+func ImageCacheKey(imageURL string, t ImageTransform) string {
+	sum := sha256.Sum256([]byte(imageURL + "|" + t.Key()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached image for hash and marks it as recently used
+func (c *ImageDiskCache) Get(hash string) (*CachedImage, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[hash]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	file, err := os.Open(c.path(hash))
+	if err != nil {
+		c.remove(hash)
+		return nil, false
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	contentType, err := reader.ReadString('\n')
+	if err != nil {
+		c.remove(hash)
+		return nil, false
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	os.Chtimes(c.path(hash), now, now) // keeps LRU order across restarts
+
+	return &CachedImage{
+		Body:        body,
+		ContentType: strings.TrimSpace(contentType),
+		ETag:        imageETag(body),
+	}, true
+}
+
+// Put stores an image under hash and evicts least recently used files over MaxBytes
+func (c *ImageDiskCache) Put(hash string, body []byte, contentType string) *CachedImage {
+	cached := &CachedImage{Body: body, ContentType: contentType, ETag: imageETag(body)}
+
+	path := c.path(hash)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Printf("[ImageCache] ⚠️  Failed to create dir: %v\n", err)
+		return cached
+	}
+
+	// Write to a temp file and rename so readers never see partial files
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp-*")
+	if err != nil {
+		fmt.Printf("[ImageCache] ⚠️  Failed to write %s: %v\n", hash, err)
+		return cached
+	}
+	_, err = tmp.WriteString(contentType + "\n")
+	if err == nil {
+		_, err = tmp.Write(body)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		fmt.Printf("[ImageCache] ⚠️  Failed to write %s: %v\n", hash, err)
+		return cached
+	}
+
+	size := int64(len(contentType) + 1 + len(body))
+
+	c.mu.Lock()
+	if elem, ok := c.entries[hash]; ok {
+		c.size -= elem.Value.(*imageCacheEntry).size
+		c.lru.Remove(elem)
+	}
+	c.entries[hash] = c.lru.PushFront(&imageCacheEntry{hash: hash, size: size})
+	c.size += size
+	evicted := c.evictLocked()
+	c.mu.Unlock()
+
+	for _, old := range evicted {
+		os.Remove(c.path(old))
+	}
+	return cached
+}
+
+// evictLocked drops LRU entries until the cache is under MaxBytes; returns hashes to delete
+func (c *ImageDiskCache) evictLocked() []string {
+	var evicted []string
+	for c.size > c.MaxBytes && c.lru.Len() > 1 {
+		elem := c.lru.Back()
+		entry := elem.Value.(*imageCacheEntry)
+		c.lru.Remove(elem)
+		delete(c.entries, entry.hash)
+		c.size -= entry.size
+		evicted = append(evicted, entry.hash)
+	}
+	return evicted
+}
+
+func (c *ImageDiskCache) remove(hash string) {
+	c.mu.Lock()
+	if elem, ok := c.entries[hash]; ok {
+		c.size -= elem.Value.(*imageCacheEntry).size
+		c.lru.Remove(elem)
+		delete(c.entries, hash)
+	}
+	c.mu.Unlock()
+	os.Remove(c.path(hash))
+}
+
+func (c *ImageDiskCache) path(hash string) string {
+	return filepath.Join(c.Dir, hash[:2], hash)
+}
+
+// loadIndex rebuilds the LRU list from files on disk (oldest modification time = least recent)
+func (c *ImageDiskCache) loadIndex() {
+	type diskFile struct {
+		hash    string
+		size    int64
+		modTime time.Time
+	}
+	var files []diskFile
+
+	filepath.WalkDir(c.Dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.Contains(d.Name(), ".tmp-") {
+			os.Remove(path) // leftover from an interrupted write
+			return nil
+		}
+		if len(d.Name()) != sha256.Size*2 {
+			return nil // not one of our files
+		}
+		if info, err := d.Info(); err == nil {
+			files = append(files, diskFile{hash: d.Name(), size: info.Size(), modTime: info.ModTime()})
+		}
+		return nil
+	})
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+	for _, f := range files {
+		c.entries[f.hash] = c.lru.PushBack(&imageCacheEntry{hash: f.hash, size: f.size})
+		c.size += f.size
+	}
+
+	for _, old := range c.evictLocked() {
+		os.Remove(c.path(old))
+	}
+	fmt.Printf("[ImageCache] Loaded %d cached images (%d bytes) from %s\n", len(c.entries), c.size, c.Dir)
+}
+
+// imageETag is a strong validator derived from the image bytes
+func imageETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
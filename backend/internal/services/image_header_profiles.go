@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// HeaderProfile is one set of request headers used to fetch an image
+type HeaderProfile struct {
+	Name      string            `json:"name"`
+	Referer   string            `json:"referer,omitempty"`
+	Origin    string            `json:"origin,omitempty"`
+	UserAgent string            `json:"userAgent,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+}
+
+// HeaderRule maps hosts (exact or glob, e.g. "*.komiku.org", "cdn?.example.com")
+// to profiles. Profiles are tried in order; the next one is used when upstream answers 403.
+type HeaderRule struct {
+	Match     []string        `json:"match"`
+	Profiles  []HeaderProfile `json:"profiles"`
+	AllowHost bool            `json:"allowHost,omitempty"` // also add Match to the image proxy allowlist
+}
+
+// HeaderProfileTable is the rules file format (IMAGE_PROXY_PROFILES_FILE, JSON)
+type HeaderProfileTable struct {
+	Rules   []HeaderRule    `json:"rules"`
+	Default []HeaderProfile `json:"default"`
+}
+
+var (
+	komikindoProfile = HeaderProfile{Name: "komikindo", Referer: "https://komikindo.ch/", Origin: "https://komikindo.ch"}
+	noRefererProfile = HeaderProfile{Name: "no-referer"}
+)
+
+// Built-in rules; a rules file replaces them
+var defaultHeaderProfileTable = HeaderProfileTable{
+	Rules: []HeaderRule{
+		{
+			Match:    []string{"komikindo.ch", "*.komikindo.ch", "*.wp.com"},
+			Profiles: []HeaderProfile{komikindoProfile, noRefererProfile},
+		},
+		{
+			Match: []string{"komiku.org", "*.komiku.org"},
+			Profiles: []HeaderProfile{
+				{Name: "komiku", Referer: "https://komiku.org/", Origin: "https://komiku.org"},
+				noRefererProfile,
+			},
+		},
+		{
+			Match: []string{"otakudesu.cloud", "*.otakudesu.cloud"},
+			Profiles: []HeaderProfile{
+				{Name: "otakudesu", Referer: "https://otakudesu.cloud/"},
+				noRefererProfile,
+			},
+		},
+		{
+			Match:    []string{"cdn.myanimelist.net", "s4.anilist.co", "placehold.co"},
+			Profiles: []HeaderProfile{noRefererProfile},
+		},
+	},
+	Default: []HeaderProfile{komikindoProfile, noRefererProfile},
+}
+
+var (
+	headerProfileTable     *HeaderProfileTable
+	headerProfileTableOnce sync.Once
+)
+
+// GetHeaderProfileTable loads IMAGE_PROXY_PROFILES_FILE once, falling back to the built-in rules
+func GetHeaderProfileTable() *HeaderProfileTable {
+	headerProfileTableOnce.Do(func() {
+		headerProfileTable = &defaultHeaderProfileTable
+
+		file := os.Getenv("IMAGE_PROXY_PROFILES_FILE")
+		if file == "" {
+			return
+		}
+
+		table, err := LoadHeaderProfileTable(file)
+		if err != nil {
+			fmt.Printf("[ImageProxy] ⚠️  Failed to load profiles from %s, using built-in rules: %v\n", file, err)
+			return
+		}
+		fmt.Printf("[ImageProxy] Loaded %d header rules from %s\n", len(table.Rules), file)
+		headerProfileTable = table
+	})
+	return headerProfileTable
+}
+
+// LoadHeaderProfileTable reads and validates a JSON rules file
+func LoadHeaderProfileTable(file string) (*HeaderProfileTable, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var table HeaderProfileTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	for i, rule := range table.Rules {
+		if len(rule.Match) == 0 || len(rule.Profiles) == 0 {
+			return nil, fmt.Errorf("rule %d needs at least one match and one profile", i)
+		}
+		for _, pattern := range rule.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d has bad pattern '%s': %w", i, pattern, err)
+			}
+		}
+	}
+	if len(table.Default) == 0 {
+		table.Default = defaultHeaderProfileTable.Default
+	}
+
+	return &table, nil
+}
+
+// ProfilesFor returns the profiles of the first rule matching host (or the defaults)
+func (t *HeaderProfileTable) ProfilesFor(host string) []HeaderProfile {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, rule := range t.Rules {
+		for _, pattern := range rule.Match {
+			if matchHostPattern(pattern, host) {
+				return rule.Profiles
+			}
+		}
+	}
+	return t.Default
+}
+
+// AllowedHosts lists patterns of rules marked allowHost
+func (t *HeaderProfileTable) AllowedHosts() []string {
+	var hosts []string
+	for _, rule := range t.Rules {
+		if rule.AllowHost {
+			hosts = append(hosts, rule.Match...)
+		}
+	}
+	return hosts
+}
+
+// Apply sets the profile headers on req (User-Agent defaults to a desktop browser)
+func (p HeaderProfile) Apply(req *http.Request) {
+	userAgent := p.UserAgent
+	if userAgent == "" {
+		userAgent = browserUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if p.Referer != "" {
+		req.Header.Set("Referer", p.Referer)
+	}
+	if p.Origin != "" {
+		req.Header.Set("Origin", p.Origin)
+	}
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// matchHostPattern compares a host with an exact name or a glob ("*" matches across dots)
+func matchHostPattern(pattern string, host string) bool {
+	pattern = strings.ToLower(pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern == host
+	}
+	matched, _ := path.Match(pattern, host)
+	return matched
+}
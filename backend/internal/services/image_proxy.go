@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Image CDN hosts the proxy may fetch from when IMAGE_PROXY_ALLOWED_HOSTS is not set.
+// Globs are allowed ("*.example.com" matches subdomains only), "*" allows any public host.
+// Rules marked allowHost in IMAGE_PROXY_PROFILES_FILE are added on top.
+var defaultImageProxyHosts = []string{
+	"komikindo.ch", "*.komikindo.ch",
+	"komiku.org", "*.komiku.org",
+	"*.wp.com",
+	"sankavollerei.com", "*.sankavollerei.com",
+	"otakudesu.cloud", "*.otakudesu.cloud",
+	"cdn.myanimelist.net",
+	"s4.anilist.co",
+	"placehold.co",
+}
+
+const defaultImageProxyMaxBytes = 15 << 20 // 15 MB
+
+var (
+	ErrHostNotAllowed   = errors.New("host is not in the image proxy allowlist")
+	ErrBlockedAddress   = errors.New("host resolves to a private or reserved address")
+	ErrInvalidSignature = errors.New("invalid or missing url signature")
+)
+
+// Extra reserved ranges not covered by net.IP helpers
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "this" network
+	"100.64.0.0/10",   // carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"240.0.0.0/4",     // reserved
+	"64:ff9b::/96",    // NAT64 (can embed private IPv4)
+	"2001:db8::/32",   // documentation
+)
+
+// ImageProxyPolicy decides which URLs /api/proxy/image may fetch
+type ImageProxyPolicy struct {
+	AllowedHosts     []string
+	MaxBytes         int64
+	SigningKey       []byte
+	RequireSignature bool
+}
+
+var (
+	imageProxyPolicy     *ImageProxyPolicy
+	imageProxyPolicyOnce sync.Once
+)
+
+// GetImageProxyPolicy loads the policy from the environment once:
+//   - IMAGE_PROXY_ALLOWED_HOSTS: comma-separated hosts (replaces the defaults)
+//   - IMAGE_PROXY_MAX_BYTES: maximum upstream response size
+//   - IMAGE_PROXY_SIGNING_KEY: HMAC key for signed proxy URLs
+//   - IMAGE_PROXY_REQUIRE_SIGNATURE=true: reject unsigned URLs
+func GetImageProxyPolicy() *ImageProxyPolicy {
+	imageProxyPolicyOnce.Do(func() {
+		policy := &ImageProxyPolicy{
+			AllowedHosts: defaultImageProxyHosts,
+			MaxBytes:     defaultImageProxyMaxBytes,
+		}
+
+		if hosts := os.Getenv("IMAGE_PROXY_ALLOWED_HOSTS"); hosts != "" {
+			policy.AllowedHosts = nil
+			for _, host := range strings.Split(hosts, ",") {
+				if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+					policy.AllowedHosts = append(policy.AllowedHosts, host)
+				}
+			}
+		}
+
+		policy.AllowedHosts = append(policy.AllowedHosts, GetHeaderProfileTable().AllowedHosts()...)
+
+		if maxBytes, err := strconv.ParseInt(os.Getenv("IMAGE_PROXY_MAX_BYTES"), 10, 64); err == nil && maxBytes > 0 {
+			policy.MaxBytes = maxBytes
+		}
+
+		if key := os.Getenv("IMAGE_PROXY_SIGNING_KEY"); key != "" {
+			policy.SigningKey = []byte(key)
+			policy.RequireSignature = os.Getenv("IMAGE_PROXY_REQUIRE_SIGNATURE") == "true"
+		} else if os.Getenv("IMAGE_PROXY_REQUIRE_SIGNATURE") == "true" {
+			fmt.Println("[ImageProxy] ⚠️  IMAGE_PROXY_REQUIRE_SIGNATURE set without IMAGE_PROXY_SIGNING_KEY, signatures disabled")
+		}
+
+		imageProxyPolicy = policy
+	})
+	return imageProxyPolicy
+}
+
+// HostAllowed matches host against the allowlist
+func (p *ImageProxyPolicy) HostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.AllowedHosts {
+		if pattern == "*" || matchHostPattern(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL validates scheme, credentials, port and host of a target URL
+func (p *ImageProxyPolicy) CheckURL(target *url.URL) error {
+	if err := CheckPublicURL(target); err != nil {
+		return err
+	}
+	if !p.HostAllowed(target.Hostname()) {
+		return ErrHostNotAllowed
+	}
+	return nil
+}
+
+// CheckPublicURL rejects non-http(s) schemes, credentials, non-web ports and private IP literals
+func CheckPublicURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme '%s'", target.Scheme)
+	}
+	if target.User != nil {
+		return errors.New("credentials in url are not allowed")
+	}
+	if port := target.Port(); port != "" && port != "80" && port != "443" {
+		return fmt.Errorf("port %s is not allowed", port)
+	}
+
+	host := target.Hostname()
+	if host == "" {
+		return errors.New("missing host")
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// Sign returns the HMAC signature for an upstream image URL
+func (p *ImageProxyPolicy) Sign(rawURL string) string {
+	mac := hmac.New(sha256.New, p.SigningKey)
+	mac.Write([]byte(rawURL))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks sig for rawURL. Unsigned URLs pass unless RequireSignature is set.
+func (p *ImageProxyPolicy) VerifySignature(rawURL string, sig string) bool {
+	if len(p.SigningKey) == 0 {
+		return true
+	}
+	if sig == "" {
+		return !p.RequireSignature
+	}
+	return hmac.Equal([]byte(p.Sign(rawURL)), []byte(sig))
+}
+
+// ProxyURL builds the /api/proxy/image path for an upstream image (signed when a key is configured)
+func (p *ImageProxyPolicy) ProxyURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+
+	proxied := "/api/proxy/image?url=" + url.QueryEscape(rawURL)
+	if len(p.SigningKey) > 0 {
+		proxied += "&sig=" + p.Sign(rawURL)
+	}
+	return proxied
+}
+
+// NewHTTPClient returns a client whose direct connections and redirects follow the policy
+func (p *ImageProxyPolicy) NewHTTPClient(timeout time.Duration) *http.Client {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           safeDialContext(proxyHostsFromEnvironment()),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConnsPerHost:   10,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if err := p.CheckURL(req.URL); err != nil {
+				return err
+			}
+			return ResolvePublicHost(req.Context(), req.URL.Hostname())
+		},
+	}
+}
+
+// ResolvePublicHost fails if any address of host is private, loopback or reserved.
+// Needed in addition to the dialer check because requests may go through HTTP_PROXY.
+// Behind a proxy this pre-resolve check is the only one: the proxy resolves the host again
+// when it connects, so a DNS rebind between the two lookups is not caught.
+func ResolvePublicHost(ctx context.Context, host string) error {
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip.IP) {
+			return ErrBlockedAddress
+		}
+	}
+	return nil
+}
+
+// IsPublicIP reports whether ip is a globally routable unicast address
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// safeDialContext resolves the target itself and only dials public addresses,
+// so DNS rebinding between the check and the connection cannot reach internal hosts.
+// Connections to the configured outbound proxy are allowed as-is, so with HTTP(S)_PROXY set
+// (as in docker-compose) only CheckPublicURL and ResolvePublicHost guard the target.
+func safeDialContext(proxyHosts map[string]bool) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyHosts[host] {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if !IsPublicIP(ip.IP) {
+				return nil, ErrBlockedAddress
+			}
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses for %s", host)
+		}
+
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+	}
+}
+
+func proxyHostsFromEnvironment() map[string]bool {
+	hosts := make(map[string]bool)
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		if value := os.Getenv(key); value != "" {
+			if proxyURL, err := url.Parse(value); err == nil && proxyURL.Hostname() != "" {
+				hosts[proxyURL.Hostname()] = true
+			}
+		}
+	}
+	return hosts
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
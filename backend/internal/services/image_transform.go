@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif" // register decoder for GIF covers
+)
+
+const (
+	maxImageDimension = 2000
+	// Decoding allocates width x height x 4+ bytes whatever the file size, so a small file
+	// declaring a huge canvas is refused before decoding (40M pixels is ~160 MB as RGBA)
+	maxSourceImagePixels = 40_000_000
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported output format (use jpeg or png)")
+	ErrImageTooLarge     = fmt.Errorf("image is larger than %d pixels", maxSourceImagePixels)
+)
+
+// ImageTransform describes a requested thumbnail (zero values keep the original)
+type ImageTransform struct {
+	Width  int
+	Height int
+	Format string // "jpeg", "png" or "" (same as source when possible)
+}
+
+// IsZero reports whether no transformation was requested
+func (t ImageTransform) IsZero() bool {
+	return t.Width == 0 && t.Height == 0 && t.Format == ""
+}
+
+// NewImageTransform validates w/h/format query values
+func NewImageTransform(width, height int, format string) (ImageTransform, error) {
+	if width < 0 || height < 0 || width > maxImageDimension || height > maxImageDimension {
+		return ImageTransform{}, fmt.Errorf("w and h must be between 0 and %d", maxImageDimension)
+	}
+
+	switch format {
+	case "", "jpeg", "png":
+	case "jpg":
+		format = "jpeg"
+	default:
+		// Pure-Go encoders only cover jpeg/png (no webp/avif encoder in the standard library)
+		return ImageTransform{}, ErrUnsupportedFormat
+	}
+
+	return ImageTransform{Width: width, Height: height, Format: format}, nil
+}
+
+// Key is a stable string used for cache addressing
+func (t ImageTransform) Key() string {
+	return fmt.Sprintf("w=%d&h=%d&f=%s", t.Width, t.Height, t.Format)
+}
+
+// TransformImage decodes body, scales it to fit within the requested box (never upscaling)
+// and re-encodes it. It returns the new bytes and content type.
+func TransformImage(body []byte, t ImageTransform) ([]byte, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxSourceImagePixels {
+		return nil, "", fmt.Errorf("%w (%dx%d)", ErrImageTooLarge, config.Width, config.Height)
+	}
+
+	src, sourceFormat, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	format := t.Format
+	if format == "" {
+		format = sourceFormat
+		if format != "png" {
+			format = "jpeg"
+		}
+	}
+
+	dst := src
+	bounds := src.Bounds()
+	width, height := fitWithin(bounds.Dx(), bounds.Dy(), t.Width, t.Height)
+	if width != bounds.Dx() || height != bounds.Dy() {
+		dst = resizeBox(src, width, height)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buf, dst)
+	default:
+		err = jpeg.Encode(&buf, flattenAlpha(dst), &jpeg.Options{Quality: 82})
+		format = "jpeg"
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return buf.Bytes(), "image/" + format, nil
+}
+
+// fitWithin keeps the aspect ratio while fitting srcW x srcH into maxW x maxH (0 = unbounded)
+func fitWithin(srcW, srcH, maxW, maxH int) (int, int) {
+	if srcW == 0 || srcH == 0 {
+		return srcW, srcH
+	}
+
+	scale := 1.0
+	if maxW > 0 && srcW > maxW {
+		scale = float64(maxW) / float64(srcW)
+	}
+	if maxH > 0 && float64(srcH)*scale > float64(maxH) {
+		scale = float64(maxH) / float64(srcH)
+	}
+
+	width := int(float64(srcW)*scale + 0.5)
+	height := int(float64(srcH)*scale + 0.5)
+	return max(width, 1), max(height, 1)
+}
+
+// resizeBox downscales with an area-averaging (box) filter, which gives clean
+// thumbnails without an external imaging library
+func resizeBox(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := bounds.Min.Y + max((y+1)*srcH/height, y*srcH/height+1)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := bounds.Min.X + max((x+1)*srcW/width, x*srcW/width+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+// flattenAlpha draws the image on white so transparent PNGs don't turn black as JPEG
+func flattenAlpha(src image.Image) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Over)
+	return dst
+}
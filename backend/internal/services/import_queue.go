@@ -0,0 +1,347 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultImportInterval         = 5 * time.Second
+	defaultImportReservedRequests = 35
+	importJobLease                = 5 * time.Minute
+	importJobHeartbeat            = 5 * time.Second
+	importJobTimeout              = 3 * time.Hour
+	maxImportJobAttempts          = 3
+	importJobRetention            = 30 // days
+)
+
+var (
+	ErrImportInProgress  = errors.New("an import is already running, wait for it to finish")
+	ErrImportJobNotFound = errors.New("import job not found")
+)
+
+// ImportQueue matches list and backup imports in the background. The upload is parsed and
+// queued in import_jobs right away; workers (on any instance, SKIP LOCKED) match the entries,
+// wait out the upstream rate limit instead of failing entries on it, and store the report
+// on the job. Their searches take tokens from the same upstream limiter as the browse
+// routes, but an entry only starts while more than ReservedRequests tokens are left, so
+// imports use the spare part of the rate limit.
+type ImportQueue struct {
+	Service          *ListTransferService
+	Interval         time.Duration
+	ReservedRequests int
+
+	limiter   *RateLimiter
+	startOnce sync.Once
+	lastPrune time.Time
+}
+
+var (
+	importQueue     *ImportQueue
+	importQueueOnce sync.Once
+)
+
+// GetImportQueue returns the shared queue. IMPORT_RESERVED_REQUESTS (default 35 of the 70
+// upstream tokens) are kept for the browse routes; each entry is one or more upstream searches.
+func GetImportQueue() *ImportQueue {
+	importQueueOnce.Do(func() {
+		reserved := defaultImportReservedRequests
+		if value := os.Getenv("IMPORT_RESERVED_REQUESTS"); value != "" {
+			if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 && parsed < upstreamLimiter.maxTokens {
+				reserved = parsed
+			} else {
+				fmt.Printf("[ListImport] ⚠️  Invalid IMPORT_RESERVED_REQUESTS %q, using %d\n", value, reserved)
+			}
+		}
+		importQueue = &ImportQueue{
+			Service:          NewListTransferService(NewDefaultProviderRegistry()),
+			Interval:         defaultImportInterval,
+			ReservedRequests: reserved,
+			limiter:          upstreamLimiter,
+		}
+	})
+	return importQueue
+}
+
+func (q *ImportQueue) Start() {
+	q.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(q.Interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if err := q.run(); err != nil {
+					fmt.Printf("[ListImport] ⚠️  Run failed: %v\n", err)
+				}
+			}
+		}()
+	})
+}
+
+// submit queues an import; payload is what the worker for format decodes
+func (q *ImportQueue) submit(userID int, format string, dryRun bool, total int, payload interface{}) (*models.ImportJob, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.ImportJob{Format: format, DryRun: dryRun, Status: "queued", Total: total}
+	err = database.DB.QueryRow(`INSERT INTO import_jobs (user_id, format, dry_run, total, payload)
+              VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`,
+		userID, format, dryRun, total, string(data)).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation: one active import per user
+		return nil, ErrImportInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue import: %w", err)
+	}
+	return job, nil
+}
+
+// Job returns one of the user's imports with its report
+func (q *ImportQueue) Job(userID int, id int64) (*models.ImportJob, error) {
+	var job models.ImportJob
+	var report []byte
+	var finishedAt sql.NullTime
+	err := database.DB.QueryRow(`SELECT id, format, dry_run, status, total, processed, COALESCE(error, ''), report,
+                  created_at, updated_at, finished_at
+              FROM import_jobs WHERE id = $1 AND user_id = $2`, id, userID).Scan(&job.ID, &job.Format, &job.DryRun,
+		&job.Status, &job.Total, &job.Processed, &job.Error, &report, &job.CreatedAt, &job.UpdatedAt, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrImportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	job.Report = report
+	return &job, nil
+}
+
+// Jobs lists the user's imports, newest first, without their reports
+func (q *ImportQueue) Jobs(userID int, page int, limit int) ([]models.ImportJob, int, error) {
+	var total int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM import_jobs WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(`SELECT id, format, dry_run, status, total, processed, COALESCE(error, ''),
+                  created_at, updated_at, finished_at
+              FROM import_jobs WHERE user_id = $1
+              ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`, userID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []models.ImportJob{}
+	for rows.Next() {
+		var job models.ImportJob
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&job.ID, &job.Format, &job.DryRun, &job.Status, &job.Total, &job.Processed, &job.Error,
+			&job.CreatedAt, &job.UpdatedAt, &finishedAt); err != nil {
+			return nil, 0, err
+		}
+		if finishedAt.Valid {
+			job.FinishedAt = &finishedAt.Time
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, total, rows.Err()
+}
+
+// run works through queued jobs one at a time until none are left
+func (q *ImportQueue) run() error {
+	for {
+		job, err := q.claim()
+		if err != nil {
+			return err
+		}
+		if job == nil {
+			break
+		}
+		q.process(job)
+	}
+
+	if time.Since(q.lastPrune) > time.Hour {
+		q.lastPrune = time.Now()
+		if _, err := database.DB.Exec(`DELETE FROM import_jobs WHERE status IN ('done', 'failed')
+              AND updated_at < NOW() - make_interval(days => $1)`, importJobRetention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type claimedImportJob struct {
+	ID       int64
+	UserID   int
+	Format   string
+	DryRun   bool
+	Attempts int
+	Payload  []byte
+}
+
+// claim leases the oldest queued job (or a running one whose worker died); nil when none
+func (q *ImportQueue) claim() (*claimedImportJob, error) {
+	var job claimedImportJob
+	err := database.DB.QueryRow(`UPDATE import_jobs
+              SET status = 'running', processed = 0, attempts = attempts + 1,
+                  locked_until = NOW() + make_interval(secs => $1), updated_at = NOW()
+              WHERE id = (
+                  SELECT id FROM import_jobs
+                  WHERE status = 'queued' OR (status = 'running' AND locked_until < NOW())
+                  ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+              RETURNING id, user_id, format, dry_run, attempts, payload`, importJobLease.Seconds()).Scan(
+		&job.ID, &job.UserID, &job.Format, &job.DryRun, &job.Attempts, &job.Payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (q *ImportQueue) process(job *claimedImportJob) {
+	if job.Attempts > maxImportJobAttempts {
+		q.finish(job.ID, "failed", nil, "import was interrupted too many times")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), importJobTimeout)
+	defer cancel()
+	run := &importRun{ctx: ctx, limiter: q.limiter, reserved: q.ReservedRequests}
+
+	// Keeps the lease and the progress the user polls up to date
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(importJobHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := database.DB.Exec(`UPDATE import_jobs SET processed = $2,
+                          locked_until = NOW() + make_interval(secs => $3), updated_at = NOW() WHERE id = $1`,
+					job.ID, run.Processed(), importJobLease.Seconds()); err != nil {
+					fmt.Printf("[ListImport] ⚠️  Failed to update import %d: %v\n", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	report, err := q.match(run, job)
+	close(done)
+	if err != nil {
+		q.finish(job.ID, "failed", nil, err.Error())
+		return
+	}
+	q.finish(job.ID, "done", report, "")
+}
+
+// match runs the import the job's format describes and returns its report
+func (q *ImportQueue) match(run *importRun, job *claimedImportJob) (interface{}, error) {
+	switch job.Format {
+	case ListFormatMAL, ListFormatAniList:
+		var entries []models.ListEntry
+		if err := json.Unmarshal(job.Payload, &entries); err != nil {
+			return nil, fmt.Errorf("failed to read queued entries: %w", err)
+		}
+		return q.Service.matchList(run, job.UserID, entries, job.Format, job.DryRun), nil
+	case ImportFormatTachiyomi:
+		var backup queuedBackup
+		if err := json.Unmarshal(job.Payload, &backup); err != nil || backup.Backup == nil {
+			return nil, fmt.Errorf("failed to read queued backup: %v", err)
+		}
+		return q.Service.matchBackup(run, job.UserID, backup, job.DryRun), nil
+	}
+	return nil, fmt.Errorf("unknown import format '%s'", job.Format)
+}
+
+func (q *ImportQueue) finish(id int64, status string, report interface{}, jobError string) {
+	var data interface{}
+	if report != nil {
+		encoded, err := json.Marshal(report)
+		if err != nil {
+			status, jobError = "failed", err.Error()
+		} else {
+			data = string(encoded)
+		}
+	}
+
+	if _, err := database.DB.Exec(`UPDATE import_jobs SET status = $2, report = $3, error = NULLIF($4, ''),
+              processed = CASE WHEN $2 = 'done' THEN total ELSE processed END,
+              payload = NULL, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+              WHERE id = $1`, id, status, data, jobError); err != nil {
+		fmt.Printf("[ListImport] ⚠️  Failed to finish import %d: %v\n", id, err)
+	}
+}
+
+// importRun paces the entries of one job and counts how many are done
+type importRun struct {
+	ctx       context.Context
+	limiter   *RateLimiter
+	reserved  int
+	processed atomic.Int64
+}
+
+func (r *importRun) Processed() int {
+	return int(r.processed.Load())
+}
+
+// each runs match for entries 0..n-1 on importWorkers goroutines. An entry that hit the
+// upstream rate limit is tried again once the limiter has spare tokens; aborted gives the result
+// for entries the job ran out of time for.
+func (r *importRun) each(n int, match func(i int) (models.ImportEntryResult, error), aborted func(i int) models.ImportEntryResult) []models.ImportEntryResult {
+	results := make([]models.ImportEntryResult, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < importWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = r.entry(i, match, aborted)
+				r.processed.Add(1)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (r *importRun) entry(i int, match func(i int) (models.ImportEntryResult, error), aborted func(i int) models.ImportEntryResult) models.ImportEntryResult {
+	for {
+		if err := r.limiter.WaitAbove(r.ctx, r.reserved); err != nil {
+			return aborted(i)
+		}
+
+		// Rate limited entries wait above for the limiter to have spare tokens again
+		result, err := match(i)
+		var limited *RateLimitError
+		if !errors.As(err, &limited) {
+			return result
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Supported list formats
+const (
+	ListFormatMAL     = "mal"
+	ListFormatAniList = "anilist"
+
+	ImportFormatTachiyomi = "tachiyomi" // Backups, imported but not exported
+)
+
+// List statuses (AniList names, lowercased)
+const (
+	ListStatusCurrent   = "current"
+	ListStatusCompleted = "completed"
+	ListStatusPaused    = "paused"
+	ListStatusDropped   = "dropped"
+	ListStatusPlanning  = "planning"
+)
+
+const maxImportBytes = 10 << 20 // 10 MB after decompression
+
+var ErrUnknownListFormat = errors.New("unknown list format (expected MyAnimeList XML or AniList JSON)")
+
+// ========== Parsing ==========
+
+// ParseListExport detects and parses a MAL XML (optionally gzipped) or AniList JSON export.
+// format may be "" to auto-detect; the detected format is returned.
+func ParseListExport(data []byte, format string) ([]models.ListEntry, string, error) {
+	// MAL offers exports as .xml.gz
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid gzip file: %w", err)
+		}
+		data, err = io.ReadAll(io.LimitReader(reader, maxImportBytes+1))
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid gzip file: %w", err)
+		}
+		if len(data) > maxImportBytes {
+			return nil, "", fmt.Errorf("import file too large")
+		}
+	}
+
+	if format == "" {
+		trimmed := bytes.TrimLeft(data, "\ufeff \t\r\n")
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("<")):
+			format = ListFormatMAL
+		case bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+			format = ListFormatAniList
+		}
+	}
+
+	switch format {
+	case ListFormatMAL:
+		entries, err := ParseMALExport(data)
+		return entries, format, err
+	case ListFormatAniList:
+		entries, err := ParseAniListExport(data)
+		return entries, format, err
+	default:
+		return nil, "", ErrUnknownListFormat
+	}
+}
+
+// malCDATA writes titles as CDATA like MAL's own export
+type malCDATA struct {
+	Value string `xml:",cdata"`
+}
+
+type malAnime struct {
+	ID             int      `xml:"series_animedb_id"`
+	Title          malCDATA `xml:"series_title"`
+	Type           string   `xml:"series_type,omitempty"`
+	Episodes       int      `xml:"series_episodes"`
+	WatchedEps     int      `xml:"my_watched_episodes"`
+	Score          int      `xml:"my_score"`
+	Status         string   `xml:"my_status"`
+	Comments       malCDATA `xml:"my_comments"`
+	UpdateOnImport int      `xml:"update_on_import"`
+}
+
+type malManga struct {
+	ID             int      `xml:"manga_mangadb_id"`
+	Title          malCDATA `xml:"manga_title"`
+	Chapters       int      `xml:"manga_chapters"`
+	ReadChapters   int      `xml:"my_read_chapters"`
+	Score          int      `xml:"my_score"`
+	Status         string   `xml:"my_status"`
+	Comments       malCDATA `xml:"my_comments"`
+	UpdateOnImport int      `xml:"update_on_import"`
+}
+
+type malExport struct {
+	XMLName xml.Name `xml:"myanimelist"`
+	MyInfo  struct {
+		ExportType int `xml:"user_export_type"` // 1 = anime, 2 = manga
+	} `xml:"myinfo"`
+	Anime []malAnime `xml:"anime"`
+	Manga []malManga `xml:"manga"`
+}
+
+// ParseMALExport reads a MyAnimeList XML export (anime or manga list)
+func ParseMALExport(data []byte) ([]models.ListEntry, error) {
+	var export malExport
+	if err := xml.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid MyAnimeList XML: %w", err)
+	}
+
+	var entries []models.ListEntry
+	for _, a := range export.Anime {
+		entries = append(entries, models.ListEntry{
+			Type:       "anime",
+			Title:      strings.TrimSpace(a.Title.Value),
+			Status:     malStatusToList(a.Status),
+			Progress:   a.WatchedEps,
+			Total:      a.Episodes,
+			Score:      float64(a.Score),
+			ExternalID: a.ID,
+			Slug:       slugFromListNote(a.Comments.Value),
+		})
+	}
+	for _, m := range export.Manga {
+		entries = append(entries, models.ListEntry{
+			Type:       "manga",
+			Title:      strings.TrimSpace(m.Title.Value),
+			Status:     malStatusToList(m.Status),
+			Progress:   m.ReadChapters,
+			Total:      m.Chapters,
+			Score:      float64(m.Score),
+			ExternalID: m.ID,
+			Slug:       slugFromListNote(m.Comments.Value),
+		})
+	}
+	return entries, nil
+}
+
+type aniListEntry struct {
+	Status   string  `json:"status"`
+	Progress int     `json:"progress"`
+	Score    float64 `json:"score"`
+	Notes    string  `json:"notes,omitempty"`
+	Media    struct {
+		ID    int `json:"id"`
+		IDMal int `json:"idMal"`
+		Title struct {
+			Romaji  string `json:"romaji"`
+			English string `json:"english"`
+			Native  string `json:"native"`
+		} `json:"title"`
+		Type     string `json:"type"` // ANIME or MANGA
+		Episodes int    `json:"episodes,omitempty"`
+		Chapters int    `json:"chapters,omitempty"`
+		Cover    *struct {
+			Large string `json:"large"`
+		} `json:"coverImage,omitempty"`
+	} `json:"media"`
+}
+
+type aniListList struct {
+	Name    string         `json:"name"`
+	Entries []aniListEntry `json:"entries"`
+}
+
+type aniListUser struct {
+	MediaListOptions struct {
+		ScoreFormat string `json:"scoreFormat"` // POINT_100, POINT_10_DECIMAL, POINT_10, POINT_5 or POINT_3
+	} `json:"mediaListOptions"`
+}
+
+type aniListCollection struct {
+	Lists []aniListList `json:"lists"`
+	User  *aniListUser  `json:"user,omitempty"`
+}
+
+// scoreScale converts the collection's scores to 0-10. The export should include
+// user { mediaListOptions { scoreFormat } }; without it scores are taken as 0-10
+// (POINT_10 / POINT_10_DECIMAL, which our own exports use), and larger ones are dropped later.
+func (c aniListCollection) scoreScale() float64 {
+	if c.User == nil {
+		return 1
+	}
+	switch c.User.MediaListOptions.ScoreFormat {
+	case "POINT_100":
+		return 0.1
+	case "POINT_5":
+		return 2
+	case "POINT_3":
+		return 10.0 / 3
+	default:
+		return 1
+	}
+}
+
+// ParseAniListExport reads AniList MediaListCollection JSON. Accepted shapes:
+// the GraphQL response ({"data":{"MediaListCollection":...}}), the collection itself,
+// or a list of collections (e.g. one for anime and one for manga). Scores are scaled to
+// 0-10 from the collection's user.mediaListOptions.scoreFormat.
+func ParseAniListExport(data []byte) ([]models.ListEntry, error) {
+	var collections []aniListCollection
+
+	var wrapped struct {
+		Data struct {
+			MediaListCollection *aniListCollection `json:"MediaListCollection"`
+		} `json:"data"`
+		MediaListCollection *aniListCollection `json:"MediaListCollection"`
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		if err := json.Unmarshal(data, &collections); err != nil {
+			return nil, fmt.Errorf("invalid AniList JSON: %w", err)
+		}
+	} else {
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid AniList JSON: %w", err)
+		}
+		switch {
+		case wrapped.Data.MediaListCollection != nil:
+			collections = append(collections, *wrapped.Data.MediaListCollection)
+		case wrapped.MediaListCollection != nil:
+			collections = append(collections, *wrapped.MediaListCollection)
+		default:
+			var bare aniListCollection
+			if err := json.Unmarshal(data, &bare); err != nil || len(bare.Lists) == 0 {
+				return nil, fmt.Errorf("invalid AniList JSON: no MediaListCollection found")
+			}
+			collections = append(collections, bare)
+		}
+	}
+
+	var entries []models.ListEntry
+	for _, collection := range collections {
+		scale := collection.scoreScale()
+		for _, list := range collection.Lists {
+			for _, e := range list.Entries {
+				entry := models.ListEntry{
+					Type:       strings.ToLower(e.Media.Type),
+					Status:     strings.ToLower(e.Status),
+					Progress:   e.Progress,
+					Score:      e.Score * scale,
+					ExternalID: e.Media.IDMal,
+					Slug:       slugFromListNote(e.Notes),
+				}
+				if entry.Status == "repeating" {
+					entry.Status = ListStatusCurrent
+				}
+				if entry.Type == "anime" {
+					entry.Total = e.Media.Episodes
+				} else {
+					entry.Total = e.Media.Chapters
+				}
+				if e.Media.Cover != nil {
+					entry.CoverImage = e.Media.Cover.Large
+				}
+
+				// Prefer romaji (closest to the titles on Indonesian sources), keep the rest for matching
+				for _, title := range []string{e.Media.Title.Romaji, e.Media.Title.English, e.Media.Title.Native} {
+					if title = strings.TrimSpace(title); title == "" {
+						continue
+					}
+					if entry.Title == "" {
+						entry.Title = title
+					} else if title != entry.Title {
+						entry.AltTitles = append(entry.AltTitles, title)
+					}
+				}
+
+				if entry.Title != "" && (entry.Type == "anime" || entry.Type == "manga") {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// slugFromListNote reads back the "slug:<slug>" note written by our exports
+func slugFromListNote(note string) string {
+	if slug, ok := strings.CutPrefix(strings.TrimSpace(note), "slug:"); ok {
+		return strings.TrimSpace(slug)
+	}
+	return ""
+}
+
+func malStatusToList(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "watching", "reading":
+		return ListStatusCurrent
+	case "completed":
+		return ListStatusCompleted
+	case "on-hold":
+		return ListStatusPaused
+	case "dropped":
+		return ListStatusDropped
+	default:
+		return ListStatusPlanning
+	}
+}
+
+func listStatusToMAL(status string, mediaType string) string {
+	switch status {
+	case ListStatusCurrent:
+		if mediaType == "manga" {
+			return "Reading"
+		}
+		return "Watching"
+	case ListStatusCompleted:
+		return "Completed"
+	case ListStatusPaused:
+		return "On-Hold"
+	case ListStatusDropped:
+		return "Dropped"
+	default:
+		if mediaType == "manga" {
+			return "Plan to Read"
+		}
+		return "Plan to Watch"
+	}
+}
+
+// ========== Writing ==========
+
+// WriteMALExport writes entries of one media type as a MAL import-compatible XML file.
+// Our slug goes into my_comments since we have no MAL ids (AniList: notes).
+func WriteMALExport(w io.Writer, entries []models.ListEntry, mediaType string) error {
+	export := malExport{}
+	if mediaType == "manga" {
+		export.MyInfo.ExportType = 2
+	} else {
+		export.MyInfo.ExportType = 1
+	}
+
+	for _, e := range entries {
+		if e.Type != mediaType {
+			continue
+		}
+		comment := malCDATA{Value: "slug:" + e.Slug}
+		if mediaType == "manga" {
+			export.Manga = append(export.Manga, malManga{
+				ID:             e.ExternalID,
+				Title:          malCDATA{Value: e.Title},
+				Chapters:       e.Total,
+				ReadChapters:   e.Progress,
+				Score:          int(e.Score),
+				Status:         listStatusToMAL(e.Status, mediaType),
+				Comments:       comment,
+				UpdateOnImport: 1,
+			})
+		} else {
+			export.Anime = append(export.Anime, malAnime{
+				ID:             e.ExternalID,
+				Title:          malCDATA{Value: e.Title},
+				Episodes:       e.Total,
+				WatchedEps:     e.Progress,
+				Score:          int(e.Score),
+				Status:         listStatusToMAL(e.Status, mediaType),
+				Comments:       comment,
+				UpdateOnImport: 1,
+			})
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	return encoder.Encode(export)
+}
+
+// WriteAniListExport writes entries as a MediaListCollection grouped by status
+func WriteAniListExport(w io.Writer, entries []models.ListEntry) error {
+	listNames := map[string]string{
+		ListStatusCurrent:   "Current",
+		ListStatusCompleted: "Completed",
+		ListStatusPaused:    "Paused",
+		ListStatusDropped:   "Dropped",
+		ListStatusPlanning:  "Planning",
+	}
+	order := []string{ListStatusCurrent, ListStatusCompleted, ListStatusPaused, ListStatusDropped, ListStatusPlanning}
+
+	grouped := make(map[string][]aniListEntry)
+	for _, e := range entries {
+		var entry aniListEntry
+		entry.Status = strings.ToUpper(e.Status)
+		entry.Progress = e.Progress
+		entry.Score = e.Score
+		entry.Notes = "slug:" + e.Slug
+		entry.Media.IDMal = e.ExternalID
+		entry.Media.Title.Romaji = e.Title
+		entry.Media.Type = strings.ToUpper(e.Type)
+		if e.Type == "anime" {
+			entry.Media.Episodes = e.Total
+		} else {
+			entry.Media.Chapters = e.Total
+		}
+		if e.CoverImage != "" {
+			entry.Media.Cover = &struct {
+				Large string `json:"large"`
+			}{Large: e.CoverImage}
+		}
+		grouped[e.Status] = append(grouped[e.Status], entry)
+	}
+
+	// Bookmark scores are whole numbers out of 10
+	collection := aniListCollection{User: &aniListUser{}}
+	collection.User.MediaListOptions.ScoreFormat = "POINT_10"
+	for _, status := range order {
+		if len(grouped[status]) == 0 {
+			continue
+		}
+		collection.Lists = append(collection.Lists, aniListList{Name: listNames[status], Entries: grouped[status]})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{
+		"data": map[string]interface{}{"MediaListCollection": collection},
+	})
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testListEntries = []models.ListEntry{
+	{Type: "anime", Title: "Sousou no Frieren", Slug: "frieren-sub-indo", Status: ListStatusCurrent, Progress: 12, Total: 28, Score: 9, ExternalID: 52991},
+	{Type: "anime", Title: "Bocchi the Rock!", Slug: "bocchi-rock-sub-indo", Status: ListStatusCompleted, Progress: 12, Total: 12, Score: 8},
+	{Type: "anime", Title: "Kusuriya no Hitorigoto", Slug: "kusuriya-hitorigoto", Status: ListStatusPlanning},
+	{Type: "manga", Title: "One Piece", Slug: "one-piece", Status: ListStatusPaused, Progress: 1100, Score: 10, ExternalID: 13},
+	{Type: "manga", Title: "Chainsaw Man", Slug: "chainsaw-man", Status: ListStatusDropped, Progress: 40},
+}
+
+func entriesOfType(entries []models.ListEntry, mediaType string) []models.ListEntry {
+	var filtered []models.ListEntry
+	for _, e := range entries {
+		if e.Type == mediaType {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+func TestMALExportRoundTrip(t *testing.T) {
+	for _, mediaType := range []string{"anime", "manga"} {
+		t.Run(mediaType, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMALExport(&buf, testListEntries, mediaType); err != nil {
+				t.Fatalf("WriteMALExport: %v", err)
+			}
+			if !strings.Contains(buf.String(), "<![CDATA[slug:") {
+				t.Errorf("export does not keep the slug in a CDATA comment:\n%s", buf.String())
+			}
+
+			var gzipped bytes.Buffer
+			zw := gzip.NewWriter(&gzipped)
+			zw.Write(buf.Bytes())
+			zw.Close()
+
+			want := entriesOfType(testListEntries, mediaType)
+			for name, data := range map[string][]byte{"xml": buf.Bytes(), "gzip": gzipped.Bytes()} {
+				entries, format, err := ParseListExport(data, "")
+				if err != nil {
+					t.Fatalf("%s: ParseListExport: %v", name, err)
+				}
+				if format != ListFormatMAL {
+					t.Errorf("%s: detected format %q, want %q", name, format, ListFormatMAL)
+				}
+				if !reflect.DeepEqual(entries, want) {
+					t.Errorf("%s: entries = %+v, want %+v", name, entries, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAniListExportRoundTrip(t *testing.T) {
+	entries := append([]models.ListEntry(nil), testListEntries...)
+	entries[0].CoverImage = "https://s4.anilist.co/file/frieren.jpg"
+
+	var buf bytes.Buffer
+	if err := WriteAniListExport(&buf, entries); err != nil {
+		t.Fatalf("WriteAniListExport: %v", err)
+	}
+
+	parsed, format, err := ParseListExport(buf.Bytes(), "")
+	if err != nil {
+		t.Fatalf("ParseListExport: %v", err)
+	}
+	if format != ListFormatAniList {
+		t.Errorf("detected format %q, want %q", format, ListFormatAniList)
+	}
+
+	// Written grouped by status, in list order
+	want := []models.ListEntry{entries[0], entries[1], entries[3], entries[4], entries[2]}
+	if !reflect.DeepEqual(parsed, want) {
+		t.Errorf("entries = %+v, want %+v", parsed, want)
+	}
+}
+
+func TestParseAniListExportShapes(t *testing.T) {
+	const collection = `{"lists":[{"name":"Watching","entries":[
+		{"status":"REPEATING","progress":3,"score":85,"notes":"rewatch",
+		 "media":{"idMal":52991,"type":"ANIME","episodes":28,
+		          "title":{"romaji":"Sousou no Frieren","english":"Frieren: Beyond Journey's End","native":"葬送のフリーレン"},
+		          "coverImage":{"large":"https://s4.anilist.co/file/frieren.jpg"}}},
+		{"status":"CURRENT","progress":5,"notes":"slug: one-piece ",
+		 "media":{"type":"MANGA","chapters":0,"title":{"romaji":"ONE PIECE","english":"ONE PIECE"}}},
+		{"status":"CURRENT","media":{"type":"NOVEL","title":{"romaji":"Some Novel"}}},
+		{"status":"CURRENT","media":{"type":"ANIME","title":{}}}
+	]}],"user":{"mediaListOptions":{"scoreFormat":"POINT_100"}}}`
+
+	want := []models.ListEntry{
+		{
+			Type: "anime", Title: "Sousou no Frieren",
+			AltTitles: []string{"Frieren: Beyond Journey's End", "葬送のフリーレン"},
+			Status:    ListStatusCurrent, Progress: 3, Total: 28, Score: 8.5, ExternalID: 52991,
+			CoverImage: "https://s4.anilist.co/file/frieren.jpg",
+		},
+		{Type: "manga", Title: "ONE PIECE", Slug: "one-piece", Status: ListStatusCurrent, Progress: 5},
+	}
+
+	for name, data := range map[string]string{
+		"graphql response":    `{"data":{"MediaListCollection":` + collection + `}}`,
+		"wrapped collection":  `{"MediaListCollection":` + collection + `}`,
+		"bare collection":     collection,
+		"list of collections": `[` + collection + `]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			entries, err := ParseAniListExport([]byte(data))
+			if err != nil {
+				t.Fatalf("ParseAniListExport: %v", err)
+			}
+			if !reflect.DeepEqual(entries, want) {
+				t.Errorf("entries = %+v, want %+v", entries, want)
+			}
+		})
+	}
+}
+
+func TestParseListExportRejectsUnknownFormats(t *testing.T) {
+	if _, _, err := ParseListExport([]byte("title,status\nFrieren,watching\n"), ""); !errors.Is(err, ErrUnknownListFormat) {
+		t.Errorf("CSV = %v, want ErrUnknownListFormat", err)
+	}
+	if _, err := ParseAniListExport([]byte(`{"data":{}}`)); err == nil {
+		t.Error("accepted JSON without a MediaListCollection")
+	}
+	if _, err := ParseMALExport([]byte("<myanimelist><anime>")); err == nil {
+		t.Error("accepted truncated XML")
+	}
+}
+
+func TestParseAniListExportScoreFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		score  string
+		want   float64
+	}{
+		{"", "8.5", 8.5}, // No format: 0-10
+		{"POINT_10_DECIMAL", "8.5", 8.5},
+		{"POINT_10", "7", 7},
+		{"POINT_100", "85", 8.5},
+		{"POINT_100", "10", 1},
+		{"POINT_5", "4", 8},
+		{"POINT_3", "3", 10},
+		{"POINT_3", "0", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.score, func(t *testing.T) {
+			user := ""
+			if tt.format != "" {
+				user = `,"user":{"mediaListOptions":{"scoreFormat":"` + tt.format + `"}}`
+			}
+			data := `{"data":{"MediaListCollection":{"lists":[{"entries":[{"status":"COMPLETED","score":` + tt.score +
+				`,"media":{"type":"ANIME","title":{"romaji":"Frieren"}}}]}]` + user + `}}}`
+
+			entries, err := ParseAniListExport([]byte(data))
+			if err != nil {
+				t.Fatalf("ParseAniListExport: %v", err)
+			}
+			if len(entries) != 1 || entries[0].Score != tt.want {
+				t.Fatalf("entries = %+v, want score %v", entries, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookmarkScore(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  int // 0: unscored
+	}{
+		{0, 0},
+		{0.4, 0},
+		{1, 1},
+		{7.5, 8},
+		{8.5, 9},
+		{10, 10},
+		{11, 0}, // Out of range: not a 0-10 score
+		{85, 0},
+		{-3, 0},
+	}
+
+	for _, tt := range tests {
+		got := bookmarkScore(tt.score)
+		switch {
+		case tt.want == 0 && got != nil:
+			t.Errorf("bookmarkScore(%v) = %d, want unscored", tt.score, *got)
+		case tt.want != 0 && (got == nil || *got != tt.want):
+			t.Errorf("bookmarkScore(%v) = %v, want %d", tt.score, got, tt.want)
+		}
+	}
+}
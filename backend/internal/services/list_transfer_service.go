@@ -0,0 +1,425 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxImportEntries = 1000
+	importWorkers    = 4 // Paced by ImportQueue anyway
+	maxCandidates    = 5
+)
+
+// Titles on our sources often carry these words; they are ignored when matching
+var titleNoiseWords = map[string]bool{"sub": true, "indo": true, "subtitle": true, "indonesia": true}
+
+var (
+	ErrTooManyEntries  = fmt.Errorf("import is limited to %d entries", maxImportEntries)
+	ErrNothingToImport = errors.New("no list entries found in file")
+)
+
+// ListTransferService imports MAL/AniList lists and Tachiyomi backups into bookmarks
+// and exports bookmarks + progress
+type ListTransferService struct {
+	Bookmarks *BookmarkService
+	Reading   *ReadingProgressService
+	Providers *ProviderRegistry
+}
+
+func NewListTransferService(providers *ProviderRegistry) *ListTransferService {
+	return &ListTransferService{
+		Bookmarks: NewBookmarkService(),
+		Reading:   NewReadingProgressService(NewSankavollereiService("")),
+		Providers: providers,
+	}
+}
+
+// Import queues a parsed MAL/AniList list; ImportQueue matches it in the background
+func (s *ListTransferService) Import(userID int, entries []models.ListEntry, format string, dryRun bool) (*models.ImportJob, error) {
+	if len(entries) == 0 {
+		return nil, ErrNothingToImport
+	}
+	if len(entries) > maxImportEntries {
+		return nil, ErrTooManyEntries
+	}
+	return GetImportQueue().submit(userID, format, dryRun, len(entries), entries)
+}
+
+// matchList matches every entry against the default anime/manga provider and bookmarks the
+// unambiguous matches (nothing is written when dryRun is set)
+func (s *ListTransferService) matchList(run *importRun, userID int, entries []models.ListEntry, format string, dryRun bool) *models.ImportReport {
+	report := &models.ImportReport{
+		Format: format,
+		DryRun: dryRun,
+		Total:  len(entries),
+	}
+	report.Entries = run.each(len(entries), func(i int) (models.ImportEntryResult, error) {
+		return s.importEntry(userID, entries[i], dryRun)
+	}, func(i int) models.ImportEntryResult {
+		return abortedImportEntry(entries[i].Type, entries[i].Title)
+	})
+
+	countImportResults(report)
+	fmt.Printf("[ListImport] User %d imported %s list: %d matched, %d ambiguous, %d unmatched, %d failed\n",
+		userID, format, report.Matched, report.Ambiguous, report.Unmatched, report.Failed)
+	return report
+}
+
+func abortedImportEntry(mediaType string, title string) models.ImportEntryResult {
+	return models.ImportEntryResult{Type: mediaType, Title: title, Result: models.ImportFailed, Error: "import timed out"}
+}
+
+func countImportResults(report *models.ImportReport) {
+	for _, entry := range report.Entries {
+		switch entry.Result {
+		case models.ImportMatched:
+			report.Matched++
+		case models.ImportAmbiguous:
+			report.Ambiguous++
+		case models.ImportUnmatched:
+			report.Unmatched++
+		default:
+			report.Failed++
+		}
+	}
+}
+
+// importEntry also returns the upstream error an entry failed on, so throttled entries can
+// be tried again
+func (s *ListTransferService) importEntry(userID int, entry models.ListEntry, dryRun bool) (models.ImportEntryResult, error) {
+	result := models.ImportEntryResult{Type: entry.Type, Title: entry.Title}
+	if entry.Type != "anime" && entry.Type != "manga" {
+		result.Result = models.ImportFailed
+		result.Error = fmt.Sprintf("unsupported type '%s'", entry.Type)
+		return result, nil
+	}
+
+	var match *models.ImportCandidate
+	if entry.Slug != "" {
+		// Our own export: slug is already known
+		match = &models.ImportCandidate{Slug: entry.Slug, Title: entry.Title, Cover: entry.CoverImage}
+	} else {
+		var candidates []models.ImportCandidate
+		var err error
+		match, candidates, err = s.matchTitle(entry)
+		switch {
+		case err != nil:
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+			return result, err
+		case match == nil && len(candidates) > 0:
+			result.Result = models.ImportAmbiguous
+			result.Candidates = candidates
+			return result, nil
+		case match == nil:
+			result.Result = models.ImportUnmatched
+			return result, nil
+		}
+	}
+
+	result.Result = models.ImportMatched
+	result.Slug = match.Slug
+	result.MatchedTitle = match.Title
+
+	if !dryRun {
+		cover := match.Cover
+		if cover == "" {
+			cover = entry.CoverImage
+		}
+		bookmark := models.Bookmark{
+			UserID:     userID,
+			Type:       entry.Type,
+			Slug:       match.Slug,
+			Title:      match.Title,
+			CoverImage: cover,
+			Status:     entry.Status,
+		}
+		bookmark.Score = bookmarkScore(entry.Score)
+		existed, err := s.Bookmarks.ImportBookmark(bookmark)
+		if err != nil {
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+		}
+		result.Updated = existed
+	}
+	return result, nil
+}
+
+// bookmarkScore maps a 0-10 list score to a 1-10 bookmark score (nil when unscored or out
+// of range). ParseAniListExport has already scaled other AniList score formats.
+func bookmarkScore(score float64) *int {
+	rounded := int(math.Round(score))
+	if rounded < 1 || rounded > 10 {
+		return nil
+	}
+	return &rounded
+}
+
+type scoredCandidate struct {
+	models.ImportCandidate
+	score float64
+}
+
+// matchTitle searches every title of the entry and returns the single confident match,
+// or the best candidates when the choice is ambiguous
+func (s *ListTransferService) matchTitle(entry models.ListEntry) (*models.ImportCandidate, []models.ImportCandidate, error) {
+	best := make(map[string]*scoredCandidate)
+	var searchErr error
+	searched := 0
+
+	for _, title := range append([]string{entry.Title}, entry.AltTitles...) {
+		query := normalizeTitle(title)
+		if query == "" {
+			continue // e.g. native (Japanese) titles, our sources don't index them
+		}
+
+		results, err := s.search(entry.Type, title)
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			return nil, nil, err // Matching on the other titles alone could pick the wrong series
+		}
+		if err != nil {
+			searchErr = err
+			continue
+		}
+		searched++
+
+		exact := false
+		for _, candidate := range results {
+			score := titleSimilarity(query, normalizeTitle(candidate.Title))
+			if current, ok := best[candidate.Slug]; !ok || score > current.score {
+				best[candidate.Slug] = &scoredCandidate{ImportCandidate: candidate, score: score}
+			}
+			exact = exact || score == 1
+		}
+		if exact {
+			break
+		}
+	}
+
+	if searched == 0 && searchErr != nil {
+		return nil, nil, searchErr
+	}
+
+	ranked := make([]scoredCandidate, 0, len(best))
+	for _, candidate := range best {
+		ranked = append(ranked, *candidate)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	if len(ranked) == 0 {
+		return nil, nil, nil
+	}
+
+	second := 0.0
+	if len(ranked) > 1 {
+		second = ranked[1].score
+	}
+	top := ranked[0]
+	if (top.score == 1 && second < 1) || (top.score >= 0.85 && top.score-second >= 0.15) {
+		return &top.ImportCandidate, nil, nil
+	}
+
+	candidates := make([]models.ImportCandidate, 0, maxCandidates)
+	for i := 0; i < len(ranked) && i < maxCandidates; i++ {
+		if ranked[i].score > 0 {
+			candidates = append(candidates, ranked[i].ImportCandidate)
+		}
+	}
+	return nil, candidates, nil
+}
+
+func (s *ListTransferService) search(mediaType string, title string) ([]models.ImportCandidate, error) {
+	var candidates []models.ImportCandidate
+
+	if mediaType == "manga" {
+		provider, err := s.Providers.Manga("")
+		if err != nil {
+			return nil, err
+		}
+		results, err := provider.SearchManga(title)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range results {
+			slug := m.Slug
+			if slug == "" && m.Link != "" {
+				slug = path.Base(strings.TrimSuffix(m.Link, "/"))
+			}
+			if slug != "" {
+				candidates = append(candidates, models.ImportCandidate{Slug: slug, Title: m.Title, Cover: firstNonEmpty(m.Image, m.Cover, m.Poster, m.Thumbnail)})
+			}
+		}
+		return candidates, nil
+	}
+
+	provider, err := s.Providers.Anime("")
+	if err != nil {
+		return nil, err
+	}
+	results, err := provider.SearchAnime(title)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range results {
+		slug := a.Slug
+		if slug == "" {
+			slug = a.AnimeID
+		}
+		if slug != "" {
+			candidates = append(candidates, models.ImportCandidate{Slug: slug, Title: a.Title, Cover: firstNonEmpty(a.Poster, a.Cover, a.Image, a.Thumbnail)})
+		}
+	}
+	return candidates, nil
+}
+
+// Export returns the user's bookmarks plus every series with watch/read progress.
+// Progress is the number of completed episodes / read chapters; planned titles with
+// progress are exported as current.
+func (s *ListTransferService) Export(userID int) ([]models.ListEntry, error) {
+	entries := make(map[string]*models.ListEntry)
+	var order []string
+	add := func(mediaType, slug, title, cover string) *models.ListEntry {
+		key := mediaType + "/" + slug
+		if entry, ok := entries[key]; ok {
+			if entry.Title == "" {
+				entry.Title = title
+			}
+			if entry.CoverImage == "" {
+				entry.CoverImage = cover
+			}
+			return entry
+		}
+		entries[key] = &models.ListEntry{Type: mediaType, Slug: slug, Title: title, CoverImage: cover, Status: ListStatusPlanning}
+		order = append(order, key)
+		return entries[key]
+	}
+
+	bookmarks, err := s.Bookmarks.GetBookmarks(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bookmarks {
+		entry := add(b.Type, b.Slug, b.Title, b.CoverImage)
+		entry.Status = b.Status
+		if b.Score != nil {
+			entry.Score = float64(*b.Score)
+		}
+	}
+
+	rows, err := database.DB.Query(`SELECT anime_slug, MAX(COALESCE(anime_title, '')), MAX(COALESCE(cover_image, '')),
+                  COUNT(*) FILTER (WHERE completed)
+              FROM watch_history WHERE user_id = $1 GROUP BY anime_slug`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var slug, title, cover string
+		var watched int
+		if err := rows.Scan(&slug, &title, &cover, &watched); err != nil {
+			return nil, err
+		}
+		entry := add("anime", slug, title, cover)
+		entry.Progress = watched
+		if entry.Status == ListStatusPlanning {
+			entry.Status = ListStatusCurrent
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	mangaRows, err := database.DB.Query(`SELECT slugs.manga_slug, COALESCE(p.manga_title, ''), COALESCE(p.cover_image, ''),
+                  (SELECT COUNT(*) FROM manga_chapter_reads r WHERE r.user_id = $1 AND r.manga_slug = slugs.manga_slug)
+              FROM (SELECT manga_slug FROM manga_reading_progress WHERE user_id = $1
+                    UNION SELECT manga_slug FROM manga_chapter_reads WHERE user_id = $1) slugs
+              LEFT JOIN manga_reading_progress p ON p.user_id = $1 AND p.manga_slug = slugs.manga_slug`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer mangaRows.Close()
+	for mangaRows.Next() {
+		var slug, title, cover string
+		var read int
+		if err := mangaRows.Scan(&slug, &title, &cover, &read); err != nil {
+			return nil, err
+		}
+		entry := add("manga", slug, title, cover)
+		entry.Progress = read
+		if entry.Status == ListStatusPlanning {
+			entry.Status = ListStatusCurrent
+		}
+	}
+	if err := mangaRows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ListEntry, 0, len(order))
+	for _, key := range order {
+		if entries[key].Title == "" {
+			entries[key].Title = entries[key].Slug
+		}
+		result = append(result, *entries[key])
+	}
+	return result, nil
+}
+
+// normalizeTitle lowercases and keeps only latin letters/digits, dropping noise words
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+
+	kept := fields[:0]
+	for _, field := range fields {
+		if !titleNoiseWords[field] {
+			kept = append(kept, field)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// titleSimilarity is 1 for equal titles, otherwise the Dice coefficient of their words
+func titleSimilarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	wordsA := strings.Fields(a)
+	wordsB := strings.Fields(b)
+	counts := make(map[string]int, len(wordsA))
+	for _, word := range wordsA {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range wordsB {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+
+	// Never call a partial overlap a perfect match
+	return math.Min(0.99, 2*float64(common)/float64(len(wordsA)+len(wordsB)))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
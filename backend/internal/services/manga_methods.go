@@ -93,34 +93,31 @@ func (s *SankavollereiService) filterBlacklistedManga(items []models.Manga) []mo
 	return filtered
 }
 
-// enrichMangaWithGemini uses Gemini AI to fill missing data (Year, Rating, etc.)
-func (s *SankavollereiService) enrichMangaWithGemini(items []models.Manga) []models.Manga {
-	var wg sync.WaitGroup
-	// Limit Concurrency for AI to 5 to be safe with rate limits
-	maxConcurrency := 5
-	sem := make(chan struct{}, maxConcurrency)
-
+// enrichMangaList fills missing data (Year, Status) from enriched_metadata; titles without
+// metadata are queued for the enrichment workers instead of blocking the request
+func (s *SankavollereiService) enrichMangaList(items []models.Manga) []models.Manga {
+	var titles []string
 	for i := range items {
 		// Only enrich if missing data
 		if items[i].ReleaseDate == "" {
-			wg.Add(1)
-			go func(idx int) {
-				defer wg.Done()
-				sem <- struct{}{}
-				defer func() { <-sem }()
-
-				data := EnrichData(items[idx].Title, "manga")
-				if data.Year != "" {
-					items[idx].ReleaseDate = data.Year
-				}
-				if items[idx].Status == "" && data.Status != "" {
-					items[idx].Status = data.Status
-				}
-				// We can also add rating if we add the field to Manga struct, but let's stick to ReleaseDate for now
-			}(i)
+			titles = append(titles, items[i].Title)
 		}
 	}
-	wg.Wait()
+	enriched := StoredEnrichments(titles, "manga")
+
+	for i := range items {
+		data, ok := enriched[items[i].Title]
+		if !ok || items[i].ReleaseDate != "" {
+			continue
+		}
+		if data.Year != "" {
+			items[i].ReleaseDate = data.Year
+		}
+		if items[i].Status == "" && data.Status != "" {
+			items[i].Status = data.Status
+		}
+		// We can also add rating if we add the field to Manga struct, but let's stick to ReleaseDate for now
+	}
 	return items
 }
 
@@ -145,7 +142,7 @@ func (s *SankavollereiService) GetMangaHome() (*models.MangaListResponse, error)
 	result.Trending = s.enrichMangaListURLs(result.Trending)
 
 	// AI Enrichment (Gemini)
-	result.Trending = s.enrichMangaWithGemini(result.Trending)
+	result.Trending = s.enrichMangaList(result.Trending)
 
 	return &models.MangaListResponse{
 		Data: struct {
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Notifier delivers notifications about new releases. The poller stores the in-app
+// notifications first (storeNotifications) and only passes the newly stored ones on.
+type Notifier interface {
+	Name() string
+	Notify(notifications []models.Notification) error
+}
+
+// storeNotifications inserts notifications for the /api/me/notifications endpoints and fills
+// in ID/CreatedAt. Notifications the user already had keep ID 0.
+func storeNotifications(tx *sql.Tx, notifications []models.Notification) error {
+	query := `INSERT INTO notifications (user_id, type, slug, title, release, target_slug, cover_image)
+              VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''))
+              ON CONFLICT (user_id, type, slug, release) DO NOTHING
+              RETURNING id, created_at`
+
+	for i := range notifications {
+		notification := &notifications[i]
+		err := tx.QueryRow(query, notification.UserID, notification.Type, notification.Slug, notification.Title,
+			notification.Release, notification.TargetSlug, notification.CoverImage).Scan(&notification.ID, &notification.CreatedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) { // ErrNoRows: already notified
+			return err
+		}
+	}
+	return nil
+}
+
+type NotificationService struct{}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{}
+}
+
+// List returns one page of the user's notifications, newest first
+func (s *NotificationService) List(userID int, unreadOnly bool, page int, limit int) ([]models.Notification, int, error) {
+	query := `SELECT id, user_id, type, slug, title, release, COALESCE(target_slug, ''), COALESCE(cover_image, ''),
+                  read_at, created_at, COUNT(*) OVER()
+              FROM notifications WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+              ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $4`
+	rows, err := database.DB.Query(query, userID, unreadOnly, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	total := 0
+	for rows.Next() {
+		var n models.Notification
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Slug, &n.Title, &n.Release, &n.TargetSlug, &n.CoverImage,
+			&readAt, &n.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, total, rows.Err()
+}
+
+func (s *NotificationService) UnreadCount(userID int) (int, error) {
+	var count int
+	err := database.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+func (s *NotificationService) MarkRead(userID int, notificationID int) error {
+	result, err := database.DB.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2`,
+		notificationID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks every unread notification read and returns how many changed
+func (s *NotificationService) MarkAllRead(userID int) (int64, error) {
+	result, err := database.DB.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// NotificationPath is the frontend page for a notification: the new episode/chapter when
+// known, otherwise the series page
+func NotificationPath(notification models.Notification) string {
+	switch {
+	case notification.TargetSlug != "" && notification.Type == "anime":
+		return "/anime/watch/" + url.PathEscape(notification.TargetSlug)
+	case notification.TargetSlug != "" && notification.Type == "manga":
+		return "/manga/read/" + url.PathEscape(notification.TargetSlug)
+	}
+	return "/" + notification.Type + "/" + url.PathEscape(notification.Slug)
+}
+
+// pruneNotifications deletes read notifications older than the retention period
+func pruneNotifications(retentionDays int) error {
+	result, err := database.DB.Exec(`DELETE FROM notifications WHERE read_at IS NOT NULL
+              AND created_at < NOW() - make_interval(days => $1)`, retentionDays)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		fmt.Printf("[Notifications] Pruned %d old notifications\n", deleted)
+	}
+	return nil
+}
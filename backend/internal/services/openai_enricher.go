@@ -0,0 +1,114 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAIEnricher calls an OpenAI-compatible /chat/completions endpoint. Besides OpenAI this
+// covers local servers such as Ollama (http://localhost:11434/v1) or llama.cpp, which
+// don't need an API key.
+type OpenAIEnricher struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Client  *http.Client
+}
+
+// NewOpenAIEnricher needs a model; baseURL defaults to the OpenAI API
+func NewOpenAIEnricher(baseURL string, apiKey string, model string) (Enricher, error) {
+	if model == "" {
+		return nil, fmt.Errorf("OPENAI_MODEL is not set")
+	}
+	return &OpenAIEnricher{
+		BaseURL: strings.TrimRight(firstNonEmpty(baseURL, defaultOpenAIBaseURL), "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (o *OpenAIEnricher) Name() string {
+	return "openai"
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []openAIMessage `json:"messages"`
+	Temperature    float64         `json:"temperature"`
+	ResponseFormat struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (o *OpenAIEnricher) Enrich(title string, mediaType string) (EnrichedData, error) {
+	reqBody := openAIChatRequest{
+		Model: o.Model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: "You are a metadata lookup service for anime and manga. Answer with JSON only."},
+			{Role: "user", Content: enrichmentPrompt(title, mediaType)},
+		},
+	}
+	reqBody.ResponseFormat.Type = "json_object"
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return EnrichedData{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.BaseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return EnrichedData{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return EnrichedData{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return EnrichedData{}, err
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil && resp.StatusCode == http.StatusOK {
+		return EnrichedData{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if chatResp.Error != nil && chatResp.Error.Message != "" {
+			return EnrichedData{}, fmt.Errorf("%s returned status %d: %s", o.BaseURL, resp.StatusCode, chatResp.Error.Message)
+		}
+		return EnrichedData{}, fmt.Errorf("%s returned status %d", o.BaseURL, resp.StatusCode)
+	}
+	if len(chatResp.Choices) == 0 || chatResp.Choices[0].Message.Content == "" {
+		return EnrichedData{}, errEmptyCompletion
+	}
+	return parseEnrichmentJSON(chatResp.Choices[0].Message.Content)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Minimal protobuf wire-format reader (no generated code / protobuf dependency needed
+// for the handful of backup messages we read)
+
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+var errProtoTruncated = errors.New("protobuf: truncated message")
+
+type protoReader struct {
+	buf []byte
+	pos int
+}
+
+func newProtoReader(buf []byte) *protoReader {
+	return &protoReader{buf: buf}
+}
+
+func (r *protoReader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+// next reads a field key and returns its number and wire type
+func (r *protoReader) next() (int, int, error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	field := int(key >> 3)
+	if field <= 0 {
+		return 0, 0, fmt.Errorf("protobuf: invalid field number %d", field)
+	}
+	return field, int(key & 7), nil
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	value, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errProtoTruncated
+	}
+	r.pos += n
+	return value, nil
+}
+
+func (r *protoReader) fixed32() (uint32, error) {
+	if r.pos+4 > len(r.buf) {
+		return 0, errProtoTruncated
+	}
+	value := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return value, nil
+}
+
+func (r *protoReader) fixed64() (uint64, error) {
+	if r.pos+8 > len(r.buf) {
+		return 0, errProtoTruncated
+	}
+	value := binary.LittleEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return value, nil
+}
+
+// bytes reads a length-delimited field (string, bytes, sub-message or packed repeated)
+func (r *protoReader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.buf)-r.pos) {
+		return nil, errProtoTruncated
+	}
+	value := r.buf[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+	return value, nil
+}
+
+func (r *protoReader) string() (string, error) {
+	value, err := r.bytes()
+	return string(value), err
+}
+
+// int64 reads an int32/int64 varint field
+func (r *protoReader) int64() (int64, error) {
+	value, err := r.varint()
+	return int64(value), err
+}
+
+func (r *protoReader) bool() (bool, error) {
+	value, err := r.varint()
+	return value != 0, err
+}
+
+// float32 reads a float field (wire type 5)
+func (r *protoReader) float32() (float32, error) {
+	value, err := r.fixed32()
+	return math.Float32frombits(value), err
+}
+
+// int64s reads one element of a repeated integer field, packed or not
+func (r *protoReader) int64s(wire int) ([]int64, error) {
+	if wire == protoWireVarint {
+		value, err := r.int64()
+		return []int64{value}, err
+	}
+	if wire != protoWireBytes {
+		return nil, fmt.Errorf("protobuf: unexpected wire type %d for repeated integer", wire)
+	}
+
+	packed, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	inner := newProtoReader(packed)
+	var values []int64
+	for !inner.done() {
+		value, err := inner.int64()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// skip discards a field we don't read
+func (r *protoReader) skip(wire int) error {
+	var err error
+	switch wire {
+	case protoWireVarint:
+		_, err = r.varint()
+	case protoWireFixed64:
+		_, err = r.fixed64()
+	case protoWireBytes:
+		_, err = r.bytes()
+	case protoWireFixed32:
+		_, err = r.fixed32()
+	default:
+		err = fmt.Errorf("protobuf: unsupported wire type %d", wire)
+	}
+	return err
+}
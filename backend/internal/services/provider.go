@@ -1,6 +1,12 @@
 package services
 
-import "anime-tanyaayomi/internal/models"
+import (
+	"anime-tanyaayomi/internal/models"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
 
 type AnimeProvider interface {
 	SearchAnime(query string) ([]models.Anime, error)
@@ -13,3 +19,152 @@ type MangaProvider interface {
 	GetMangaDetail(slug string) (*models.MangaDetail, error)
 	GetChapterImages(slug string) ([]string, error)
 }
+
+// Provider names registered by NewDefaultProviderRegistry
+const (
+	ProviderOtakudesu = "otakudesu"
+	ProviderAnimeIndo = "animeindo"
+	ProviderZoro      = "zoro"
+	ProviderKomikindo = "komikindo"
+)
+
+// ProviderRegistry holds named anime/manga providers so routes can pick a source by name
+type ProviderRegistry struct {
+	mu           sync.RWMutex
+	anime        map[string]AnimeProvider
+	manga        map[string]MangaProvider
+	defaultAnime string
+	defaultManga string
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{
+		anime: make(map[string]AnimeProvider),
+		manga: make(map[string]MangaProvider),
+	}
+}
+
+// NewDefaultProviderRegistry registers every built-in source.
+// Defaults come from ANIME_PROVIDER / MANGA_PROVIDER (falling back to otakudesu / komikindo).
+func NewDefaultProviderRegistry() *ProviderRegistry {
+	registry := NewProviderRegistry()
+
+	sankavollerei := NewSankavollereiService("")
+	registry.RegisterAnime(ProviderOtakudesu, &OtakudesuProvider{Service: sankavollerei})
+	registry.RegisterAnime(ProviderAnimeIndo, &AnimeIndoProvider{Service: NewAnimeIndoService()})
+	registry.RegisterAnime(ProviderZoro, &ZoroProvider{Service: NewZoroService()})
+	registry.RegisterManga(ProviderKomikindo, &KomikindoProvider{Service: sankavollerei})
+
+	if name := os.Getenv("ANIME_PROVIDER"); name != "" {
+		if err := registry.SetDefaultAnime(name); err != nil {
+			fmt.Printf("[Providers] ⚠️  %v, keeping '%s'\n", err, registry.defaultAnime)
+		}
+	}
+	if name := os.Getenv("MANGA_PROVIDER"); name != "" {
+		if err := registry.SetDefaultManga(name); err != nil {
+			fmt.Printf("[Providers] ⚠️  %v, keeping '%s'\n", err, registry.defaultManga)
+		}
+	}
+
+	return registry
+}
+
+// RegisterAnime adds an anime provider. The first one registered becomes the default.
+func (r *ProviderRegistry) RegisterAnime(name string, provider AnimeProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.anime[name] = provider
+	if r.defaultAnime == "" {
+		r.defaultAnime = name
+	}
+}
+
+// RegisterManga adds a manga provider. The first one registered becomes the default.
+func (r *ProviderRegistry) RegisterManga(name string, provider MangaProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.manga[name] = provider
+	if r.defaultManga == "" {
+		r.defaultManga = name
+	}
+}
+
+func (r *ProviderRegistry) SetDefaultAnime(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.anime[name]; !ok {
+		return fmt.Errorf("unknown anime provider '%s'", name)
+	}
+	r.defaultAnime = name
+	return nil
+}
+
+func (r *ProviderRegistry) SetDefaultManga(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.manga[name]; !ok {
+		return fmt.Errorf("unknown manga provider '%s'", name)
+	}
+	r.defaultManga = name
+	return nil
+}
+
+// Anime returns the named provider, or the default one when name is empty or "default"
+func (r *ProviderRegistry) Anime(name string) (AnimeProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" || name == "default" {
+		name = r.defaultAnime
+	}
+	provider, ok := r.anime[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown anime provider '%s'", name)
+	}
+	return provider, nil
+}
+
+// Manga returns the named provider, or the default one when name is empty or "default"
+func (r *ProviderRegistry) Manga(name string) (MangaProvider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if name == "" || name == "default" {
+		name = r.defaultManga
+	}
+	provider, ok := r.manga[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown manga provider '%s'", name)
+	}
+	return provider, nil
+}
+
+// AnimeNames lists registered anime providers (sorted) and the default
+func (r *ProviderRegistry) AnimeNames() ([]string, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.anime))
+	for name := range r.anime {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, r.defaultAnime
+}
+
+// MangaNames lists registered manga providers (sorted) and the default
+func (r *ProviderRegistry) MangaNames() ([]string, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.manga))
+	for name := range r.manga {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, r.defaultManga
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Adapters that expose each upstream client through AnimeProvider / MangaProvider
+
+// ========== Otakudesu (Sankavollerei) ==========
+
+type OtakudesuProvider struct {
+	Service *SankavollereiService
+}
+
+func (p *OtakudesuProvider) SearchAnime(query string) ([]models.Anime, error) {
+	result, err := p.Service.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data.AnimeList, nil
+}
+
+func (p *OtakudesuProvider) GetAnimeDetail(slug string) (*models.AnimeDetail, error) {
+	result, err := p.Service.GetAnimeDetail(slug)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+func (p *OtakudesuProvider) GetEpisodeStream(slug string) (*models.StreamData, error) {
+	result, err := p.Service.GetEpisodeStream(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StreamData{
+		DefaultStreamingUrl: result.Data.DefaultStreamingUrl,
+		StreamLink:          result.Data.StreamLink,
+		Url:                 result.Data.URL,
+		Title:               result.Data.Title,
+		AnimeID:             result.Data.AnimeID,
+	}, nil
+}
+
+// ========== Anime Indo ==========
+
+type AnimeIndoProvider struct {
+	Service *AnimeIndoService
+}
+
+func (p *AnimeIndoProvider) SearchAnime(query string) ([]models.Anime, error) {
+	cards, err := p.Service.SearchAnime(query)
+	if err != nil {
+		return nil, err
+	}
+
+	animeList := make([]models.Anime, 0, len(cards))
+	for _, card := range cards {
+		animeList = append(animeList, models.Anime{
+			Title:   card.Title,
+			Slug:    card.Slug,
+			AnimeID: card.Slug,
+			Cover:   card.Poster,
+			Poster:  card.Poster,
+			Genre:   strings.Join(card.Genres, ", "),
+		})
+	}
+	return animeList, nil
+}
+
+func (p *AnimeIndoProvider) GetAnimeDetail(slug string) (*models.AnimeDetail, error) {
+	detail, err := p.Service.GetAnimeDetail(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]models.Episode, 0, len(detail.Episodes))
+	for _, ep := range detail.Episodes {
+		episodes = append(episodes, models.Episode{
+			Title:     ep.EpisodeTitle,
+			EpisodeID: ep.EpisodeSlug,
+			Slug:      ep.EpisodeSlug,
+		})
+	}
+
+	return &models.AnimeDetail{
+		Anime: models.Anime{
+			Title:    detail.Title,
+			Slug:     slug,
+			AnimeID:  slug,
+			Cover:    detail.Poster,
+			Poster:   detail.Poster,
+			Synopsis: detail.Synopsis,
+			Genre:    strings.Join(detail.Genres, ", "),
+		},
+		EpisodeList: episodes,
+	}, nil
+}
+
+func (p *AnimeIndoProvider) GetEpisodeStream(slug string) (*models.StreamData, error) {
+	streams, err := p.Service.GetEpisodeStream(slug, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StreamData{
+		DefaultStreamingUrl: streams["default"],
+		StreamLink:          streams["default"],
+		Url:                 streams["default"],
+		Title:               slug,
+		AnimeID:             animeSlugFromEpisode(slug),
+	}, nil
+}
+
+// ========== Zoro (Consumet) ==========
+
+type ZoroProvider struct {
+	Service *ZoroService
+}
+
+func (p *ZoroProvider) SearchAnime(query string) ([]models.Anime, error) {
+	result, err := p.Service.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	animeList := make([]models.Anime, 0, len(result.Results))
+	for _, info := range result.Results {
+		animeList = append(animeList, models.Anime{
+			Title:   info.Title,
+			Slug:    info.ID,
+			AnimeID: info.ID,
+			Cover:   info.Image,
+			Image:   info.Image,
+		})
+	}
+	return animeList, nil
+}
+
+func (p *ZoroProvider) GetAnimeDetail(slug string) (*models.AnimeDetail, error) {
+	info, err := p.Service.GetAnimeInfo(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]models.Episode, 0, len(info.Episodes))
+	for _, ep := range info.Episodes {
+		episodes = append(episodes, models.Episode{
+			Title:     fmt.Sprintf("Episode %d", ep.Number),
+			EpisodeID: ep.ID,
+			Slug:      ep.ID,
+			Episode:   strconv.Itoa(ep.Number),
+			Eps:       ep.Number,
+		})
+	}
+
+	return &models.AnimeDetail{
+		Anime: models.Anime{
+			Title:    info.Title,
+			Slug:     info.ID,
+			AnimeID:  info.ID,
+			Cover:    info.Image,
+			Image:    info.Image,
+			Synopsis: info.Description,
+			Type:     info.Type,
+		},
+		EpisodeList: episodes,
+	}, nil
+}
+
+// GetEpisodeStream accepts either a Zoro episode ID (contains "$episode$")
+// or an Otakudesu-style slug, which is resolved through title search.
+func (p *ZoroProvider) GetEpisodeStream(slug string) (*models.StreamData, error) {
+	if strings.Contains(slug, "$episode$") {
+		streamData, err := p.Service.Watch(slug)
+		if err != nil {
+			return nil, err
+		}
+		if len(streamData.Sources) == 0 {
+			return nil, fmt.Errorf("no stream sources found")
+		}
+
+		defaultURL := streamData.Sources[0].URL
+		return &models.StreamData{
+			DefaultStreamingUrl: defaultURL,
+			StreamLink:          defaultURL,
+			Url:                 defaultURL,
+			Title:               slug,
+			AnimeID:             strings.SplitN(slug, "$", 2)[0],
+		}, nil
+	}
+
+	title, episodeNumber, err := ParseOtakudesuSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	qualities, err := p.Service.GetEpisodeStream(title, episodeNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultURL := qualities["default"]
+	if defaultURL == "" {
+		for _, q := range []string{"1080p", "720p", "480p", "360p"} {
+			if qualities[q] != "" {
+				defaultURL = qualities[q]
+				break
+			}
+		}
+	}
+
+	return &models.StreamData{
+		DefaultStreamingUrl: defaultURL,
+		StreamLink:          defaultURL,
+		Url:                 defaultURL,
+		Title:               slug,
+		AnimeID:             animeSlugFromEpisode(slug),
+	}, nil
+}
+
+// ========== Komikindo (Sankavollerei comic) ==========
+
+type KomikindoProvider struct {
+	Service *SankavollereiService
+}
+
+func (p *KomikindoProvider) SearchManga(query string) ([]models.Manga, error) {
+	result, err := p.Service.SearchManga(query)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data.MangaList, nil
+}
+
+func (p *KomikindoProvider) GetMangaDetail(slug string) (*models.MangaDetail, error) {
+	result, err := p.Service.GetMangaDetail(slug)
+	if err != nil {
+		return nil, err
+	}
+	detail := models.MangaDetail(*result)
+	return &detail, nil
+}
+
+func (p *KomikindoProvider) GetChapterImages(slug string) ([]string, error) {
+	result, err := p.Service.GetChapterImages(slug)
+	if err != nil {
+		return nil, err
+	}
+	return result.Images, nil
+}
+
+// animeSlugFromEpisode strips the "-episode-N..." suffix from an episode slug
+func animeSlugFromEpisode(slug string) string {
+	if idx := strings.Index(slug, "-episode-"); idx != -1 {
+		return slug[:idx]
+	}
+	return slug
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxPushSubscriptionsPerUser = 20
+	pushWorkers                 = 8
+)
+
+var (
+	ErrInvalidPushSubscription  = errors.New("invalid push subscription")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+)
+
+type PushSubscriptionService struct {
+	Sender *WebPushSender
+}
+
+func NewPushSubscriptionService(sender *WebPushSender) *PushSubscriptionService {
+	return &PushSubscriptionService{Sender: sender}
+}
+
+// Subscribe stores (or moves to this user) the subscription of one browser/device
+func (s *PushSubscriptionService) Subscribe(userID int, req models.PushSubscriptionRequest) (*models.PushSubscription, error) {
+	if err := s.Sender.CheckEndpoint(req.Endpoint); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPushSubscription, err)
+	}
+	// Reject keys we couldn't encrypt for now rather than failing on every notification
+	if _, err := EncryptWebPushPayload(nil, req.Keys.P256dh, req.Keys.Auth); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPushSubscription, err)
+	}
+	if len(req.DeviceName) > 255 {
+		req.DeviceName = req.DeviceName[:255]
+	}
+
+	var count int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM push_subscriptions WHERE user_id = $1 AND endpoint <> $2`,
+		userID, req.Endpoint).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxPushSubscriptionsPerUser {
+		return nil, fmt.Errorf("%w: at most %d devices per user", ErrInvalidPushSubscription, maxPushSubscriptionsPerUser)
+	}
+
+	query := `INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, device_name)
+              VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+              ON CONFLICT (endpoint) DO UPDATE SET
+                  user_id = EXCLUDED.user_id,
+                  p256dh = EXCLUDED.p256dh,
+                  auth = EXCLUDED.auth,
+                  device_name = COALESCE(EXCLUDED.device_name, push_subscriptions.device_name)
+              RETURNING id, user_id, endpoint, p256dh, auth, COALESCE(device_name, ''), created_at, last_used_at`
+	return scanPushSubscription(database.DB.QueryRow(query, userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth, req.DeviceName))
+}
+
+// Unsubscribe removes a subscription by id (endpoint empty) or by endpoint
+func (s *PushSubscriptionService) Unsubscribe(userID int, subscriptionID int, endpoint string) error {
+	result, err := database.DB.Exec(`DELETE FROM push_subscriptions WHERE user_id = $1 AND (id = $2 OR endpoint = $3)`,
+		userID, subscriptionID, endpoint)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *PushSubscriptionService) List(userID int) ([]models.PushSubscription, error) {
+	return queryPushSubscriptions(`SELECT id, user_id, endpoint, p256dh, auth, COALESCE(device_name, ''), created_at, last_used_at
+              FROM push_subscriptions WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+}
+
+// SendTest pushes a test notification to every device of the user and returns how many got it
+func (s *PushSubscriptionService) SendTest(userID int) (int, error) {
+	subscriptions, err := s.List(userID)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(models.PushPayload{Title: "Notifications enabled", Body: "New episodes and chapters will show up here.", URL: "/", Tag: "test"})
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, subscription := range subscriptions {
+		if err := s.deliver(subscription, payload); err == nil {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver sends one message and keeps the subscription table tidy:
+// dead subscriptions are deleted, live ones get last_used_at
+func (s *PushSubscriptionService) deliver(subscription models.PushSubscription, payload []byte) error {
+	err := s.Sender.Send(subscription, payload)
+	switch {
+	case errors.Is(err, ErrPushSubscriptionGone):
+		if _, dbErr := database.DB.Exec(`DELETE FROM push_subscriptions WHERE id = $1`, subscription.ID); dbErr != nil {
+			fmt.Printf("[WebPush] ⚠️  Failed to remove subscription %d: %v\n", subscription.ID, dbErr)
+		}
+	case err != nil:
+		fmt.Printf("[WebPush] ⚠️  Push to subscription %d failed: %v\n", subscription.ID, err)
+	default:
+		if _, dbErr := database.DB.Exec(`UPDATE push_subscriptions SET last_used_at = NOW() WHERE id = $1`, subscription.ID); dbErr != nil {
+			fmt.Printf("[WebPush] ⚠️  Failed to update subscription %d: %v\n", subscription.ID, dbErr)
+		}
+	}
+	return err
+}
+
+// WebPushNotifier sends each notification to every device of its user
+type WebPushNotifier struct {
+	Subscriptions *PushSubscriptionService
+}
+
+func (n *WebPushNotifier) Name() string {
+	return "web-push"
+}
+
+func (n *WebPushNotifier) Notify(notifications []models.Notification) error {
+	userIDs := make([]int64, 0, len(notifications))
+	seen := make(map[int]bool)
+	for _, notification := range notifications {
+		if !seen[notification.UserID] {
+			seen[notification.UserID] = true
+			userIDs = append(userIDs, int64(notification.UserID))
+		}
+	}
+
+	subscriptions, err := queryPushSubscriptions(`SELECT id, user_id, endpoint, p256dh, auth, COALESCE(device_name, ''), created_at, last_used_at
+              FROM push_subscriptions WHERE user_id = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return err
+	}
+	byUser := make(map[int][]models.PushSubscription)
+	for _, subscription := range subscriptions {
+		byUser[subscription.UserID] = append(byUser[subscription.UserID], subscription)
+	}
+
+	type job struct {
+		subscription models.PushSubscription
+		payload      []byte
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < pushWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				n.Subscriptions.deliver(j.subscription, j.payload)
+			}
+		}()
+	}
+
+	sent := 0
+	for _, notification := range notifications {
+		if len(byUser[notification.UserID]) == 0 {
+			continue
+		}
+		payload, err := json.Marshal(pushPayloadFor(notification))
+		if err != nil {
+			continue
+		}
+		for _, subscription := range byUser[notification.UserID] {
+			jobs <- job{subscription: subscription, payload: payload}
+			sent++
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if sent > 0 {
+		fmt.Printf("[WebPush] Sent %d push messages\n", sent)
+	}
+	return nil
+}
+
+func pushPayloadFor(notification models.Notification) models.PushPayload {
+	payload := models.PushPayload{
+		Title: notification.Title,
+		Body:  notification.Release,
+		URL:   NotificationPath(notification),
+		Icon:  notification.CoverImage,
+		Tag:   notification.Type + ":" + notification.Slug,
+	}
+	// Titles and cover URLs are short in practice; never let one push exceed the record size
+	if len(payload.Title)+len(payload.Body)+len(payload.URL)+len(payload.Icon)+len(payload.Tag) > MaxWebPushPayload-256 {
+		payload.Icon = ""
+	}
+	return payload
+}
+
+func queryPushSubscriptions(query string, args ...interface{}) ([]models.PushSubscription, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.PushSubscription{}
+	for rows.Next() {
+		subscription, err := scanPushSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, *subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func scanPushSubscription(row rowScanner) (*models.PushSubscription, error) {
+	var subscription models.PushSubscription
+	var lastUsed sql.NullTime
+	if err := row.Scan(&subscription.ID, &subscription.UserID, &subscription.Endpoint, &subscription.P256dh, &subscription.Auth,
+		&subscription.DeviceName, &subscription.CreatedAt, &lastUsed); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		subscription.LastUsedAt = &lastUsed.Time
+	}
+	return &subscription, nil
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrInvalidReadingProgress = errors.New("invalid reading progress")
+	ErrChapterNotFound        = errors.New("chapter not found in manga")
+)
+
+// Chapter number from slugs/titles like "one-piece-chapter-1100" or "Chapter 12.5"
+var chapterNumberPattern = regexp.MustCompile(`(?i)chapter[\s-]*(\d+(?:[.-]\d+)?)`)
+
+type ReadingProgressService struct {
+	Manga *SankavollereiService
+}
+
+func NewReadingProgressService(manga *SankavollereiService) *ReadingProgressService {
+	return &ReadingProgressService{Manga: manga}
+}
+
+// SaveProgress stores the last chapter/page for a manga. Reaching the last page marks the chapter read.
+func (s *ReadingProgressService) SaveProgress(userID int, req models.ReadingProgressRequest) (*models.ReadingProgress, error) {
+	if req.MangaSlug == "" || req.ChapterSlug == "" {
+		return nil, fmt.Errorf("%w: manga_slug and chapter_slug are required", ErrInvalidReadingProgress)
+	}
+	if req.PageIndex < 0 || req.TotalPages < 0 {
+		return nil, fmt.Errorf("%w: page_index and total_pages must not be negative", ErrInvalidReadingProgress)
+	}
+
+	query := `INSERT INTO manga_reading_progress (user_id, manga_slug, chapter_slug, page_index, total_pages,
+                  manga_title, cover_image, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+              ON CONFLICT (user_id, manga_slug) DO UPDATE SET
+                  chapter_slug = EXCLUDED.chapter_slug,
+                  page_index = EXCLUDED.page_index,
+                  total_pages = EXCLUDED.total_pages,
+                  manga_title = COALESCE(NULLIF(EXCLUDED.manga_title, ''), manga_reading_progress.manga_title),
+                  cover_image = COALESCE(NULLIF(EXCLUDED.cover_image, ''), manga_reading_progress.cover_image),
+                  updated_at = NOW()
+              RETURNING manga_slug, chapter_slug, page_index, total_pages, COALESCE(manga_title, ''),
+                  COALESCE(cover_image, ''), updated_at`
+
+	var p models.ReadingProgress
+	err := database.DB.QueryRow(query, userID, req.MangaSlug, req.ChapterSlug, req.PageIndex, req.TotalPages,
+		req.MangaTitle, req.CoverImage).
+		Scan(&p.MangaSlug, &p.ChapterSlug, &p.PageIndex, &p.TotalPages, &p.MangaTitle, &p.CoverImage, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.TotalPages > 0 && req.PageIndex >= req.TotalPages-1 {
+		if err := s.SetChaptersRead(userID, req.MangaSlug, []string{req.ChapterSlug}, true); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// GetRecent returns the mangas the user read most recently
+func (s *ReadingProgressService) GetRecent(userID int, limit int) ([]models.ReadingProgress, error) {
+	query := `SELECT manga_slug, chapter_slug, page_index, total_pages, COALESCE(manga_title, ''),
+                  COALESCE(cover_image, ''), updated_at
+              FROM manga_reading_progress WHERE user_id = $1 ORDER BY updated_at DESC LIMIT $2`
+	rows, err := database.DB.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.ReadingProgress{}
+	for rows.Next() {
+		var p models.ReadingProgress
+		if err := rows.Scan(&p.MangaSlug, &p.ChapterSlug, &p.PageIndex, &p.TotalPages, &p.MangaTitle,
+			&p.CoverImage, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// GetReadState returns progress (nil if never opened) and read chapters for one manga
+func (s *ReadingProgressService) GetReadState(userID int, mangaSlug string) (*models.MangaReadState, error) {
+	state := &models.MangaReadState{ReadChapters: []string{}}
+
+	var p models.ReadingProgress
+	err := database.DB.QueryRow(`SELECT manga_slug, chapter_slug, page_index, total_pages, COALESCE(manga_title, ''),
+                  COALESCE(cover_image, ''), updated_at
+              FROM manga_reading_progress WHERE user_id = $1 AND manga_slug = $2`, userID, mangaSlug).
+		Scan(&p.MangaSlug, &p.ChapterSlug, &p.PageIndex, &p.TotalPages, &p.MangaTitle, &p.CoverImage, &p.UpdatedAt)
+	switch {
+	case err == nil:
+		state.Progress = &p
+	case err != sql.ErrNoRows:
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(`SELECT chapter_slug FROM manga_chapter_reads WHERE user_id = $1 AND manga_slug = $2`,
+		userID, mangaSlug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chapterSlug string
+		if err := rows.Scan(&chapterSlug); err != nil {
+			return nil, err
+		}
+		state.ReadChapters = append(state.ReadChapters, chapterSlug)
+	}
+	return state, rows.Err()
+}
+
+// SetChaptersRead marks the given chapters read or unread
+func (s *ReadingProgressService) SetChaptersRead(userID int, mangaSlug string, chapters []string, read bool) error {
+	if mangaSlug == "" || len(chapters) == 0 {
+		return fmt.Errorf("%w: manga slug and at least one chapter are required", ErrInvalidReadingProgress)
+	}
+
+	if read {
+		query := `INSERT INTO manga_chapter_reads (user_id, manga_slug, chapter_slug)
+                  SELECT $1, $2, unnest($3::text[])
+                  ON CONFLICT DO NOTHING`
+		_, err := database.DB.Exec(query, userID, mangaSlug, pq.Array(chapters))
+		return err
+	}
+
+	query := `DELETE FROM manga_chapter_reads WHERE user_id = $1 AND manga_slug = $2 AND chapter_slug = ANY($3)`
+	_, err := database.DB.Exec(query, userID, mangaSlug, pq.Array(chapters))
+	return err
+}
+
+// MarkPreviousRead marks chapterSlug and every earlier chapter of the manga as read.
+// Returns the number of chapters marked.
+func (s *ReadingProgressService) MarkPreviousRead(userID int, mangaSlug string, chapterSlug string) (int, error) {
+	if chapterSlug == "" {
+		return 0, fmt.Errorf("%w: chapter_slug is required", ErrInvalidReadingProgress)
+	}
+
+	detail, err := s.Manga.GetMangaDetail(mangaSlug)
+	if err != nil {
+		return 0, err
+	}
+
+	var slugs []string
+	found := false
+	for _, ch := range OrderChapters(detail.Chapters) {
+		slugs = append(slugs, ChapterSlug(ch))
+		if ChapterSlug(ch) == chapterSlug {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, ErrChapterNotFound
+	}
+
+	if err := s.SetChaptersRead(userID, mangaSlug, slugs, true); err != nil {
+		return 0, err
+	}
+	return len(slugs), nil
+}
+
+// AnnotateDetail adds the user's read state and unread count to a manga detail
+func (s *ReadingProgressService) AnnotateDetail(userID int, mangaSlug string, detail *models.MangaDetailResponse) (*models.MangaDetailWithProgress, error) {
+	state, err := s.GetReadState(userID, mangaSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	read := make(map[string]bool, len(state.ReadChapters))
+	for _, slug := range state.ReadChapters {
+		read[slug] = true
+	}
+
+	result := &models.MangaDetailWithProgress{
+		MangaDetailResponse: detail,
+		Chapters:            make([]models.ReadChapter, 0, len(detail.Chapters)),
+		Progress:            state.Progress,
+	}
+	for _, ch := range detail.Chapters {
+		isRead := read[ChapterSlug(ch)]
+		if !isRead {
+			result.UnreadCount++
+		}
+		result.Chapters = append(result.Chapters, models.ReadChapter{Chapter: ch, Read: isRead})
+	}
+	return result, nil
+}
+
+// ChapterSlug returns the id used by /manga/chapter/:chapterId
+func ChapterSlug(ch models.Chapter) string {
+	if ch.Slug != "" {
+		return ch.Slug
+	}
+	return ch.ChapterID
+}
+
+// OrderChapters returns chapters from first to latest. Numbers come from the slug or title;
+// if any chapter has none, the upstream (newest first) order is reversed instead.
+func OrderChapters(list []models.Chapter) []models.Chapter {
+	ordered := make([]models.Chapter, len(list))
+	numbers := make([]float64, len(list))
+
+	for i, ch := range list {
+		number, ok := chapterNumber(ch)
+		if !ok {
+			for j := range list {
+				ordered[len(list)-1-j] = list[j]
+			}
+			return ordered
+		}
+		numbers[i] = number
+	}
+
+	index := make([]int, len(list))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(a, b int) bool { return numbers[index[a]] < numbers[index[b]] })
+	for i, from := range index {
+		ordered[i] = list[from]
+	}
+	return ordered
+}
+
+func chapterNumber(ch models.Chapter) (float64, bool) {
+	for _, text := range []string{ChapterSlug(ch), ch.Chapter, ch.Title} {
+		if matches := chapterNumberPattern.FindStringSubmatch(text); len(matches) > 1 {
+			// "12-5" in slugs means 12.5
+			value := strings.ReplaceAll(matches[1], "-", ".")
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				return number, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestChapterNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		chapter models.Chapter
+		number  float64
+		ok      bool
+	}{
+		{"slug", models.Chapter{Slug: "one-piece-chapter-1100"}, 1100, true},
+		{"slug with suffix", models.Chapter{Slug: "one-piece-chapter-12-bahasa-indonesia"}, 12, true},
+		{"dashed decimal", models.Chapter{Slug: "one-piece-chapter-12-5"}, 12.5, true},
+		{"dotted decimal", models.Chapter{Chapter: "Chapter 12.5"}, 12.5, true},
+		{"chapter id", models.Chapter{ChapterID: "naruto-chapter-7"}, 7, true},
+		{"display name", models.Chapter{Slug: "abc123", Chapter: "Chapter 45"}, 45, true},
+		{"title", models.Chapter{Slug: "abc123", Title: "chapter-46"}, 46, true},
+		{"slug wins", models.Chapter{Slug: "x-chapter-3", Chapter: "Chapter 4"}, 3, true},
+		{"case insensitive", models.Chapter{Title: "CHAPTER 9"}, 9, true},
+		{"missing", models.Chapter{Slug: "oneshot", Title: "Oneshot"}, 0, false},
+		{"empty", models.Chapter{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			number, ok := chapterNumber(tt.chapter)
+			if number != tt.number || ok != tt.ok {
+				t.Errorf("chapterNumber = %v, %v, want %v, %v", number, ok, tt.number, tt.ok)
+			}
+		})
+	}
+}
+
+func chapterSlugs(list []models.Chapter) []string {
+	slugs := make([]string, len(list))
+	for i, ch := range list {
+		slugs[i] = ChapterSlug(ch)
+	}
+	return slugs
+}
+
+func TestOrderChapters(t *testing.T) {
+	tests := []struct {
+		name string
+		list []models.Chapter
+		want []string
+	}{
+		{
+			name: "newest first",
+			list: []models.Chapter{
+				{Slug: "op-chapter-10"},
+				{Slug: "op-chapter-9"},
+				{Slug: "op-chapter-2"},
+				{Slug: "op-chapter-1"},
+			},
+			want: []string{"op-chapter-1", "op-chapter-2", "op-chapter-9", "op-chapter-10"},
+		},
+		{
+			name: "half chapter",
+			list: []models.Chapter{
+				{Slug: "op-chapter-13"},
+				{Slug: "op-chapter-12-5"},
+				{Slug: "op-chapter-12"},
+			},
+			want: []string{"op-chapter-12", "op-chapter-12-5", "op-chapter-13"},
+		},
+		{
+			name: "numbers from the display name",
+			list: []models.Chapter{
+				{ChapterID: "c3", Chapter: "Chapter 3"},
+				{ChapterID: "c1", Chapter: "Chapter 1"},
+				{ChapterID: "c2", Chapter: "Chapter 2"},
+			},
+			want: []string{"c1", "c2", "c3"},
+		},
+		{
+			name: "missing number reverses upstream order",
+			list: []models.Chapter{
+				{Slug: "op-chapter-2"},
+				{Slug: "op-extra", Title: "Extra"},
+				{Slug: "op-chapter-1"},
+			},
+			want: []string{"op-chapter-1", "op-extra", "op-chapter-2"},
+		},
+		{
+			name: "empty",
+			list: nil,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chapterSlugs(OrderChapters(tt.list)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OrderChapters = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
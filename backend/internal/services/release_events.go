@@ -0,0 +1,236 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEventTailInterval = 5 * time.Second
+	releaseEventRetention    = 3 * 24 * time.Hour
+	MaxEventReplay           = 500
+	eventSubscriberBuffer    = 64
+)
+
+// EventBroker fans release events out to SSE clients. Events are written to the
+// release_events table by whichever instance's poller saw them first; every instance tails
+// the table, so clients get the same stream whichever instance they are connected to.
+// Writers hold releaseEventsLockKey, so ids become visible in order and tailing by id is safe.
+type EventBroker struct {
+	Interval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan models.ReleaseEvent]struct{}
+	lastID      int64
+	startOnce   sync.Once
+}
+
+var (
+	eventBroker     *EventBroker
+	eventBrokerOnce sync.Once
+)
+
+// GetEventBroker returns the shared broker. EVENTS_POLL_INTERVAL (default 5s) is how often
+// the table is checked for new events.
+func GetEventBroker() *EventBroker {
+	eventBrokerOnce.Do(func() {
+		interval := defaultEventTailInterval
+		if value := os.Getenv("EVENTS_POLL_INTERVAL"); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				interval = parsed
+			} else {
+				fmt.Printf("[Events] ⚠️  Invalid EVENTS_POLL_INTERVAL %q, using %s\n", value, interval)
+			}
+		}
+		eventBroker = &EventBroker{
+			Interval:    interval,
+			subscribers: make(map[chan models.ReleaseEvent]struct{}),
+		}
+	})
+	return eventBroker
+}
+
+// Start tails release_events from the current end of the table
+func (b *EventBroker) Start() {
+	b.startOnce.Do(func() {
+		lastID, err := LatestReleaseEventID()
+		if err != nil {
+			fmt.Printf("[Events] ⚠️  Failed to read latest event id: %v\n", err)
+		}
+		b.mu.Lock()
+		b.lastID = lastID
+		b.mu.Unlock()
+
+		go func() {
+			ticker := time.NewTicker(b.Interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if err := b.tail(); err != nil {
+					fmt.Printf("[Events] ⚠️  Tail failed: %v\n", err)
+				}
+			}
+		}()
+	})
+}
+
+// Subscribe registers a client. The channel is closed when the client falls too far
+// behind; it should reconnect with Last-Event-ID and catch up from the table.
+func (b *EventBroker) Subscribe() (<-chan models.ReleaseEvent, func()) {
+	ch := make(chan models.ReleaseEvent, eventSubscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *EventBroker) tail() error {
+	b.mu.Lock()
+	lastID := b.lastID
+	b.mu.Unlock()
+
+	events, err := ReleaseEventsSince(lastID, MaxEventReplay)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID = events[len(events)-1].ID
+subscribers:
+	for ch := range b.subscribers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				// Slow client: drop it rather than block everyone else
+				delete(b.subscribers, ch)
+				close(ch)
+				continue subscribers
+			}
+		}
+	}
+	return nil
+}
+
+// releaseEventsLockKey is the advisory lock writers of release_events hold until they commit.
+// BIGSERIAL ids can otherwise commit out of order across instances, and tails reading
+// id > lastID would skip a lower id that becomes visible after a higher one.
+const releaseEventsLockKey = 0x72656c65617365 // "release"
+
+// recordReleaseEvents appends new releases to the event stream in the poll's transaction
+// and returns the stored events. The lock is held until tx commits.
+func recordReleaseEvents(tx *sql.Tx, releases []models.Release) ([]models.ReleaseEvent, error) {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, releaseEventsLockKey); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO release_events (type, slug, title, release, target_slug, cover_image)
+              VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+              RETURNING id, created_at`
+	events := make([]models.ReleaseEvent, 0, len(releases))
+	for _, release := range releases {
+		event := models.ReleaseEvent{Release: release}
+		if err := tx.QueryRow(query, release.Type, release.Slug, release.Title, release.Release,
+			release.TargetSlug, release.CoverImage).Scan(&event.ID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.URL = NotificationPath(models.Notification{Type: release.Type, Slug: release.Slug, TargetSlug: release.TargetSlug})
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// pruneReleaseEvents deletes events older than the reconnect window
+func pruneReleaseEvents() error {
+	_, err := database.DB.Exec(`DELETE FROM release_events WHERE created_at < NOW() - make_interval(secs => $1)`,
+		releaseEventRetention.Seconds())
+	return err
+}
+
+// LatestReleaseEventID is where a new client without Last-Event-ID starts
+func LatestReleaseEventID() (int64, error) {
+	var id sql.NullInt64
+	err := database.DB.QueryRow(`SELECT MAX(id) FROM release_events`).Scan(&id)
+	return id.Int64, err
+}
+
+// ReleaseEventsSince returns up to limit events after afterID, oldest first
+func ReleaseEventsSince(afterID int64, limit int) ([]models.ReleaseEvent, error) {
+	rows, err := database.DB.Query(`SELECT id, type, slug, title, release, COALESCE(target_slug, ''), COALESCE(cover_image, ''), created_at
+              FROM release_events WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.ReleaseEvent
+	for rows.Next() {
+		var e models.ReleaseEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Slug, &e.Title, &e.Release, &e.TargetSlug, &e.CoverImage, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.URL = NotificationPath(models.Notification{Type: e.Type, Slug: e.Slug, TargetSlug: e.TargetSlug})
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// BookmarkEventFilter matches events against a user's bookmarks the way release
+// notifications do (by slug or title, dropped titles excluded). It reloads the bookmarks
+// every minute so long-lived streams pick up new ones.
+type BookmarkEventFilter struct {
+	UserID int
+
+	loadedAt time.Time
+	slugs    map[string]bool
+	titles   map[string]bool
+}
+
+func (f *BookmarkEventFilter) Match(event models.ReleaseEvent) (bool, error) {
+	if time.Since(f.loadedAt) > time.Minute {
+		if err := f.load(); err != nil {
+			return false, err
+		}
+	}
+	return f.slugs[event.Type+"/"+event.Slug] || f.titles[event.Type+"/"+strings.ToLower(strings.TrimSpace(event.Title))], nil
+}
+
+func (f *BookmarkEventFilter) load() error {
+	rows, err := database.DB.Query(`SELECT type, slug, LOWER(TRIM(title)) FROM bookmarks
+              WHERE user_id = $1 AND status <> $2`, f.UserID, ListStatusDropped)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	slugs := make(map[string]bool)
+	titles := make(map[string]bool)
+	for rows.Next() {
+		var mediaType, slug, title string
+		if err := rows.Scan(&mediaType, &slug, &title); err != nil {
+			return err
+		}
+		slugs[mediaType+"/"+slug] = true
+		titles[mediaType+"/"+title] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	f.slugs, f.titles, f.loadedAt = slugs, titles, time.Now()
+	return nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	releaseSourceAnimeIndo = "animeindo"
+	releaseSourceKomikindo = "komikindo"
+
+	defaultReleasePollInterval = 15 * time.Minute
+	notificationRetentionDays  = 90
+)
+
+// "one-piece-episode-1100-subtitle-indonesia" -> "one-piece"
+var episodeSlugSuffix = regexp.MustCompile(`-episode-\d+.*$`)
+
+// ReleasePoller checks the latest-episode/chapter feeds, remembers what it has seen and
+// notifies users who bookmarked a title when a new release shows up
+type ReleasePoller struct {
+	AnimeIndo *AnimeIndoService
+	Manga     *SankavollereiService
+	Interval  time.Duration
+
+	mu        sync.RWMutex
+	notifiers []Notifier
+	startOnce sync.Once
+}
+
+var (
+	releasePoller     *ReleasePoller
+	releasePollerOnce sync.Once
+)
+
+// GetReleasePoller returns the shared poller with the Web Push notifier registered when
+// VAPID keys are available (in-app notifications are always stored).
+// NOTIFICATION_POLL_INTERVAL is a Go duration (default 15m); "0" disables polling.
+func GetReleasePoller() *ReleasePoller {
+	releasePollerOnce.Do(func() {
+		interval := defaultReleasePollInterval
+		if value := os.Getenv("NOTIFICATION_POLL_INTERVAL"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				fmt.Printf("[Notifications] ⚠️  Invalid NOTIFICATION_POLL_INTERVAL %q, using %s\n", value, interval)
+			} else {
+				interval = parsed
+			}
+		}
+
+		releasePoller = &ReleasePoller{
+			AnimeIndo: NewAnimeIndoService(),
+			Manga:     NewSankavollereiService(""),
+			Interval:  interval,
+		}
+		if sender, err := GetWebPushSender(); err == nil {
+			releasePoller.AddNotifier(&WebPushNotifier{Subscriptions: NewPushSubscriptionService(sender)})
+		}
+	})
+	return releasePoller
+}
+
+func (p *ReleasePoller) AddNotifier(notifier Notifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notifiers = append(p.notifiers, notifier)
+}
+
+// Start polls in the background, once right away and then every Interval
+func (p *ReleasePoller) Start() {
+	if p.Interval <= 0 {
+		fmt.Println("[Notifications] Release polling disabled")
+		return
+	}
+
+	p.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(p.Interval)
+			defer ticker.Stop()
+
+			for {
+				if err := p.Poll(); err != nil {
+					fmt.Printf("[Notifications] ⚠️  Poll failed: %v\n", err)
+				}
+				<-ticker.C
+			}
+		}()
+		fmt.Printf("[Notifications] Polling latest releases every %s\n", p.Interval)
+	})
+}
+
+// Poll runs one check of both feeds. A failing feed doesn't stop the other one.
+func (p *ReleasePoller) Poll() error {
+	var errs []error
+
+	feeds := []struct {
+		source    string
+		mediaType string
+		fetch     func() ([]models.Release, error)
+	}{
+		{releaseSourceAnimeIndo, "anime", p.latestEpisodes},
+		{releaseSourceKomikindo, "manga", p.latestChapters},
+	}
+
+	for _, feed := range feeds {
+		releases, err := feed.fetch()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", feed.source, err))
+			continue
+		}
+
+		fresh, events, notifications, err := recordReleases(feed.source, feed.mediaType, releases)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", feed.source, err))
+			continue
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		if err := GetWebhookDispatcher().Enqueue(events); err != nil {
+			errs = append(errs, fmt.Errorf("%s webhooks: %w", feed.source, err))
+		}
+		fmt.Printf("[Notifications] %s: %d new releases, %d notifications\n", feed.source, len(fresh), len(notifications))
+		p.dispatch(notifications)
+	}
+
+	if err := pruneReleaseEvents(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := pruneNotifications(notificationRetentionDays); err != nil {
+		errs = append(errs, err)
+	}
+	if err := pruneFeedEntries(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// recordReleases advances release_feed_state and stores the release events and in-app
+// notifications for the fresh releases in one transaction, so a failure leaves the releases
+// fresh for the next poll. It returns the fresh releases, their events and the notifications
+// that were newly stored; webhooks and the other notifiers run after the commit.
+func recordReleases(source string, mediaType string, releases []models.Release) ([]models.Release, []models.ReleaseEvent, []models.Notification, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer tx.Rollback()
+
+	fresh, err := diffReleases(tx, source, releases)
+	if err != nil || len(fresh) == 0 {
+		return nil, nil, nil, err
+	}
+	events, err := recordReleaseEvents(tx, fresh)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	notifications, err := matchBookmarks(tx, mediaType, fresh)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := storeNotifications(tx, notifications); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Notifications the user already had (no ID assigned) are not passed on
+	stored := notifications[:0:0]
+	for _, notification := range notifications {
+		if notification.ID != 0 {
+			stored = append(stored, notification)
+		}
+	}
+	return fresh, events, stored, nil
+}
+
+// dispatch hands the stored notifications to every notifier
+func (p *ReleasePoller) dispatch(notifications []models.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+
+	p.mu.RLock()
+	notifiers := append([]Notifier(nil), p.notifiers...)
+	p.mu.RUnlock()
+
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(notifications); err != nil {
+			fmt.Printf("[Notifications] ⚠️  %s notifier failed: %v\n", notifier.Name(), err)
+		}
+	}
+}
+
+func (p *ReleasePoller) latestEpisodes() ([]models.Release, error) {
+	episodes, err := p.AnimeIndo.GetLatestEpisodes(1)
+	if err != nil {
+		return nil, err
+	}
+	return episodeReleases(episodes), nil
+}
+
+// episodeReleases turns Anime Indo latest episodes into releases keyed by series slug
+func episodeReleases(episodes []LatestEpisode) []models.Release {
+	releases := make([]models.Release, 0, len(episodes))
+	for _, episode := range episodes {
+		if episode.Slug == "" || episode.Episode == "" {
+			continue
+		}
+		release := models.Release{
+			Type:       "anime",
+			Slug:       episodeSlugSuffix.ReplaceAllString(episode.Slug, ""),
+			Title:      episode.Title,
+			Release:    strings.TrimSpace(episode.Episode),
+			CoverImage: episode.Poster,
+		}
+		if release.Slug != episode.Slug {
+			release.TargetSlug = episode.Slug
+		}
+		releases = append(releases, release)
+	}
+	return releases
+}
+
+func (p *ReleasePoller) latestChapters() ([]models.Release, error) {
+	result, err := p.Manga.GetOngoingManga(1)
+	if err != nil {
+		return nil, err
+	}
+	return chapterReleases(result.Data.MangaList), nil
+}
+
+// chapterReleases turns a manga list with latest chapters into releases
+func chapterReleases(mangaList []models.Manga) []models.Release {
+	releases := make([]models.Release, 0, len(mangaList))
+	for _, manga := range mangaList {
+		slug := mangaSlug(manga)
+		if slug == "" || manga.Chapter == "" {
+			continue
+		}
+		releases = append(releases, models.Release{
+			Type:       "manga",
+			Slug:       slug,
+			Title:      manga.Title,
+			Release:    strings.TrimSpace(manga.Chapter),
+			CoverImage: firstNonEmpty(manga.Image, manga.Cover, manga.Poster, manga.Thumbnail),
+		})
+	}
+	return releases
+}
+
+func mangaSlug(manga models.Manga) string {
+	if manga.Slug == "" && manga.Link != "" {
+		return path.Base(strings.TrimSuffix(manga.Link, "/"))
+	}
+	return manga.Slug
+}
+
+// newestPerSeries keeps the first (newest) release of each series. The state holds one
+// release per series, so a page listing episodes 12 and 11 of a series would otherwise
+// flip it between the two on every poll and report both as fresh each time.
+func newestPerSeries(releases []models.Release) []models.Release {
+	seen := make(map[string]bool, len(releases))
+	newest := make([]models.Release, 0, len(releases))
+	for _, release := range releases {
+		if !seen[release.Slug] {
+			seen[release.Slug] = true
+			newest = append(newest, release)
+		}
+	}
+	return newest
+}
+
+// diffReleases records the feed in release_feed_state and returns the releases not seen
+// before. The first poll of a source only records the baseline. The conditional upsert
+// makes each change show up exactly once even with several backend instances polling.
+func diffReleases(tx *sql.Tx, source string, releases []models.Release) ([]models.Release, error) {
+	releases = newestPerSeries(releases)
+
+	var seeded bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM release_feed_state WHERE source = $1)`, source).
+		Scan(&seeded); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO release_feed_state (source, series_slug, latest_release) VALUES ($1, $2, $3)
+              ON CONFLICT (source, series_slug) DO UPDATE SET latest_release = EXCLUDED.latest_release, updated_at = NOW()
+              WHERE release_feed_state.latest_release <> EXCLUDED.latest_release
+              RETURNING series_slug`
+
+	var fresh []models.Release
+	for _, release := range releases {
+		var slug string
+		err := tx.QueryRow(query, source, release.Slug, release.Release).Scan(&slug)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Unchanged
+		}
+		if err != nil {
+			return nil, err
+		}
+		if seeded {
+			fresh = append(fresh, release)
+		}
+	}
+	return fresh, nil
+}
+
+// matchBookmarks builds one notification per user who bookmarked a released title
+// (matched by slug, or by title when the feed uses another site's slug). Dropped titles are skipped.
+func matchBookmarks(tx *sql.Tx, mediaType string, releases []models.Release) ([]models.Notification, error) {
+	bySlug := make(map[string]models.Release, len(releases))
+	byTitle := make(map[string]models.Release, len(releases))
+	slugs := make([]string, 0, len(releases))
+	titles := make([]string, 0, len(releases))
+	for _, release := range releases {
+		bySlug[release.Slug] = release
+		slugs = append(slugs, release.Slug)
+		if title := strings.ToLower(strings.TrimSpace(release.Title)); title != "" {
+			byTitle[title] = release
+			titles = append(titles, title)
+		}
+	}
+
+	query := `SELECT user_id, slug, title, COALESCE(cover_image, '') FROM bookmarks
+              WHERE type = $1 AND status <> $2 AND (slug = ANY($3) OR LOWER(TRIM(title)) = ANY($4))`
+	rows, err := tx.Query(query, mediaType, ListStatusDropped, pq.Array(slugs), pq.Array(titles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		var userID int
+		var slug, title, cover string
+		if err := rows.Scan(&userID, &slug, &title, &cover); err != nil {
+			return nil, err
+		}
+
+		release, ok := bySlug[slug]
+		if !ok {
+			if release, ok = byTitle[strings.ToLower(strings.TrimSpace(title))]; !ok {
+				continue
+			}
+			release.TargetSlug = "" // Episode slug of another site
+		}
+		notifications = append(notifications, models.Notification{
+			UserID:     userID,
+			Type:       mediaType,
+			Slug:       slug, // The bookmarked slug, so the link opens the user's series
+			Title:      title,
+			Release:    release.Release,
+			TargetSlug: release.TargetSlug,
+			CoverImage: firstNonEmpty(release.CoverImage, cover),
+		})
+	}
+	return notifications, rows.Err()
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestNewestPerSeriesWithSeriesListedTwice(t *testing.T) {
+	// Latest episodes, newest first, with two episodes of one series on the page
+	releases := episodeReleases([]LatestEpisode{
+		{Title: "One Piece", Slug: "one-piece-episode-1101-subtitle-indonesia", Episode: "Episode 1101"},
+		{Title: "Frieren", Slug: "frieren-episode-12", Episode: "Episode 12"},
+		{Title: "One Piece", Slug: "one-piece-episode-1100-subtitle-indonesia", Episode: "Episode 1100"},
+		{Title: "Frieren", Slug: "frieren-episode-11", Episode: "Episode 11"},
+		{Title: "No episode", Slug: "no-episode"},
+	})
+
+	want := []models.Release{
+		{Type: "anime", Slug: "one-piece", Title: "One Piece", Release: "Episode 1101", TargetSlug: "one-piece-episode-1101-subtitle-indonesia"},
+		{Type: "anime", Slug: "frieren", Title: "Frieren", Release: "Episode 12", TargetSlug: "frieren-episode-12"},
+	}
+	if got := newestPerSeries(releases); !reflect.DeepEqual(got, want) {
+		t.Fatalf("newestPerSeries = %+v, want %+v", got, want)
+	}
+}
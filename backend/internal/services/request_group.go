@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+)
+
+// requestGroup merges identical in-flight upstream calls into one
+// (a minimal singleflight: callers with the same key share the first call's result)
+type requestGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// Shared by every SankavollereiService instance so controllers coalesce with each other
+var upstreamRequests = newRequestGroup()
+
+func newRequestGroup() *requestGroup {
+	return &requestGroup{calls: make(map[string]*inflightCall)}
+}
+
+// Do runs fn once per key at a time; concurrent callers wait and get the same result.
+// A panic in fn is recovered and returned to every caller as an error, so waiters are
+// never left blocked and a background Go call can't crash the server.
+func (g *requestGroup) Do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.data, call.err
+	}
+
+	call := &inflightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	g.run(key, call, fn)
+	return call.data, call.err
+}
+
+func (g *requestGroup) run(key string, call *inflightCall, fn func() ([]byte, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.data, call.err = nil, fmt.Errorf("upstream call %s panicked: %v", key, r)
+			fmt.Printf("[Sankavollerei] ⚠️  %v\n", call.err)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.data, call.err = fn()
+}
+
+// Go starts fn in the background unless a call for key is already running
+func (g *requestGroup) Go(key string, fn func() ([]byte, error)) {
+	g.mu.Lock()
+	_, running := g.calls[key]
+	g.mu.Unlock()
+	if running {
+		return
+	}
+
+	go g.Do(key, fn)
+}
@@ -5,7 +5,6 @@ import (
 	"anime-tanyaayomi/internal/models"
 	"anime-tanyaayomi/internal/repository"
 	"fmt"
-	"sort"
 	"sync"
 	"time"
 )
@@ -29,73 +28,59 @@ func getEnrichmentRepo() *repository.EnrichmentRepository {
 	return enrichmentRepo
 }
 
-// enrichAnimeWithGemini uses Gemini AI to fill missing data (Year, Rating, etc.) for Anime
-// Now with database persistence to reduce API costs by ~90%
-func (s *SankavollereiService) enrichAnimeWithGemini(items []models.Anime) []models.Anime {
-	var wg sync.WaitGroup
-	maxConcurrency := 5
-	sem := make(chan struct{}, maxConcurrency)
-
-	// Get repository (will use database if available)
-	repo := getEnrichmentRepo()
-
+// enrichAnimeList fills missing data (Year, Rating, etc.) for Anime from enriched_metadata.
+// It never waits for the LLM: titles without metadata are queued and show up once a worker
+// has enriched them.
+func (s *SankavollereiService) enrichAnimeList(items []models.Anime) []models.Anime {
+	var titles []string
 	for i := range items {
 		// Only enrich if missing data (upstream usually sends "ReleaseDate" as empty or non-year string)
-		// Usually upstream has specific format, but if it's missing or we want year:
-		// We'll trust Gemini if ReleaseDate is missing.
 		if items[i].ReleaseDate == "" {
-			wg.Add(1)
-			go func(idx int) {
-				defer wg.Done()
-				sem <- struct{}{}
-				defer func() { <-sem }()
-
-				// Use database-aware enrichment (DB → Cache → Gemini)
-				data := EnrichDataWithDB(items[idx].Title, "anime", repo)
-				if data.Year != "" {
-					items[idx].ReleaseDate = data.Year
-				}
-				if items[idx].Status == "" && data.Status != "" {
-					items[idx].Status = data.Status
-				}
-				if items[idx].Rating == "" && data.Rating != "" {
-					items[idx].Rating = data.Rating
-				}
-			}(i)
+			titles = append(titles, items[i].Title)
+		}
+	}
+	enriched := StoredEnrichments(titles, "anime")
+
+	for i := range items {
+		if data, ok := enriched[items[i].Title]; ok && items[i].ReleaseDate == "" {
+			if data.Year != "" {
+				items[i].ReleaseDate = data.Year
+			}
+			if items[i].Status == "" && data.Status != "" {
+				items[i].Status = data.Status
+			}
+			if items[i].Rating == "" && data.Rating != "" {
+				items[i].Rating = data.Rating
+			}
 		}
 
 		// User requested Type to be "Anime" always, instead of "TV"/"Movie" or empty.
 		// This applies to all lists (Home, Ongoing, Completed, Search, Genre)
 		items[i].Type = "Anime"
 	}
-	wg.Wait()
 	return items
 }
 
 // GetHome fetches ongoing and completed anime from homepage
 func (s *SankavollereiService) GetHome() (*models.HomeResponse, error) {
-	// Check cache first (cache the enriched data, not raw API response)
-	cacheKey := s.Prefix + "home_enriched"
-	if cached, found := s.Cache.Get(cacheKey); found {
-		if result, ok := cached.(*models.HomeResponse); ok {
-			return result, nil
+	// Cache the enriched data, not raw API response (5 minutes, stale-while-revalidate)
+	var result models.HomeResponse
+	err := s.cachedFetch("home_enriched", &result, 5*time.Minute, func() (interface{}, error) {
+		// Fetch raw data from API (no cache for raw data)
+		var home models.HomeResponse
+		if err := s.makeRequest("home", &home); err != nil {
+			return nil, err
 		}
-	}
 
-	// Fetch raw data from API (no cache for raw data)
-	var result models.HomeResponse
-	err := s.makeRequest("home", &result)
+		// Stored AI enrichment (this now happens BEFORE caching)
+		home.Data.Ongoing.AnimeList = s.enrichAnimeList(home.Data.Ongoing.AnimeList)
+		home.Data.Completed.AnimeList = s.enrichAnimeList(home.Data.Completed.AnimeList)
+		return &home, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home data: %w", err)
 	}
 
-	// AI Enrichment (this now happens BEFORE caching)
-	result.Data.Ongoing.AnimeList = s.enrichAnimeWithGemini(result.Data.Ongoing.AnimeList)
-	result.Data.Completed.AnimeList = s.enrichAnimeWithGemini(result.Data.Completed.AnimeList)
-
-	// Cache the enriched result for 5 minutes
-	s.Cache.Set(cacheKey, &result, 5*time.Minute)
-
 	return &result, nil
 }
 
@@ -128,7 +113,7 @@ func (s *SankavollereiService) GetGenre(slug string) (*models.SearchResponse, er
 	}
 
 	// AI Enrichment
-	result.Data.AnimeList = s.enrichAnimeWithGemini(result.Data.AnimeList)
+	result.Data.AnimeList = s.enrichAnimeList(result.Data.AnimeList)
 
 	return &result, nil
 }
@@ -149,7 +134,7 @@ func (s *SankavollereiService) GetOngoingAnime(page int) (*models.SearchResponse
 	}
 
 	// AI Enrichment
-	result.Data.AnimeList = s.enrichAnimeWithGemini(result.Data.AnimeList)
+	result.Data.AnimeList = s.enrichAnimeList(result.Data.AnimeList)
 
 	return &result, nil
 }
@@ -166,7 +151,7 @@ func (s *SankavollereiService) SearchAnime(query string) (*models.SearchResponse
 		return nil, fmt.Errorf("failed to search anime: %w", err)
 	}
 
-	result.Data.AnimeList = s.enrichAnimeWithGemini(result.Data.AnimeList)
+	result.Data.AnimeList = s.enrichAnimeList(result.Data.AnimeList)
 	return &result, nil
 }
 
@@ -186,7 +171,7 @@ func (s *SankavollereiService) GetCompleteAnime(page int) (*models.SearchRespons
 	}
 
 	// AI Enrichment
-	result.Data.AnimeList = s.enrichAnimeWithGemini(result.Data.AnimeList)
+	result.Data.AnimeList = s.enrichAnimeList(result.Data.AnimeList)
 
 	return &result, nil
 }
@@ -236,91 +221,17 @@ func (s *SankavollereiService) GetAnimeDetail(slug string) (*models.AnimeDetailR
 	return &result, nil
 }
 
-// GetEpisodeStream fetches streaming URLs for an episode from multiple sources
-// Now aggregates both Otakudesu (Server 1) and Oploverz (Quality Selector)
+// GetEpisodeStream fetches the Otakudesu streaming data for an episode.
+// Other mirrors (Anime Indo, Zoro) are merged by StreamResolver.
 func (s *SankavollereiService) GetEpisodeStream(episodeId string) (*models.StreamResponse, error) {
 	var result models.StreamResponse
 
-	// Step 1: Get Otakudesu stream (Server 1 - Sub Indo, default quality)
 	endpoint := fmt.Sprintf("episode/%s", episodeId)
 	err := s.makeRequestWithCache(endpoint, &result, 15*time.Minute)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get episode stream: %w", err)
 	}
 
-	// Step 2: Try to get Anime Indo streams for quality selector
-	fmt.Printf("[Sankavollerei] Got Otakudesu title: '%s'\n", result.Data.Title)
-	animeIndoService := NewAnimeIndoService()
-	animeIndoStreams, animeIndoErr := animeIndoService.GetEpisodeStream(episodeId, result.Data.Title)
-
-	if animeIndoErr == nil && len(animeIndoStreams) > 0 {
-		// Successfully got Anime Indo streams, add as server options
-		fmt.Printf("[Stream] ✅ Anime Indo servers available for %s\n", episodeId)
-
-		// Build server options from Anime Indo response
-		qualityOptions := []models.QualityOption{}
-
-		// Map of quality order for sorting
-		qualityOrder := map[string]int{
-			"360p":  1,
-			"480p":  2,
-			"720p":  3,
-			"1080p": 4,
-		}
-
-		// Group streams by quality
-		for serverName, url := range animeIndoStreams {
-			if serverName == "default" {
-				continue // Skip default
-			}
-
-			// Create server entry
-			serverList := []models.StreamServer{
-				{
-					Title:    serverName,
-					ServerID: fmt.Sprintf("animeindo_%s", serverName),
-					Href:     url,
-				},
-			}
-
-			qualityOptions = append(qualityOptions, models.QualityOption{
-				Title:      serverName,
-				ServerList: serverList,
-			})
-		}
-
-		// Sort qualities by resolution (360p first, then 480p, 720p, 1080p)
-		sort.Slice(qualityOptions, func(i, j int) bool {
-			orderI := qualityOrder[qualityOptions[i].Title]
-			orderJ := qualityOrder[qualityOptions[j].Title]
-			return orderI < orderJ
-		})
-
-		// Update result with quality options
-		if len(qualityOptions) > 0 {
-			result.Data.Server.Qualities = qualityOptions
-			fmt.Printf("[Stream] ✅ Added %d server options from Anime Indo\n", len(qualityOptions))
-		}
-	} else {
-		fmt.Printf("[Stream] ⚠️ Anime Indo unavailable for %s: %v\n", episodeId, animeIndoErr)
-		// Continue without Anime Indo - Otakudesu stream still available
-	}
-
-	// Step 3: Update Server 1 title to indicate Otakudesu
-	// Rename existing qualities to "Server 1 - Otakudesu"
-	// This step is only relevant if Anime Indo did NOT overwrite the qualities.
-	// If Anime Indo successfully provided streams, result.Data.Server.Qualities would have been replaced.
-	// If Anime Indo failed, the original Otakudesu qualities remain, and this update applies.
-	if animeIndoErr != nil && len(result.Data.Server.Qualities) > 0 {
-		for i := range result.Data.Server.Qualities {
-			// Only update the first quality (Otakudesu)
-			if i == 0 {
-				result.Data.Server.Qualities[i].Title = fmt.Sprintf("Server 1 - Otakudesu (Sub Indo) - %s", result.Data.Server.Qualities[i].Title)
-				break
-			}
-		}
-	}
-
 	return &result, nil
 }
 
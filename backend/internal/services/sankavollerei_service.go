@@ -1,10 +1,12 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 )
@@ -31,15 +33,7 @@ func (rl *RateLimiter) Allow() bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	// Refill tokens based on time passed
-	now := time.Now()
-	elapsed := now.Sub(rl.lastRefill)
-	tokensToAdd := int(elapsed / rl.refillRate)
-
-	if tokensToAdd > 0 {
-		rl.tokens = min(rl.maxTokens, rl.tokens+tokensToAdd)
-		rl.lastRefill = now
-	}
+	rl.refill()
 
 	// Check if we have tokens available
 	if rl.tokens > 0 {
@@ -49,67 +43,87 @@ func (rl *RateLimiter) Allow() bool {
 	return false
 }
 
-// Cache entry
-type CacheEntry struct {
-	Data      interface{}
-	ExpiresAt time.Time
-}
-
-// Simple in-memory cache
-type Cache struct {
-	store map[string]*CacheEntry
-	mu    sync.RWMutex
-}
+// refill adds the tokens earned since the last refill; rl.mu must be held
+func (rl *RateLimiter) refill() {
+	now := time.Now()
+	elapsed := now.Sub(rl.lastRefill)
+	tokensToAdd := int(elapsed / rl.refillRate)
 
-func NewCache() *Cache {
-	cache := &Cache{
-		store: make(map[string]*CacheEntry),
+	if tokensToAdd > 0 {
+		rl.tokens = min(rl.maxTokens, rl.tokens+tokensToAdd)
+		rl.lastRefill = now
 	}
-	// Start cleanup goroutine
-	go cache.cleanup()
-	return cache
 }
 
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// RetryAfter is how long until the next token is added
+func (rl *RateLimiter) RetryAfter() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
 
-	entry, exists := c.store[key]
-	if !exists {
-		return nil, false
+	if wait := rl.refillRate - time.Since(rl.lastRefill); wait > 0 {
+		return wait
 	}
+	return 0
+}
 
-	if time.Now().After(entry.ExpiresAt) {
-		return nil, false
+// Wait blocks until a token is available (and takes it) or ctx is done
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	for !rl.Allow() {
+		timer := time.NewTimer(rl.RetryAfter())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
 	}
+	return nil
+}
 
-	return entry.Data, true
+// WaitAbove blocks until more than reserve tokens are left, without taking one, or ctx is
+// done. Background work uses it to spend only what callers of Allow leave over.
+func (rl *RateLimiter) WaitAbove(ctx context.Context, reserve int) error {
+	for {
+		rl.mu.Lock()
+		rl.refill()
+		if rl.tokens > reserve {
+			rl.mu.Unlock()
+			return nil
+		}
+		wait := rl.refillRate - time.Since(rl.lastRefill)
+		rl.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
 }
 
-func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// upstreamLimiter is shared by every SankavollereiService: the 70 requests per minute
+// (1 token every ~857ms) are the API's limit, not one per service instance
+var upstreamLimiter = NewRateLimiter(70, 857*time.Millisecond)
 
-	c.store[key] = &CacheEntry{
-		Data:      value,
-		ExpiresAt: time.Now().Add(ttl),
-	}
+// RateLimitError is returned instead of calling the upstream when the rate limit is used up
+type RateLimitError struct {
+	RetryAfter time.Duration
 }
 
-func (c *Cache) cleanup() {
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+func (e *RateLimitError) Error() string {
+	return "rate limit exceeded, please wait"
+}
 
-	for range ticker.C {
-		c.mu.Lock()
-		now := time.Now()
-		for key, entry := range c.store {
-			if now.After(entry.ExpiresAt) {
-				delete(c.store, key)
-			}
-		}
-		c.mu.Unlock()
-	}
+// UpstreamStatusError is a non-200 response from the upstream API
+type UpstreamStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *UpstreamStatusError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
 }
 
 // Sankavollerei Service
@@ -118,7 +132,20 @@ type SankavollereiService struct {
 	Client      *http.Client
 	Prefix      string
 	RateLimiter *RateLimiter
-	Cache       *Cache
+	Cache       CacheStore
+
+	// ServeStaleOnError keeps returning the last good response (up to StaleIfErrorTTL old)
+	// when the upstream request fails. Enabled with UPSTREAM_SERVE_STALE=true.
+	ServeStaleOnError bool
+	StaleIfErrorTTL   time.Duration
+}
+
+// cachedResponse wraps cached upstream data with its freshness window.
+// Fresh: served as-is. Stale (until StaleUntil): served while one background refresh runs.
+type cachedResponse struct {
+	Data       json.RawMessage `json:"data"`
+	FreshUntil time.Time       `json:"freshUntil"`
+	StaleUntil time.Time       `json:"staleUntil"`
 }
 
 func NewSankavollereiService(prefix string) *SankavollereiService {
@@ -129,19 +156,21 @@ func NewSankavollereiService(prefix string) *SankavollereiService {
 	}
 
 	return &SankavollereiService{
-		BaseURL: "https://www.sankavollerei.com",
-		Client:  client,
-		Prefix:  prefix,
-		// 70 requests per minute = 1 token every ~857ms
-		RateLimiter: NewRateLimiter(70, 857*time.Millisecond),
-		Cache:       NewCache(),
+		BaseURL:     "https://www.sankavollerei.com",
+		Client:      client,
+		Prefix:      prefix,
+		RateLimiter: upstreamLimiter,
+		// Shared cache tier (Redis when available) so every instance and replica sees the same entries
+		Cache:             SharedCache(),
+		ServeStaleOnError: os.Getenv("UPSTREAM_SERVE_STALE") == "true",
+		StaleIfErrorTTL:   24 * time.Hour,
 	}
 }
 
 func (s *SankavollereiService) makeRequest(endpoint string, result interface{}) error {
 	// Check rate limit
 	if !s.RateLimiter.Allow() {
-		return fmt.Errorf("rate limit exceeded, please wait")
+		return &RateLimitError{RetryAfter: s.RateLimiter.RetryAfter()}
 	}
 
 	// Build URL - check if endpoint starts with "comic/"
@@ -173,7 +202,7 @@ func (s *SankavollereiService) makeRequest(endpoint string, result interface{})
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &UpstreamStatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	// Parse response
@@ -185,23 +214,84 @@ func (s *SankavollereiService) makeRequest(endpoint string, result interface{})
 }
 
 func (s *SankavollereiService) makeRequestWithCache(endpoint string, result interface{}, cacheTTL time.Duration) error {
-	// Check cache first
-	cacheKey := s.Prefix + endpoint
-	if cached, found := s.Cache.Get(cacheKey); found {
-		// Use type assertion to copy cached data to result
-		cachedJSON, _ := json.Marshal(cached)
-		json.Unmarshal(cachedJSON, result)
-		return nil
+	return s.cachedFetch(endpoint, result, cacheTTL, func() (interface{}, error) {
+		var raw json.RawMessage
+		if err := s.makeRequest(endpoint, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	})
+}
+
+// cachedFetch serves name from the shared cache with stale-while-revalidate:
+//   - fresh entry (younger than cacheTTL): returned directly
+//   - stale entry (up to another cacheTTL): returned, and a single background refresh is started
+//   - missing/expired: fetched once, concurrent callers for the same key share that call
+//
+// With ServeStaleOnError, an expired entry is still returned when the fetch fails.
+func (s *SankavollereiService) cachedFetch(name string, result interface{}, cacheTTL time.Duration, fetch func() (interface{}, error)) error {
+	cacheKey := s.cacheKey(name)
+	refresh := func() ([]byte, error) {
+		return s.refreshCache(cacheKey, cacheTTL, fetch)
+	}
+
+	var entry cachedResponse
+	hasEntry := s.Cache.Get(cacheKey, &entry)
+	now := time.Now()
+
+	if hasEntry && now.Before(entry.FreshUntil) {
+		return json.Unmarshal(entry.Data, result)
+	}
+
+	if hasEntry && now.Before(entry.StaleUntil) {
+		upstreamRequests.Go(cacheKey, refresh)
+		return json.Unmarshal(entry.Data, result)
 	}
 
-	// Make actual API request
-	if err := s.makeRequest(endpoint, result); err != nil {
+	data, err := upstreamRequests.Do(cacheKey, refresh)
+	if err != nil {
+		if hasEntry && s.ServeStaleOnError {
+			fmt.Printf("[Sankavollerei] ⚠️  Upstream failed for %s, serving stale copy: %v\n", name, err)
+			return json.Unmarshal(entry.Data, result)
+		}
 		return err
 	}
 
-	// Store in cache
-	s.Cache.Set(cacheKey, result, cacheTTL)
-	return nil
+	return json.Unmarshal(data, result)
+}
+
+// refreshCache calls fetch and stores the result with its freshness window
+func (s *SankavollereiService) refreshCache(cacheKey string, cacheTTL time.Duration, fetch func() (interface{}, error)) ([]byte, error) {
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	now := time.Now()
+	entry := cachedResponse{
+		Data:       data,
+		FreshUntil: now.Add(cacheTTL),
+		StaleUntil: now.Add(2 * cacheTTL),
+	}
+
+	// Keep the entry around longer when it may be served on upstream errors
+	storeTTL := 2 * cacheTTL
+	if s.ServeStaleOnError && s.StaleIfErrorTTL > storeTTL {
+		storeTTL = s.StaleIfErrorTTL
+	}
+	s.Cache.Set(cacheKey, entry, storeTTL)
+
+	return data, nil
+}
+
+// cacheKey namespaces Sankavollerei entries in the shared cache
+func (s *SankavollereiService) cacheKey(name string) string {
+	return "sankavollerei:" + s.Prefix + name
 }
 
 func min(a, b int) int {
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default order of the stream fallback chain (override with STREAM_SOURCES="otakudesu,zoro")
+var defaultStreamSources = []string{ProviderOtakudesu, ProviderAnimeIndo, ProviderZoro}
+
+// SourceStream is what a single stream source returns for an episode
+type SourceStream struct {
+	Title       string
+	AnimeID     string
+	DefaultURL  string
+	Qualities   []models.QualityOption
+	DownloadURL interface{}
+}
+
+// StreamSource is one link in the episode stream chain
+type StreamSource interface {
+	Name() string
+	Resolve(slug string) (*SourceStream, error)
+}
+
+// StreamResolver queries every configured source for an episode and merges
+// the working servers into one response. Sources run concurrently; the chain
+// order decides priority (default URL, title, metadata) and server ordering.
+type StreamResolver struct {
+	Sources []StreamSource
+}
+
+func NewStreamResolver(sources ...StreamSource) *StreamResolver {
+	return &StreamResolver{Sources: sources}
+}
+
+// NewDefaultStreamResolver builds the chain from STREAM_SOURCES (comma-separated names)
+func NewDefaultStreamResolver(sankavollerei *SankavollereiService, animeIndo *AnimeIndoService, zoro *ZoroService) *StreamResolver {
+	available := map[string]StreamSource{
+		ProviderOtakudesu: &OtakudesuEpisodeSource{Service: sankavollerei},
+		ProviderAnimeIndo: &AnimeIndoEpisodeSource{Service: animeIndo},
+		ProviderZoro:      &ZoroEpisodeSource{Service: zoro},
+	}
+
+	names := defaultStreamSources
+	if env := os.Getenv("STREAM_SOURCES"); env != "" {
+		names = strings.Split(env, ",")
+	}
+
+	var chain []StreamSource
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if source, ok := available[name]; ok {
+			chain = append(chain, source)
+		} else if name != "" {
+			fmt.Printf("[StreamResolver] ⚠️  Unknown stream source '%s' ignored\n", name)
+		}
+	}
+
+	return NewStreamResolver(chain...)
+}
+
+// Resolve runs the whole chain. It only fails when no source returned a server;
+// the per-source results (including failure reasons) are always filled in.
+func (r *StreamResolver) Resolve(slug string) (*models.ResolvedStream, error) {
+	streams := make([]*SourceStream, len(r.Sources))
+	results := make([]models.StreamSourceResult, len(r.Sources))
+
+	var wg sync.WaitGroup
+	for i, source := range r.Sources {
+		wg.Add(1)
+		go func(idx int, source StreamSource) {
+			defer wg.Done()
+
+			start := time.Now()
+			stream, err := source.Resolve(slug)
+			result := models.StreamSourceResult{
+				Source:     source.Name(),
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+
+			if err == nil && stream != nil && (stream.DefaultURL != "" || countServers(stream.Qualities) > 0) {
+				result.OK = true
+				result.Servers = countServers(stream.Qualities)
+				streams[idx] = stream
+			} else if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Error = "no servers returned"
+			}
+
+			results[idx] = result
+		}(i, source)
+	}
+	wg.Wait()
+
+	resolved := &models.ResolvedStream{
+		StreamLink: make(map[string]string),
+		Server:     models.ServerData{Qualities: []models.QualityOption{}},
+		Sources:    results,
+	}
+
+	for i, stream := range streams {
+		if stream == nil {
+			continue
+		}
+		name := r.Sources[i].Name()
+
+		// First successful source in chain order wins the top-level fields
+		if resolved.DefaultStreamingUrl == "" && stream.DefaultURL != "" {
+			resolved.DefaultStreamingUrl = stream.DefaultURL
+			resolved.URL = stream.DefaultURL
+		}
+		if resolved.Title == "" {
+			resolved.Title = stream.Title
+		}
+		if resolved.AnimeID == "" {
+			resolved.AnimeID = stream.AnimeID
+		}
+		if resolved.DownloadURL == nil {
+			resolved.DownloadURL = stream.DownloadURL
+		}
+
+		// Every direct (absolute) server URL becomes a mirror in stream_link
+		hrefs := make(map[string]bool)
+		for _, quality := range stream.Qualities {
+			for j := range quality.ServerList {
+				quality.ServerList[j].Source = name
+				if href := quality.ServerList[j].Href; strings.HasPrefix(href, "http") {
+					resolved.StreamLink[fmt.Sprintf("%s %s", name, quality.ServerList[j].Title)] = href
+					hrefs[href] = true
+				}
+			}
+			resolved.Server.Qualities = append(resolved.Server.Qualities, quality)
+		}
+		if stream.DefaultURL != "" && !hrefs[stream.DefaultURL] {
+			resolved.StreamLink[name] = stream.DefaultURL
+		}
+	}
+
+	if resolved.DefaultStreamingUrl == "" && len(resolved.Server.Qualities) == 0 {
+		return resolved, fmt.Errorf("no stream source returned servers for '%s'", slug)
+	}
+
+	if resolved.DefaultStreamingUrl != "" {
+		resolved.StreamLink["default"] = resolved.DefaultStreamingUrl
+	}
+	if resolved.Title == "" {
+		resolved.Title = slug
+	}
+	if resolved.AnimeID == "" {
+		resolved.AnimeID = animeSlugFromEpisode(slug)
+	}
+	if resolved.DownloadURL == nil {
+		resolved.DownloadURL = ""
+	}
+
+	return resolved, nil
+}
+
+func countServers(qualities []models.QualityOption) int {
+	total := 0
+	for _, q := range qualities {
+		total += len(q.ServerList)
+	}
+	return total
+}
+
+// ========== Stream Sources ==========
+
+// OtakudesuEpisodeSource uses the Sankavollerei Otakudesu episode endpoint
+type OtakudesuEpisodeSource struct {
+	Service *SankavollereiService
+}
+
+func (s *OtakudesuEpisodeSource) Name() string { return ProviderOtakudesu }
+
+func (s *OtakudesuEpisodeSource) Resolve(slug string) (*SourceStream, error) {
+	result, err := s.Service.GetEpisodeStream(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	// Label Otakudesu qualities so they can be told apart from other mirrors.
+	// Copy first: the response may be the same value held in the request cache.
+	qualities := make([]models.QualityOption, 0, len(result.Data.Server.Qualities))
+	for _, q := range result.Data.Server.Qualities {
+		qualities = append(qualities, models.QualityOption{
+			Title:      fmt.Sprintf("Otakudesu (Sub Indo) - %s", q.Title),
+			ServerList: append([]models.StreamServer(nil), q.ServerList...),
+		})
+	}
+
+	return &SourceStream{
+		Title:       result.Data.Title,
+		AnimeID:     result.Data.AnimeID,
+		DefaultURL:  result.Data.DefaultStreamingUrl,
+		Qualities:   qualities,
+		DownloadURL: result.Data.DownloadURL,
+	}, nil
+}
+
+// AnimeIndoEpisodeSource uses the Anime Indo stream endpoint (one quality per server)
+type AnimeIndoEpisodeSource struct {
+	Service *AnimeIndoService
+}
+
+func (s *AnimeIndoEpisodeSource) Name() string { return ProviderAnimeIndo }
+
+func (s *AnimeIndoEpisodeSource) Resolve(slug string) (*SourceStream, error) {
+	streams, err := s.Service.GetEpisodeStream(slug, "")
+	if err != nil {
+		return nil, err
+	}
+
+	serverNames := make([]string, 0, len(streams))
+	for serverName := range streams {
+		if serverName != "default" {
+			serverNames = append(serverNames, serverName)
+		}
+	}
+	sort.Strings(serverNames)
+
+	qualities := make([]models.QualityOption, 0, len(serverNames))
+	for _, serverName := range serverNames {
+		qualities = append(qualities, models.QualityOption{
+			Title: fmt.Sprintf("Anime Indo - %s", serverName),
+			ServerList: []models.StreamServer{
+				{
+					Title:    serverName,
+					ServerID: fmt.Sprintf("animeindo_%s", serverName),
+					Href:     streams[serverName],
+				},
+			},
+		})
+	}
+
+	return &SourceStream{
+		AnimeID:    animeSlugFromEpisode(slug),
+		DefaultURL: streams["default"],
+		Qualities:  qualities,
+	}, nil
+}
+
+// ZoroEpisodeSource uses the Consumet Zoro API (HLS sources, one per quality)
+type ZoroEpisodeSource struct {
+	Service *ZoroService
+}
+
+func (s *ZoroEpisodeSource) Name() string { return ProviderZoro }
+
+func (s *ZoroEpisodeSource) Resolve(slug string) (*SourceStream, error) {
+	title, episodeNumber, err := ParseOtakudesuSlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	streamData, err := s.Service.GetEpisodeSources(title, episodeNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var qualities []models.QualityOption
+	for _, source := range streamData.Sources {
+		if source.URL == "" {
+			continue
+		}
+
+		// Browsers can't send the Referer Zoro's CDN wants, so playlists go through /api/proxy/hls
+		href := source.URL
+		if source.IsM3U8 {
+			href = GetHLSProxy().ProxyURL(source.URL, streamData.Headers.Referer)
+		}
+
+		qualities = append(qualities, models.QualityOption{
+			Title: fmt.Sprintf("Zoro - %s", source.Quality),
+			ServerList: []models.StreamServer{
+				{
+					Title:    source.Quality,
+					ServerID: fmt.Sprintf("zoro_%s", source.Quality),
+					Href:     href,
+					IsM3U8:   source.IsM3U8,
+				},
+			},
+		})
+	}
+
+	return &SourceStream{
+		AnimeID:   animeSlugFromEpisode(slug),
+		Qualities: qualities,
+	}, nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Backups with thousands of chapters stay well below this once decompressed
+const maxBackupBytes = 64 << 20 // 64 MB
+
+var ErrInvalidBackup = errors.New("invalid Tachiyomi/Mihon backup")
+
+// TachiyomiBackup holds the parts of a .tachibk / .proto.gz backup we import.
+// Field numbers follow Mihon's BackupSerializer (compatible with Tachiyomi 0.13+).
+type TachiyomiBackup struct {
+	Manga      []TachiyomiManga
+	Categories []TachiyomiCategory
+	Sources    map[int64]string // source id -> name
+}
+
+type TachiyomiManga struct {
+	Source     int64
+	URL        string
+	Title      string
+	Thumbnail  string
+	Favorite   bool
+	Chapters   []TachiyomiChapter
+	Categories []int64 // Category "order" values
+	History    []TachiyomiHistory
+}
+
+type TachiyomiChapter struct {
+	URL           string
+	Name          string
+	Read          bool
+	LastPageRead  int64
+	ChapterNumber float32
+}
+
+type TachiyomiHistory struct {
+	URL      string // Chapter url
+	LastRead int64  // Unix millis
+}
+
+type TachiyomiCategory struct {
+	Name  string
+	Order int64
+}
+
+// SourceName returns the extension name for a manga's source id ("" if unknown)
+func (b *TachiyomiBackup) SourceName(id int64) string {
+	return b.Sources[id]
+}
+
+// CategoryNames resolves a manga's category order values to names
+func (b *TachiyomiBackup) CategoryNames(orders []int64) []string {
+	var names []string
+	for _, order := range orders {
+		for _, category := range b.Categories {
+			if category.Order == order {
+				names = append(names, category.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// ParseTachiyomiBackup decodes a gzipped (or plain) protobuf backup
+func ParseTachiyomiBackup(data []byte) (*TachiyomiBackup, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		data, err = io.ReadAll(io.LimitReader(reader, maxBackupBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+		}
+		if len(data) > maxBackupBytes {
+			return nil, fmt.Errorf("%w: backup too large", ErrInvalidBackup)
+		}
+	}
+
+	backup, err := decodeTachiyomiBackup(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if len(backup.Manga) == 0 {
+		return nil, fmt.Errorf("%w: no manga in backup", ErrInvalidBackup)
+	}
+	return backup, nil
+}
+
+func decodeTachiyomiBackup(buf []byte) (*TachiyomiBackup, error) {
+	backup := &TachiyomiBackup{Sources: make(map[int64]string)}
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if wire != protoWireBytes || (field != 1 && field != 2 && field != 100 && field != 101) {
+			if err := r.skip(wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		msg, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1: // backupManga
+			manga, err := decodeTachiyomiManga(msg)
+			if err != nil {
+				return nil, fmt.Errorf("manga %d: %w", len(backup.Manga), err)
+			}
+			backup.Manga = append(backup.Manga, *manga)
+		case 2: // backupCategories
+			category, err := decodeTachiyomiCategory(msg)
+			if err != nil {
+				return nil, err
+			}
+			backup.Categories = append(backup.Categories, *category)
+		case 100, 101: // brokenBackupSources (old) / backupSources
+			id, name, err := decodeTachiyomiSource(msg)
+			if err != nil {
+				return nil, err
+			}
+			backup.Sources[id] = name
+		}
+	}
+	return backup, nil
+}
+
+func decodeTachiyomiManga(buf []byte) (*TachiyomiManga, error) {
+	// favorite defaults to true and kotlinx ProtoBuf doesn't encode defaults, so library
+	// entries have no field 100 at all
+	manga := &TachiyomiManga{Favorite: true}
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 1 && wire == protoWireVarint:
+			manga.Source, err = r.int64()
+		case field == 2 && wire == protoWireBytes:
+			manga.URL, err = r.string()
+		case field == 3 && wire == protoWireBytes:
+			manga.Title, err = r.string()
+		case field == 9 && wire == protoWireBytes:
+			manga.Thumbnail, err = r.string()
+		case field == 16 && wire == protoWireBytes:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				var chapter *TachiyomiChapter
+				if chapter, err = decodeTachiyomiChapter(msg); err == nil {
+					manga.Chapters = append(manga.Chapters, *chapter)
+				}
+			}
+		case field == 17:
+			var orders []int64
+			if orders, err = r.int64s(wire); err == nil {
+				manga.Categories = append(manga.Categories, orders...)
+			}
+		case field == 100 && wire == protoWireVarint:
+			manga.Favorite, err = r.bool()
+		case field == 104 && wire == protoWireBytes:
+			var msg []byte
+			if msg, err = r.bytes(); err == nil {
+				var history *TachiyomiHistory
+				if history, err = decodeTachiyomiHistory(msg); err == nil {
+					manga.History = append(manga.History, *history)
+				}
+			}
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return manga, nil
+}
+
+func decodeTachiyomiChapter(buf []byte) (*TachiyomiChapter, error) {
+	chapter := &TachiyomiChapter{}
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			chapter.URL, err = r.string()
+		case field == 2 && wire == protoWireBytes:
+			chapter.Name, err = r.string()
+		case field == 4 && wire == protoWireVarint:
+			chapter.Read, err = r.bool()
+		case field == 6 && wire == protoWireVarint:
+			chapter.LastPageRead, err = r.int64()
+		case field == 9 && wire == protoWireFixed32:
+			chapter.ChapterNumber, err = r.float32()
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return chapter, nil
+}
+
+func decodeTachiyomiHistory(buf []byte) (*TachiyomiHistory, error) {
+	history := &TachiyomiHistory{}
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			history.URL, err = r.string()
+		case field == 2 && wire == protoWireVarint:
+			history.LastRead, err = r.int64()
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+func decodeTachiyomiCategory(buf []byte) (*TachiyomiCategory, error) {
+	category := &TachiyomiCategory{}
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			category.Name, err = r.string()
+		case field == 2 && wire == protoWireVarint:
+			category.Order, err = r.int64()
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return category, nil
+}
+
+func decodeTachiyomiSource(buf []byte) (int64, string, error) {
+	var id int64
+	var name string
+	r := newProtoReader(buf)
+
+	for !r.done() {
+		field, wire, err := r.next()
+		if err != nil {
+			return 0, "", err
+		}
+
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			name, err = r.string()
+		case field == 2 && wire == protoWireVarint:
+			id, err = r.int64()
+		default:
+			err = r.skip(wire)
+		}
+		if err != nil {
+			return 0, "", err
+		}
+	}
+	return id, strings.TrimSpace(name), nil
+}
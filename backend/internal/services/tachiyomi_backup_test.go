@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// protoMessage builds protobuf wire format by hand, the way kotlinx ProtoBuf writes backups
+type protoMessage []byte
+
+func (m protoMessage) key(field, wire int) protoMessage {
+	return binary.AppendUvarint(m, uint64(field)<<3|uint64(wire))
+}
+
+func (m protoMessage) varint(field int, value uint64) protoMessage {
+	return binary.AppendUvarint(m.key(field, protoWireVarint), value)
+}
+
+func (m protoMessage) bytes(field int, value []byte) protoMessage {
+	m = binary.AppendUvarint(m.key(field, protoWireBytes), uint64(len(value)))
+	return append(m, value...)
+}
+
+func (m protoMessage) string(field int, value string) protoMessage {
+	return m.bytes(field, []byte(value))
+}
+
+func (m protoMessage) float32(field int, value float32) protoMessage {
+	return binary.LittleEndian.AppendUint32(m.key(field, protoWireFixed32), math.Float32bits(value))
+}
+
+func TestParseTachiyomiBackup(t *testing.T) {
+	chapter := protoMessage{}.
+		string(1, "/chapter/one-piece-1").
+		string(2, "Chapter 1").
+		varint(4, 1).
+		varint(6, 17).
+		float32(9, 1)
+	history := protoMessage{}.string(1, "/chapter/one-piece-1").varint(2, 1700000000000)
+	packedCategories := binary.AppendUvarint(binary.AppendUvarint(nil, 0), 2)
+
+	library := protoMessage{}.
+		varint(1, 1234).
+		string(2, "/manga/one-piece").
+		string(3, "One Piece").
+		string(9, "https://example.com/one-piece.jpg").
+		varint(7, 99). // Unread field
+		bytes(16, chapter).
+		bytes(17, packedCategories).
+		bytes(104, history)
+	removed := protoMessage{}.
+		varint(1, 1234).
+		string(2, "/manga/naruto").
+		string(3, "Naruto").
+		varint(100, 0)
+
+	backup := protoMessage{}.
+		bytes(1, library).
+		bytes(1, removed).
+		bytes(2, protoMessage{}.string(1, "Reading").varint(2, 0)).
+		bytes(2, protoMessage{}.string(1, "Done").varint(2, 2)).
+		bytes(101, protoMessage{}.string(1, " Komikindo ").varint(2, 1234))
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(backup)
+	zw.Close()
+
+	for name, data := range map[string][]byte{"plain": backup, "gzip": gzipped.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			parsed, err := ParseTachiyomiBackup(data)
+			if err != nil {
+				t.Fatalf("ParseTachiyomiBackup: %v", err)
+			}
+			if len(parsed.Manga) != 2 {
+				t.Fatalf("got %d manga, want 2", len(parsed.Manga))
+			}
+
+			want := TachiyomiManga{
+				Source:    1234,
+				URL:       "/manga/one-piece",
+				Title:     "One Piece",
+				Thumbnail: "https://example.com/one-piece.jpg",
+				Favorite:  true, // Field 100 missing: the default
+				Chapters: []TachiyomiChapter{
+					{URL: "/chapter/one-piece-1", Name: "Chapter 1", Read: true, LastPageRead: 17, ChapterNumber: 1},
+				},
+				Categories: []int64{0, 2},
+				History:    []TachiyomiHistory{{URL: "/chapter/one-piece-1", LastRead: 1700000000000}},
+			}
+			if !reflect.DeepEqual(parsed.Manga[0], want) {
+				t.Errorf("manga = %+v, want %+v", parsed.Manga[0], want)
+			}
+			if parsed.Manga[1].Title != "Naruto" || parsed.Manga[1].Favorite {
+				t.Errorf("manga = %+v, want Naruto with favorite=false", parsed.Manga[1])
+			}
+
+			if got := parsed.SourceName(1234); got != "Komikindo" {
+				t.Errorf("SourceName = %q, want Komikindo", got)
+			}
+			if got := parsed.CategoryNames(parsed.Manga[0].Categories); !reflect.DeepEqual(got, []string{"Reading", "Done"}) {
+				t.Errorf("CategoryNames = %v, want [Reading Done]", got)
+			}
+		})
+	}
+}
+
+func TestParseTachiyomiBackupRejectsInvalidData(t *testing.T) {
+	manga := protoMessage{}.string(3, "One Piece")
+	truncated := protoMessage{}.bytes(1, manga)
+	truncated = truncated[:len(truncated)-2]
+
+	for name, data := range map[string][]byte{
+		"empty":     {},
+		"no manga":  protoMessage{}.bytes(2, protoMessage{}.string(1, "Reading")),
+		"truncated": truncated,
+		"gzip":      {0x1f, 0x8b, 0x00},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseTachiyomiBackup(data); !errors.Is(err, ErrInvalidBackup) {
+				t.Fatalf("ParseTachiyomiBackup = %v, want ErrInvalidBackup", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// queuedBackup is what an ImportQueue job keeps of a Tachiyomi backup
+type queuedBackup struct {
+	Backup  *TachiyomiBackup `json:"backup"`
+	Skipped int              `json:"skipped"` // Non-library entries (history only)
+}
+
+// ImportTachiyomi queues the library manga of a Tachiyomi/Mihon backup; ImportQueue
+// bookmarks them and marks their read chapters in the background
+func (s *ListTransferService) ImportTachiyomi(userID int, backup *TachiyomiBackup, dryRun bool) (*models.ImportJob, error) {
+	queued := &TachiyomiBackup{Categories: backup.Categories, Sources: backup.Sources}
+	for _, manga := range backup.Manga {
+		if manga.Favorite {
+			queued.Manga = append(queued.Manga, compactBackupManga(manga))
+		}
+	}
+	if len(queued.Manga) == 0 {
+		return nil, ErrNothingToImport
+	}
+	if len(queued.Manga) > maxImportEntries {
+		return nil, ErrTooManyEntries
+	}
+
+	payload := queuedBackup{Backup: queued, Skipped: len(backup.Manga) - len(queued.Manga)}
+	return GetImportQueue().submit(userID, ImportFormatTachiyomi, dryRun, len(queued.Manga), payload)
+}
+
+// compactBackupManga drops the chapters mapBackupChapters doesn't look at (unread and
+// never opened), which is most of a big backup
+func compactBackupManga(manga TachiyomiManga) TachiyomiManga {
+	opened := make(map[string]bool, len(manga.History))
+	for _, h := range manga.History {
+		opened[h.URL] = true
+	}
+
+	chapters := make([]TachiyomiChapter, 0, len(manga.Chapters))
+	for _, chapter := range manga.Chapters {
+		if chapter.Read || opened[chapter.URL] {
+			chapter.Name = ""
+			chapters = append(chapters, chapter)
+		}
+	}
+	manga.Chapters = chapters
+	return manga
+}
+
+// matchBackup bookmarks every library manga of a backup and marks its read chapters.
+// Komikindo entries are mapped by URL, other sources by title search.
+func (s *ListTransferService) matchBackup(run *importRun, userID int, queued queuedBackup, dryRun bool) *models.BackupImportReport {
+	backup := queued.Backup
+	report := &models.BackupImportReport{
+		ImportReport: models.ImportReport{
+			Format: ImportFormatTachiyomi,
+			DryRun: dryRun,
+			Total:  len(backup.Manga),
+		},
+		Categories: []string{},
+		Skipped:    queued.Skipped,
+	}
+	for _, category := range backup.Categories {
+		report.Categories = append(report.Categories, category.Name)
+	}
+
+	report.Entries = run.each(len(backup.Manga), func(i int) (models.ImportEntryResult, error) {
+		return s.importBackupManga(userID, backup, backup.Manga[i], dryRun)
+	}, func(i int) models.ImportEntryResult {
+		return abortedImportEntry("manga", backup.Manga[i].Title)
+	})
+
+	countImportResults(&report.ImportReport)
+	for _, entry := range report.Entries {
+		report.ChaptersRead += entry.ChaptersRead
+	}
+
+	fmt.Printf("[ListImport] User %d imported Tachiyomi backup: %d matched, %d ambiguous, %d unmatched, %d failed, %d chapters read\n",
+		userID, report.Matched, report.Ambiguous, report.Unmatched, report.Failed, report.ChaptersRead)
+	return report
+}
+
+// importBackupManga also returns the upstream error an entry failed on, so throttled
+// entries can be tried again. Only a Komikindo slug the source doesn't know is unmatched;
+// transport and rate limit errors are failures.
+func (s *ListTransferService) importBackupManga(userID int, backup *TachiyomiBackup, manga TachiyomiManga, dryRun bool) (models.ImportEntryResult, error) {
+	result := models.ImportEntryResult{
+		Type:       "manga",
+		Title:      manga.Title,
+		Source:     backup.SourceName(manga.Source),
+		Categories: backup.CategoryNames(manga.Categories),
+	}
+
+	var slug, title, cover string
+	var chapters []models.Chapter
+
+	if isKomikindoSource(result.Source, manga.URL) {
+		slug = lastPathSegment(manga.URL)
+		provider, err := s.Providers.Manga(ProviderKomikindo)
+		if err != nil {
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+			return result, nil
+		}
+		detail, err := provider.GetMangaDetail(slug)
+		var status *UpstreamStatusError
+		switch {
+		case errors.As(err, &status) && status.StatusCode == http.StatusNotFound,
+			err == nil && detail.Title == "" && len(detail.Chapters) == 0:
+			result.Result = models.ImportUnmatched
+			result.Error = fmt.Sprintf("komikindo has no manga '%s'", slug)
+			return result, nil
+		case err != nil:
+			result.Result = models.ImportFailed
+			result.Error = fmt.Sprintf("komikindo slug '%s': %v", slug, err)
+			return result, err
+		}
+		title, cover, chapters = detail.Title, detail.Image, detail.Chapters
+	} else {
+		match, candidates, err := s.matchTitle(models.ListEntry{Type: "manga", Title: manga.Title})
+		switch {
+		case err != nil:
+			result.Result = models.ImportFailed
+			result.Error = err.Error()
+			return result, err
+		case match == nil && len(candidates) > 0:
+			result.Result = models.ImportAmbiguous
+			result.Candidates = candidates
+			return result, nil
+		case match == nil:
+			result.Result = models.ImportUnmatched
+			return result, nil
+		}
+
+		slug, title, cover = match.Slug, match.Title, match.Cover
+		if provider, err := s.Providers.Manga(""); err == nil {
+			detail, err := provider.GetMangaDetail(slug)
+			var limited *RateLimitError
+			if errors.As(err, &limited) {
+				// Without the chapter list read chapters would be mapped by URL alone
+				result.Result = models.ImportFailed
+				result.Error = err.Error()
+				return result, err
+			}
+			if err == nil {
+				chapters = detail.Chapters
+			}
+		}
+	}
+	if title == "" {
+		title = manga.Title
+	}
+	if cover == "" {
+		cover = manga.Thumbnail
+	}
+	result.Result = models.ImportMatched
+	result.Slug = slug
+	result.MatchedTitle = title
+
+	readSlugs, last := mapBackupChapters(manga, chapters)
+	result.ChaptersRead = len(readSlugs)
+	if dryRun {
+		return result, nil
+	}
+
+	bookmark := models.Bookmark{UserID: userID, Type: "manga", Slug: slug, Title: title, CoverImage: cover,
+		Tags: categoryTags(result.Categories)}
+	if len(readSlugs) > 0 {
+		bookmark.Status = ListStatusCurrent
+	}
+	err := s.Bookmarks.AddBookmark(bookmark)
+	if err == nil && len(readSlugs) > 0 {
+		err = s.Reading.SetChaptersRead(userID, slug, readSlugs, true)
+	}
+	if err == nil && last != nil {
+		_, err = s.Reading.SaveProgress(userID, models.ReadingProgressRequest{
+			MangaSlug:   slug,
+			ChapterSlug: last.slug,
+			PageIndex:   int(last.page),
+			MangaTitle:  title,
+			CoverImage:  cover,
+		})
+	}
+	if err != nil {
+		result.Result = models.ImportFailed
+		result.Error = err.Error()
+	}
+	return result, nil
+}
+
+// categoryTags turns category names into tags AddBookmark accepts: names longer than a tag
+// are cut short and only the first maxBookmarkTags are kept, instead of the whole manga
+// failing on a category the app allows but bookmarks don't
+func categoryTags(categories []string) []string {
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		tag := normalizeTag(category)
+		if len(tag) > maxTagLength {
+			tag = strings.TrimSpace(strings.ToValidUTF8(tag[:maxTagLength], ""))
+		}
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tags) == maxBookmarkTags {
+			break
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+type backupPosition struct {
+	slug string
+	page int64
+}
+
+// mapBackupChapters converts the backup's read chapters to our chapter slugs (by URL,
+// then by chapter number) and finds the last chapter opened according to the history.
+// Without our chapter list the URL's last segment is used as is.
+func mapBackupChapters(manga TachiyomiManga, chapters []models.Chapter) ([]string, *backupPosition) {
+	bySlug := make(map[string]bool, len(chapters))
+	byNumber := make(map[float64]string, len(chapters))
+	for _, ch := range chapters {
+		bySlug[ChapterSlug(ch)] = true
+		if number, ok := chapterNumber(ch); ok {
+			byNumber[number] = ChapterSlug(ch)
+		}
+	}
+
+	resolve := func(chapter TachiyomiChapter) string {
+		segment := lastPathSegment(chapter.URL)
+		if len(chapters) == 0 || bySlug[segment] {
+			return segment
+		}
+		if chapter.ChapterNumber >= 0 {
+			// float32 -> float64 keeps .5 exact, which is what chapter numbers use
+			return byNumber[float64(chapter.ChapterNumber)]
+		}
+		return ""
+	}
+
+	var read []string
+	byURL := make(map[string]TachiyomiChapter, len(manga.Chapters))
+	var latestRead *TachiyomiChapter
+	for i, chapter := range manga.Chapters {
+		byURL[chapter.URL] = chapter
+		if !chapter.Read {
+			continue
+		}
+		if slug := resolve(chapter); slug != "" {
+			read = append(read, slug)
+		}
+		if latestRead == nil || chapter.ChapterNumber > latestRead.ChapterNumber {
+			latestRead = &manga.Chapters[i]
+		}
+	}
+
+	// Last position: most recent history entry, else the highest read chapter
+	history := append([]TachiyomiHistory(nil), manga.History...)
+	sort.Slice(history, func(i, j int) bool { return history[i].LastRead > history[j].LastRead })
+	for _, h := range history {
+		if chapter, ok := byURL[h.URL]; ok {
+			if slug := resolve(chapter); slug != "" {
+				return read, &backupPosition{slug: slug, page: chapter.LastPageRead}
+			}
+		}
+	}
+	if latestRead != nil {
+		if slug := resolve(*latestRead); slug != "" {
+			return read, &backupPosition{slug: slug, page: latestRead.LastPageRead}
+		}
+	}
+	return read, nil
+}
+
+// isKomikindoSource matches the Komikindo extension by name, or by its "/komik/<slug>/" URLs
+// when the backup has no source list
+func isKomikindoSource(sourceName string, mangaURL string) bool {
+	if sourceName != "" {
+		return strings.Contains(strings.ToLower(sourceName), "komikindo")
+	}
+	return strings.Contains(mangaURL, "/komik/")
+}
+
+func lastPathSegment(rawURL string) string {
+	return path.Base("/" + strings.Trim(rawURL, "/"))
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingDB is a database/sql driver that records Exec calls and has no rows
+type recordingDB struct {
+	mu      sync.Mutex
+	records []recordedExec
+}
+
+type recordedExec struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *recordingDB) execs() []recordedExec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]recordedExec(nil), d.records...)
+}
+
+var (
+	recordingDriver         = &recordingDrivers{dbs: make(map[string]*recordingDB)}
+	registerRecordingDriver sync.Once
+)
+
+type recordingDrivers struct {
+	mu  sync.Mutex
+	dbs map[string]*recordingDB
+}
+
+func (d *recordingDrivers) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return recordingConn{d.dbs[name]}, nil
+}
+
+type recordingConn struct{ db *recordingDB }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.db, query}, nil
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+type recordingStmt struct {
+	db    *recordingDB
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	s.db.records = append(s.db.records, recordedExec{strings.TrimSpace(s.query), args})
+	s.db.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("queries not supported")
+}
+
+// useRecordingDB points database.DB at a new recordingDB for the test
+func useRecordingDB(t *testing.T) *recordingDB {
+	t.Helper()
+	registerRecordingDriver.Do(func() { sql.Register("recording", recordingDriver) })
+
+	db := &recordingDB{}
+	recordingDriver.mu.Lock()
+	recordingDriver.dbs[t.Name()] = db
+	recordingDriver.mu.Unlock()
+
+	conn, err := sql.Open("recording", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = conn
+	t.Cleanup(func() {
+		database.DB = previous
+		conn.Close()
+	})
+	return db
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"anime-tanyaayomi/internal/database"
 	"anime-tanyaayomi/internal/models"
+	"database/sql"
 	"errors"
 
 	"golang.org/x/crypto/bcrypt"
@@ -42,3 +43,13 @@ func (s *UserService) Login(req models.LoginRequest) (*models.User, error) {
 
 	return &user, nil
 }
+
+// IsAdmin reports whether the user may use the /api/admin routes
+func (s *UserService) IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	err := database.DB.QueryRow(`SELECT is_admin FROM users WHERE id = $1`, userID).Scan(&isAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return isAdmin, err
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An episode counts as watched once 90% of it has been played
+const watchedThreshold = 0.9
+
+const maxContinueWatching = 20
+
+var ErrInvalidProgress = errors.New("invalid progress")
+
+type WatchHistoryService struct {
+	Anime *SankavollereiService
+}
+
+func NewWatchHistoryService(anime *SankavollereiService) *WatchHistoryService {
+	return &WatchHistoryService{Anime: anime}
+}
+
+// SaveProgress upserts the user's position in an episode. A completed episode stays completed.
+func (s *WatchHistoryService) SaveProgress(userID int, req models.ProgressRequest) (*models.WatchProgress, error) {
+	if req.AnimeSlug == "" || req.EpisodeSlug == "" {
+		return nil, fmt.Errorf("%w: anime_slug and episode_slug are required", ErrInvalidProgress)
+	}
+	if req.PositionSeconds < 0 || req.DurationSeconds < 0 {
+		return nil, fmt.Errorf("%w: position_seconds and duration_seconds must not be negative", ErrInvalidProgress)
+	}
+	if req.DurationSeconds > 0 && req.PositionSeconds > req.DurationSeconds {
+		req.PositionSeconds = req.DurationSeconds
+	}
+
+	completed := req.Completed ||
+		(req.DurationSeconds > 0 && float64(req.PositionSeconds) >= float64(req.DurationSeconds)*watchedThreshold)
+
+	query := `INSERT INTO watch_history (user_id, anime_slug, episode_slug, anime_title, episode_title, cover_image,
+                  position_seconds, duration_seconds, completed, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+              ON CONFLICT (user_id, episode_slug) DO UPDATE SET
+                  anime_slug = EXCLUDED.anime_slug,
+                  anime_title = COALESCE(NULLIF(EXCLUDED.anime_title, ''), watch_history.anime_title),
+                  episode_title = COALESCE(NULLIF(EXCLUDED.episode_title, ''), watch_history.episode_title),
+                  cover_image = COALESCE(NULLIF(EXCLUDED.cover_image, ''), watch_history.cover_image),
+                  position_seconds = EXCLUDED.position_seconds,
+                  duration_seconds = GREATEST(EXCLUDED.duration_seconds, watch_history.duration_seconds),
+                  completed = watch_history.completed OR EXCLUDED.completed,
+                  updated_at = NOW()
+              RETURNING id, user_id, anime_slug, episode_slug, COALESCE(anime_title, ''), COALESCE(episode_title, ''),
+                  COALESCE(cover_image, ''), position_seconds, duration_seconds, completed, updated_at`
+
+	var p models.WatchProgress
+	err := database.DB.QueryRow(query, userID, req.AnimeSlug, req.EpisodeSlug, req.AnimeTitle, req.EpisodeTitle,
+		req.CoverImage, req.PositionSeconds, req.DurationSeconds, completed).
+		Scan(&p.ID, &p.UserID, &p.AnimeSlug, &p.EpisodeSlug, &p.AnimeTitle, &p.EpisodeTitle,
+			&p.CoverImage, &p.PositionSeconds, &p.DurationSeconds, &p.Completed, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetHistory returns the user's most recently watched episodes
+func (s *WatchHistoryService) GetHistory(userID int, limit int) ([]models.WatchProgress, error) {
+	query := `SELECT id, user_id, anime_slug, episode_slug, COALESCE(anime_title, ''), COALESCE(episode_title, ''),
+                  COALESCE(cover_image, ''), position_seconds, duration_seconds, completed, updated_at
+              FROM watch_history WHERE user_id = $1 ORDER BY updated_at DESC LIMIT $2`
+	return queryWatchProgress(query, userID, limit)
+}
+
+// GetAnimeProgress returns progress for every episode of one anime the user started
+func (s *WatchHistoryService) GetAnimeProgress(userID int, animeSlug string) ([]models.WatchProgress, error) {
+	query := `SELECT id, user_id, anime_slug, episode_slug, COALESCE(anime_title, ''), COALESCE(episode_title, ''),
+                  COALESCE(cover_image, ''), position_seconds, duration_seconds, completed, updated_at
+              FROM watch_history WHERE user_id = $1 AND anime_slug = $2 ORDER BY updated_at DESC`
+	return queryWatchProgress(query, userID, animeSlug)
+}
+
+// RemoveProgress deletes the user's progress for one episode
+func (s *WatchHistoryService) RemoveProgress(userID int, episodeSlug string) error {
+	query := `DELETE FROM watch_history WHERE user_id = $1 AND episode_slug = $2`
+	_, err := database.DB.Exec(query, userID, episodeSlug)
+	return err
+}
+
+// ContinueWatching returns, per series (most recent first), either the unfinished
+// episode to resume or the next unwatched episode from the anime's EpisodeList.
+// Series the user has caught up on are left out.
+func (s *WatchHistoryService) ContinueWatching(userID int) ([]models.ContinueWatchingItem, error) {
+	// Latest row per anime
+	query := `SELECT * FROM (
+                  SELECT DISTINCT ON (anime_slug) id, user_id, anime_slug, episode_slug, COALESCE(anime_title, ''),
+                      COALESCE(episode_title, ''), COALESCE(cover_image, ''), position_seconds, duration_seconds,
+                      completed, updated_at
+                  FROM watch_history WHERE user_id = $1
+                  ORDER BY anime_slug, updated_at DESC
+              ) latest ORDER BY updated_at DESC LIMIT $2`
+	latest, err := queryWatchProgress(query, userID, maxContinueWatching)
+	if err != nil {
+		return nil, err
+	}
+
+	watched, err := s.completedEpisodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Episode lists come from the (cached) anime detail, fetched in parallel
+	items := make([]*models.ContinueWatchingItem, len(latest))
+	var wg sync.WaitGroup
+	for i := range latest {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			items[i] = s.nextEpisode(latest[i], watched[latest[i].AnimeSlug])
+		}(i)
+	}
+	wg.Wait()
+
+	result := []models.ContinueWatchingItem{}
+	for _, item := range items {
+		if item != nil {
+			result = append(result, *item)
+		}
+	}
+	return result, nil
+}
+
+// completedEpisodes maps anime slug -> set of watched episode slugs
+func (s *WatchHistoryService) completedEpisodes(userID int) (map[string]map[string]bool, error) {
+	rows, err := database.DB.Query(`SELECT anime_slug, episode_slug FROM watch_history WHERE user_id = $1 AND completed`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	watched := make(map[string]map[string]bool)
+	for rows.Next() {
+		var animeSlug, episodeSlug string
+		if err := rows.Scan(&animeSlug, &episodeSlug); err != nil {
+			return nil, err
+		}
+		if watched[animeSlug] == nil {
+			watched[animeSlug] = make(map[string]bool)
+		}
+		watched[animeSlug][episodeSlug] = true
+	}
+	return watched, rows.Err()
+}
+
+func (s *WatchHistoryService) nextEpisode(last models.WatchProgress, watched map[string]bool) *models.ContinueWatchingItem {
+	item := &models.ContinueWatchingItem{
+		AnimeSlug:     last.AnimeSlug,
+		AnimeTitle:    last.AnimeTitle,
+		CoverImage:    last.CoverImage,
+		LastWatchedAt: last.UpdatedAt,
+	}
+
+	// Unfinished episode: resume where the player stopped
+	if !last.Completed {
+		item.EpisodeSlug = last.EpisodeSlug
+		item.EpisodeTitle = last.EpisodeTitle
+		item.PositionSeconds = last.PositionSeconds
+		item.DurationSeconds = last.DurationSeconds
+		item.Resume = true
+		return item
+	}
+
+	detail, err := s.Anime.GetAnimeDetail(last.AnimeSlug)
+	if err != nil {
+		fmt.Printf("[WatchHistory] ⚠️  Could not load episodes for %s: %v\n", last.AnimeSlug, err)
+		return nil
+	}
+	if item.AnimeTitle == "" {
+		item.AnimeTitle = detail.Data.Title
+	}
+	if item.CoverImage == "" {
+		item.CoverImage = detail.Data.Poster
+	}
+
+	episodes := OrderEpisodes(detail.Data.EpisodeList)
+	start := 0
+	for i, ep := range episodes {
+		if EpisodeSlug(ep) == last.EpisodeSlug {
+			start = i + 1
+			break
+		}
+	}
+
+	for _, ep := range episodes[start:] {
+		slug := EpisodeSlug(ep)
+		if slug == "" || watched[slug] {
+			continue
+		}
+		item.EpisodeSlug = slug
+		item.EpisodeTitle = ep.Title
+		return item
+	}
+
+	// Caught up
+	return nil
+}
+
+// EpisodeSlug returns the slug used by /anime/episode/:slug
+func EpisodeSlug(ep models.Episode) string {
+	if ep.Slug != "" {
+		return ep.Slug
+	}
+	return ep.EpisodeID
+}
+
+// "episode-12" or "episode-12-5" (a half/recap episode, 12.5) in Otakudesu slugs
+var episodeNumberPattern = regexp.MustCompile(`episode-(\d+(?:-\d+)?)`)
+
+// OrderEpisodes returns the list from first to last episode. Episode numbers are
+// taken from the slug; without them the upstream (newest first) order is reversed.
+func OrderEpisodes(list []models.Episode) []models.Episode {
+	ordered := make([]models.Episode, len(list))
+	numbers := make(map[string]float64, len(list))
+
+	for _, ep := range list {
+		number, ok := episodeNumber(ep)
+		if !ok {
+			// No number on some entry: fall back to reversing the upstream order
+			for i := range list {
+				ordered[len(list)-1-i] = list[i]
+			}
+			return ordered
+		}
+		numbers[EpisodeSlug(ep)] = number
+	}
+
+	copy(ordered, list)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return numbers[EpisodeSlug(ordered[i])] < numbers[EpisodeSlug(ordered[j])]
+	})
+	return ordered
+}
+
+func episodeNumber(ep models.Episode) (float64, bool) {
+	matches := episodeNumberPattern.FindStringSubmatch(EpisodeSlug(ep))
+	if len(matches) < 2 {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(matches[1], "-", "."), 64)
+	return number, err == nil
+}
+
+func queryWatchProgress(query string, args ...interface{}) ([]models.WatchProgress, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.WatchProgress{}
+	for rows.Next() {
+		var p models.WatchProgress
+		if err := rows.Scan(&p.ID, &p.UserID, &p.AnimeSlug, &p.EpisodeSlug, &p.AnimeTitle, &p.EpisodeTitle,
+			&p.CoverImage, &p.PositionSeconds, &p.DurationSeconds, &p.Completed, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, p)
+	}
+	return history, rows.Err()
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"reflect"
+	"testing"
+)
+
+func episodeSlugs(list []models.Episode) []string {
+	slugs := make([]string, len(list))
+	for i, ep := range list {
+		slugs[i] = EpisodeSlug(ep)
+	}
+	return slugs
+}
+
+func TestOrderEpisodes(t *testing.T) {
+	tests := []struct {
+		name string
+		list []models.Episode
+		want []string
+	}{
+		{
+			name: "newest first",
+			list: []models.Episode{
+				{Slug: "frieren-episode-3-sub-indo"},
+				{Slug: "frieren-episode-2-sub-indo"},
+				{Slug: "frieren-episode-1-sub-indo"},
+			},
+			want: []string{"frieren-episode-1-sub-indo", "frieren-episode-2-sub-indo", "frieren-episode-3-sub-indo"},
+		},
+		{
+			name: "numeric not lexical",
+			list: []models.Episode{
+				{Slug: "op-episode-10"},
+				{Slug: "op-episode-9"},
+				{Slug: "op-episode-100"},
+				{Slug: "op-episode-1"},
+			},
+			want: []string{"op-episode-1", "op-episode-9", "op-episode-10", "op-episode-100"},
+		},
+		{
+			name: "half episode",
+			list: []models.Episode{
+				{Slug: "op-episode-13"},
+				{Slug: "op-episode-12-5"},
+				{Slug: "op-episode-12"},
+			},
+			want: []string{"op-episode-12", "op-episode-12-5", "op-episode-13"},
+		},
+		{
+			name: "episode id without slug",
+			list: []models.Episode{
+				{EpisodeID: "op-episode-2"},
+				{EpisodeID: "op-episode-1"},
+			},
+			want: []string{"op-episode-1", "op-episode-2"},
+		},
+		{
+			name: "missing number reverses upstream order",
+			list: []models.Episode{
+				{Slug: "op-episode-2"},
+				{Slug: "op-special"},
+				{Slug: "op-episode-1"},
+			},
+			want: []string{"op-episode-1", "op-special", "op-episode-2"},
+		},
+		{
+			name: "empty",
+			list: nil,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := episodeSlugs(OrderEpisodes(tt.list)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OrderEpisodes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
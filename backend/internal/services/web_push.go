@@ -0,0 +1,331 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webPushRecordSize = 4096
+	// Encrypted body = 86 byte header + payload + delimiter + 16 byte tag, within one 4096 byte record
+	MaxWebPushPayload = webPushRecordSize - 86 - 1 - 16
+	webPushTTL        = 24 * time.Hour
+	vapidTokenTTL     = 12 * time.Hour
+)
+
+var (
+	ErrPushSubscriptionGone = errors.New("push subscription expired or unsubscribed")
+	ErrPushPayloadTooLarge  = fmt.Errorf("push payload is limited to %d bytes", MaxWebPushPayload)
+)
+
+// VAPIDKeys is the application server key pair (RFC 8292). Browsers bind subscriptions to
+// the public key, so it must stay the same across restarts.
+type VAPIDKeys struct {
+	Private *ecdh.PrivateKey
+	signer  *ecdsa.PrivateKey
+}
+
+// PublicKey returns the uncompressed public key, base64url encoded (the applicationServerKey)
+func (k *VAPIDKeys) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.Private.PublicKey().Bytes())
+}
+
+// PrivateKey returns the raw private scalar, base64url encoded (VAPID_PRIVATE_KEY format)
+func (k *VAPIDKeys) PrivateKey() string {
+	return base64.RawURLEncoding.EncodeToString(k.Private.Bytes())
+}
+
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newVAPIDKeys(private), nil
+}
+
+// ParseVAPIDKeys reads a base64url private key (the public key is derived from it)
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	private, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	return newVAPIDKeys(private), nil
+}
+
+func newVAPIDKeys(private *ecdh.PrivateKey) *VAPIDKeys {
+	public := private.PublicKey().Bytes() // 0x04 || X || Y
+	signer := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:65]),
+		},
+		D: new(big.Int).SetBytes(private.Bytes()),
+	}
+	return &VAPIDKeys{Private: private, signer: signer}
+}
+
+// authorization builds the "vapid t=<jwt>, k=<public key>" header for a push service origin
+func (k *VAPIDKeys) authorization(audience string, subject string, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": audience,
+		"exp": now.Add(vapidTokenTTL).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, k.signer, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS ES256 signatures are r || s, 32 bytes each
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, k.PublicKey()), nil
+}
+
+// WebPushSender encrypts (RFC 8291, aes128gcm) and delivers push messages
+type WebPushSender struct {
+	Keys    *VAPIDKeys
+	Subject string // mailto: or https: contact for push services
+	Client  *http.Client
+	// AllowPrivateEndpoints accepts http:// and private-network endpoints, for a local fake push service
+	AllowPrivateEndpoints bool
+}
+
+var (
+	webPushSender     *WebPushSender
+	webPushSenderErr  error
+	webPushSenderOnce sync.Once
+)
+
+// GetWebPushSender returns the shared sender. Keys come from VAPID_PRIVATE_KEY, otherwise
+// they are generated once and stored in the database. VAPID_SUBJECT is the contact URI;
+// WEB_PUSH_ALLOW_PRIVATE_ENDPOINTS=true allows local (fake) push services.
+func GetWebPushSender() (*WebPushSender, error) {
+	webPushSenderOnce.Do(func() {
+		var keys *VAPIDKeys
+		if privateKey := os.Getenv("VAPID_PRIVATE_KEY"); privateKey != "" {
+			keys, webPushSenderErr = ParseVAPIDKeys(privateKey)
+		} else {
+			keys, webPushSenderErr = loadStoredVAPIDKeys()
+		}
+		if webPushSenderErr != nil {
+			fmt.Printf("[WebPush] ⚠️  VAPID keys unavailable: %v\n", webPushSenderErr)
+			return
+		}
+
+		subject := os.Getenv("VAPID_SUBJECT")
+		if subject == "" {
+			subject = "mailto:admin@localhost"
+			fmt.Println("[WebPush] ⚠️  VAPID_SUBJECT not set, push services may reject messages")
+		}
+
+		allowPrivate := os.Getenv("WEB_PUSH_ALLOW_PRIVATE_ENDPOINTS") == "true"
+		client := newPublicHTTPClient(30 * time.Second)
+		if allowPrivate {
+			client = &http.Client{Timeout: 30 * time.Second}
+		}
+		webPushSender = &WebPushSender{Keys: keys, Subject: subject, Client: client, AllowPrivateEndpoints: allowPrivate}
+	})
+	return webPushSender, webPushSenderErr
+}
+
+// loadStoredVAPIDKeys reads the key pair from web_push_vapid_keys, creating it on first use.
+// Concurrent first starts agree on whichever row was inserted first.
+func loadStoredVAPIDKeys() (*VAPIDKeys, error) {
+	generated, err := GenerateVAPIDKeys()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := database.DB.Exec(`INSERT INTO web_push_vapid_keys (id, private_key) VALUES (TRUE, $1)
+              ON CONFLICT (id) DO NOTHING`, generated.PrivateKey()); err != nil {
+		return nil, err
+	}
+
+	var privateKey string
+	if err := database.DB.QueryRow(`SELECT private_key FROM web_push_vapid_keys WHERE id`).Scan(&privateKey); err != nil {
+		return nil, err
+	}
+	return ParseVAPIDKeys(privateKey)
+}
+
+// CheckEndpoint validates a subscription endpoint before we store it or post to it
+func (s *WebPushSender) CheckEndpoint(endpoint string) error {
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Host == "" {
+		return errors.New("invalid push endpoint")
+	}
+	if s.AllowPrivateEndpoints {
+		if target.Scheme != "https" && target.Scheme != "http" {
+			return errors.New("push endpoint must be an http(s) url")
+		}
+		return nil
+	}
+	if target.Scheme != "https" {
+		return errors.New("push endpoint must use https")
+	}
+	return CheckPublicURL(target)
+}
+
+// Send encrypts payload for the subscription and posts it to the push service.
+// 404/410 responses return ErrPushSubscriptionGone so the caller can drop the subscription.
+func (s *WebPushSender) Send(subscription models.PushSubscription, payload []byte) error {
+	if len(payload) > MaxWebPushPayload {
+		return ErrPushPayloadTooLarge
+	}
+	if err := s.CheckEndpoint(subscription.Endpoint); err != nil {
+		return err
+	}
+	target, _ := url.Parse(subscription.Endpoint)
+
+	body, err := EncryptWebPushPayload(payload, subscription.P256dh, subscription.Auth)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.Keys.authorization(target.Scheme+"://"+target.Host, s.Subject, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrPushSubscriptionGone
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// EncryptWebPushPayload encrypts payload for a subscription's p256dh key and auth secret
+// (both base64url) as a single aes128gcm record (RFC 8291 / RFC 8188)
+func EncryptWebPushPayload(payload []byte, p256dh string, auth string) ([]byte, error) {
+	userAgentKeyBytes, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	userAgentKey, err := ecdh.P256().NewPublicKey(userAgentKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("invalid auth secret")
+	}
+
+	serverKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptWebPush(payload, userAgentKey, authSecret, serverKey, salt)
+}
+
+// encryptWebPush does the RFC 8291 key derivation and encryption with a given ephemeral key and salt
+func encryptWebPush(payload []byte, userAgentKey *ecdh.PublicKey, authSecret []byte, serverKey *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	sharedSecret, err := serverKey.ECDH(userAgentKey)
+	if err != nil {
+		return nil, err
+	}
+	userAgentKeyBytes := userAgentKey.Bytes()
+	serverPublic := serverKey.PublicKey().Bytes()
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentKeyBytes...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm := hkdfSHA256(authSecret, sharedSecret, keyInfo, 32)
+
+	contentKey := hkdfSHA256(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfSHA256(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt(16) || record size(4) || key id length(1) || key id (server public key)
+	header := make([]byte, 0, 86)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+
+	plaintext := append(append([]byte(nil), payload...), 0x02) // 0x02 = last record
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// hkdfSHA256 is HKDF extract + expand for outputs of at most one SHA-256 block
+func hkdfSHA256(salt, secret, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write(info)
+	expand.Write([]byte{0x01})
+	return expand.Sum(nil)[:length]
+}
+
+// Browsers hand out keys as unpadded base64url, some libraries pad them
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// pushSubscriber is the browser side of a subscription: its key pair and auth secret
+type pushSubscriber struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newPushSubscriber(t *testing.T) *pushSubscriber {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+	return &pushSubscriber{key: key, auth: auth}
+}
+
+func (s *pushSubscriber) subscription(id int, endpoint string) models.PushSubscription {
+	return models.PushSubscription{
+		ID:       id,
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(s.key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(s.auth),
+	}
+}
+
+// decrypt reverses RFC 8291 the way a browser does, using only the subscriber's keys
+func (s *pushSubscriber) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	if len(body) < 21 {
+		t.Fatalf("body is %d bytes, too short for an aes128gcm header", len(body))
+	}
+	salt := body[:16]
+	recordSize := binary.BigEndian.Uint32(body[16:20])
+	keyIDLength := int(body[20])
+	if recordSize != 4096 || keyIDLength != 65 || len(body) < 21+keyIDLength {
+		t.Fatalf("unexpected header: record size %d, key id length %d", recordSize, keyIDLength)
+	}
+	serverPublicBytes := body[21 : 21+keyIDLength]
+	ciphertext := body[21+keyIDLength:]
+
+	serverPublic, err := ecdh.P256().NewPublicKey(serverPublicBytes)
+	if err != nil {
+		t.Fatalf("key id is not a P-256 public key: %v", err)
+	}
+	sharedSecret, err := s.key.ECDH(serverPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), s.key.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, serverPublicBytes...)
+	ikm := mustHKDF(t, sharedSecret, s.auth, string(keyInfo), 32)
+	contentKey := mustHKDF(t, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce := mustHKDF(t, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt record: %v", err)
+	}
+
+	// Strip the padding: the last non-zero byte is the delimiter, 0x02 for the last record
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("record does not end with the last-record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func mustHKDF(t *testing.T, secret, salt []byte, info string, length int) []byte {
+	t.Helper()
+	key, err := hkdf.Key(sha256.New, secret, salt, info, length)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptWebPushPayloadRoundTrip(t *testing.T) {
+	subscriber := newPushSubscriber(t)
+	subscription := subscriber.subscription(1, "https://push.example.com/send/abc")
+
+	for _, payload := range [][]byte{[]byte(`{"title":"Episode 12 is out"}`), {}, bytes.Repeat([]byte("x"), MaxWebPushPayload)} {
+		body, err := EncryptWebPushPayload(payload, subscription.P256dh, subscription.Auth)
+		if err != nil {
+			t.Fatalf("encrypt %d bytes: %v", len(payload), err)
+		}
+		if len(body) > webPushRecordSize {
+			t.Fatalf("encrypted body is %d bytes, more than one %d byte record", len(body), webPushRecordSize)
+		}
+		if got := subscriber.decrypt(t, body); !bytes.Equal(got, payload) {
+			t.Fatalf("decrypted %q, want %q", got, payload)
+		}
+	}
+
+	// Every message uses a fresh salt and server key
+	first, _ := EncryptWebPushPayload([]byte("same"), subscription.P256dh, subscription.Auth)
+	second, _ := EncryptWebPushPayload([]byte("same"), subscription.P256dh, subscription.Auth)
+	if bytes.Equal(first[:86], second[:86]) {
+		t.Fatal("two messages share a salt and server key")
+	}
+}
+
+func TestEncryptWebPushPayloadRejectsBadKeys(t *testing.T) {
+	subscription := newPushSubscriber(t).subscription(1, "https://push.example.com/send/abc")
+
+	if _, err := EncryptWebPushPayload([]byte("x"), "not-a-key", subscription.Auth); err == nil {
+		t.Error("accepted an invalid p256dh key")
+	}
+	if _, err := EncryptWebPushPayload([]byte("x"), subscription.P256dh, "c2hvcnQ"); err == nil {
+		t.Error("accepted an auth secret that isn't 16 bytes")
+	}
+}
+
+// pushServer is a fake push service that records what it receives
+type pushServer struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newPushServer(t *testing.T, status int) *pushServer {
+	t.Helper()
+	s := &pushServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestWebPushSender(t *testing.T, server *pushServer) *WebPushSender {
+	t.Helper()
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &WebPushSender{Keys: keys, Subject: "mailto:admin@example.com", Client: server.Client(), AllowPrivateEndpoints: true}
+}
+
+func TestWebPushSenderSend(t *testing.T) {
+	server := newPushServer(t, http.StatusCreated)
+	sender := newTestWebPushSender(t, server)
+	subscriber := newPushSubscriber(t)
+
+	payload := []byte(`{"title":"Chapter 101","url":"/manga/one-piece"}`)
+	if err := sender.Send(subscriber.subscription(1, server.URL+"/send/abc"), payload); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(server.requests) != 1 {
+		t.Fatalf("push service got %d requests, want 1", len(server.requests))
+	}
+
+	req := server.requests[0]
+	if req.Method != http.MethodPost || req.URL.Path != "/send/abc" {
+		t.Errorf("request = %s %s, want POST /send/abc", req.Method, req.URL.Path)
+	}
+	for header, want := range map[string]string{
+		"Content-Encoding": "aes128gcm",
+		"Content-Type":     "application/octet-stream",
+		"TTL":              "86400",
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if got := subscriber.decrypt(t, server.bodies[0]); !bytes.Equal(got, payload) {
+		t.Errorf("push service got %q, want %q", got, payload)
+	}
+
+	verifyVAPIDAuthorization(t, req.Header.Get("Authorization"), sender.Keys, server.URL)
+}
+
+// verifyVAPIDAuthorization checks the RFC 8292 header: a JWT signed by the VAPID key for the
+// push service's origin, and the matching public key
+func verifyVAPIDAuthorization(t *testing.T, header string, keys *VAPIDKeys, audience string) {
+	t.Helper()
+	token, publicKey, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	if !ok || !strings.HasPrefix(header, "vapid t=") {
+		t.Fatalf("Authorization = %q, want vapid t=..., k=...", header)
+	}
+	if publicKey != keys.PublicKey() {
+		t.Errorf("k = %q, want the VAPID public key %q", publicKey, keys.PublicKey())
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3", len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		t.Fatalf("signature is not 64 bytes of base64url")
+	}
+	raw := keys.Private.PublicKey().Bytes()
+	public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(raw[1:33]), Y: new(big.Int).SetBytes(raw[33:])}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(public, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Error("JWT signature does not verify with the VAPID public key")
+	}
+
+	var claims struct {
+		Aud string `json:"aud"`
+		Sub string `json:"sub"`
+		Exp int64  `json:"exp"`
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("invalid claims: %v", err)
+	}
+	if claims.Aud != audience || claims.Sub != "mailto:admin@example.com" || claims.Exp == 0 {
+		t.Errorf("claims = %+v, want aud %q", claims, audience)
+	}
+}
+
+func TestWebPushSenderSendRejectsLargePayload(t *testing.T) {
+	server := newPushServer(t, http.StatusCreated)
+	sender := newTestWebPushSender(t, server)
+
+	err := sender.Send(newPushSubscriber(t).subscription(1, server.URL), make([]byte, MaxWebPushPayload+1))
+	if !errors.Is(err, ErrPushPayloadTooLarge) {
+		t.Fatalf("Send = %v, want ErrPushPayloadTooLarge", err)
+	}
+	if len(server.requests) != 0 {
+		t.Fatal("oversized payload was sent")
+	}
+}
+
+func TestPushDeliverRemovesGoneSubscriptions(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		query  string
+	}{
+		{http.StatusCreated, nil, "UPDATE push_subscriptions SET last_used_at"},
+		{http.StatusNotFound, ErrPushSubscriptionGone, "DELETE FROM push_subscriptions"},
+		{http.StatusGone, ErrPushSubscriptionGone, "DELETE FROM push_subscriptions"},
+		{http.StatusTooManyRequests, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			db := useRecordingDB(t)
+			server := newPushServer(t, tt.status)
+			service := NewPushSubscriptionService(newTestWebPushSender(t, server))
+
+			err := service.deliver(newPushSubscriber(t).subscription(42, server.URL+"/send/abc"), []byte("{}"))
+			switch {
+			case tt.err != nil && !errors.Is(err, tt.err):
+				t.Fatalf("deliver = %v, want %v", err, tt.err)
+			case tt.err == nil && tt.query != "" && err != nil:
+				t.Fatalf("deliver = %v", err)
+			case tt.query == "" && err == nil:
+				t.Fatalf("deliver succeeded on %d", tt.status)
+			}
+
+			execs := db.execs()
+			if tt.query == "" {
+				if len(execs) != 0 {
+					t.Fatalf("subscription table changed on %d: %v", tt.status, execs)
+				}
+				return
+			}
+			if len(execs) != 1 || !strings.HasPrefix(execs[0].query, tt.query) {
+				t.Fatalf("statements = %v, want one %q", execs, tt.query)
+			}
+			if len(execs[0].args) != 1 || execs[0].args[0] != int64(42) {
+				t.Fatalf("args = %v, want the subscription id 42", execs[0].args)
+			}
+		})
+	}
+}
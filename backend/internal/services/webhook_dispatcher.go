@@ -0,0 +1,378 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultWebhookInterval = 10 * time.Second
+	webhookBatchSize       = 20
+	webhookWorkers         = 4
+	maxWebhookAttempts     = 8
+	webhookBaseBackoff     = 30 * time.Second
+	webhookLease           = 5 * time.Minute // A claimed delivery is retried after this if its sender died
+	webhookLogRetention    = 30              // days
+)
+
+// WebhookDispatcher turns release events into webhook deliveries and sends them.
+//
+// Each request is a JSON POST of models.WebhookPayload with the headers
+//
+//	X-Webhook-Event: episode.released | chapter.released | ping
+//	X-Webhook-Delivery: <delivery id> (replays get a new id)
+//	X-Webhook-Timestamp: <unix seconds>
+//	X-Webhook-Signature: sha256=<hex HMAC-SHA256(secret, timestamp + "." + body)>
+//
+// Any 2xx response counts as delivered. Other responses and network errors are retried with
+// exponential backoff (30s, 1m, 2m, ... 32m, about 1h in total) up to 8 attempts; 410 Gone
+// disables the webhook.
+type WebhookDispatcher struct {
+	Client       *http.Client
+	Interval     time.Duration
+	AllowPrivate bool // http:// and private-network URLs, for receivers on the same network
+
+	startOnce sync.Once
+	lastPrune time.Time
+}
+
+var (
+	webhookDispatcher     *WebhookDispatcher
+	webhookDispatcherOnce sync.Once
+)
+
+// GetWebhookDispatcher returns the shared dispatcher. WEBHOOK_POLL_INTERVAL (default 10s) is
+// how often due deliveries are picked up; WEBHOOK_ALLOW_PRIVATE_URLS=true allows receivers on
+// private networks (e.g. a bot container next to the backend).
+func GetWebhookDispatcher() *WebhookDispatcher {
+	webhookDispatcherOnce.Do(func() {
+		interval := defaultWebhookInterval
+		if value := os.Getenv("WEBHOOK_POLL_INTERVAL"); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				interval = parsed
+			} else {
+				fmt.Printf("[Webhooks] ⚠️  Invalid WEBHOOK_POLL_INTERVAL %q, using %s\n", value, interval)
+			}
+		}
+
+		allowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE_URLS") == "true"
+		client := newPublicHTTPClient(15 * time.Second)
+		if allowPrivate {
+			client = &http.Client{Timeout: 15 * time.Second}
+		}
+		webhookDispatcher = &WebhookDispatcher{Client: client, Interval: interval, AllowPrivate: allowPrivate}
+	})
+	return webhookDispatcher
+}
+
+// CheckURL validates a webhook URL before it is stored
+func (d *WebhookDispatcher) CheckURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" || len(raw) > 2048 {
+		return errors.New("invalid url")
+	}
+	if d.AllowPrivate {
+		if target.Scheme != "https" && target.Scheme != "http" {
+			return errors.New("url must be http(s)")
+		}
+		return nil
+	}
+	return CheckPublicURL(target)
+}
+
+func (d *WebhookDispatcher) Start() {
+	d.startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(d.Interval)
+			defer ticker.Stop()
+
+			for range ticker.C {
+				if err := d.run(); err != nil {
+					fmt.Printf("[Webhooks] ⚠️  Dispatch failed: %v\n", err)
+				}
+			}
+		}()
+	})
+}
+
+// Enqueue creates a delivery for every active webhook whose filters match an event
+func (d *WebhookDispatcher) Enqueue(events []models.ReleaseEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	rows, err := database.DB.Query(`SELECT id, user_id, types, genres, slugs, bookmarked_only FROM webhooks WHERE active`)
+	if err != nil {
+		return err
+	}
+	type target struct {
+		id, userID           int
+		types, genres, slugs pq.StringArray
+		bookmarkedOnly       bool
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.id, &t.userID, &t.types, &t.genres, &t.slugs, &t.bookmarkedOnly); err != nil {
+			rows.Close()
+			return err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(targets) == 0 {
+		return err
+	}
+
+	bookmarks := make(map[int]*BookmarkEventFilter)
+	genres := make(map[int64][]string)
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, release_event_id, payload) VALUES ($1, $2, $3, $4)`
+	queued := 0
+	for _, event := range events {
+		payload, err := json.Marshal(models.WebhookPayload{Event: event.Name(), CreatedAt: event.CreatedAt.UTC(), Data: &event})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range targets {
+			if len(t.types) > 0 && !slices.Contains(t.types, event.Type) {
+				continue
+			}
+			if len(t.slugs) > 0 && !slices.Contains(t.slugs, event.Slug) {
+				continue
+			}
+			if len(t.genres) > 0 {
+				eventGenres, ok := genres[event.ID]
+				if !ok {
+					eventGenres = releaseGenres(event)
+					genres[event.ID] = eventGenres
+				}
+				if !anyString(t.genres, eventGenres) {
+					continue
+				}
+			}
+			if t.bookmarkedOnly {
+				filter, ok := bookmarks[t.userID]
+				if !ok {
+					filter = &BookmarkEventFilter{UserID: t.userID}
+					bookmarks[t.userID] = filter
+				}
+				if matched, err := filter.Match(event); err != nil || !matched {
+					continue
+				}
+			}
+
+			if _, err := database.DB.Exec(query, t.id, event.Name(), event.ID, string(payload)); err != nil {
+				return err
+			}
+			queued++
+		}
+	}
+	if queued > 0 {
+		fmt.Printf("[Webhooks] Queued %d deliveries\n", queued)
+	}
+	return nil
+}
+
+type claimedDelivery struct {
+	id       int64
+	event    string
+	payload  []byte
+	attempts int
+	webhook  int
+	url      string
+	secret   string
+	active   bool
+}
+
+// run sends every due delivery, a batch at a time. Claiming pushes next_attempt_at out by
+// the lease, so several instances can run this without sending anything twice.
+func (d *WebhookDispatcher) run() error {
+	for {
+		rows, err := database.DB.Query(`UPDATE webhook_deliveries d
+              SET attempts = d.attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+              FROM webhooks w
+              WHERE w.id = d.webhook_id AND d.id IN (
+                  SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= NOW()
+                  ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED)
+              RETURNING d.id, d.event, d.payload, d.attempts, w.id, w.url, w.secret, w.active`,
+			webhookBatchSize, webhookLease.Seconds())
+		if err != nil {
+			return err
+		}
+
+		var batch []claimedDelivery
+		for rows.Next() {
+			var c claimedDelivery
+			if err := rows.Scan(&c.id, &c.event, &c.payload, &c.attempts, &c.webhook, &c.url, &c.secret, &c.active); err != nil {
+				rows.Close()
+				return err
+			}
+			batch = append(batch, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		jobs := make(chan claimedDelivery)
+		var wg sync.WaitGroup
+		for w := 0; w < webhookWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for c := range jobs {
+					d.deliver(c)
+				}
+			}()
+		}
+		for _, c := range batch {
+			jobs <- c
+		}
+		close(jobs)
+		wg.Wait()
+
+		if len(batch) < webhookBatchSize {
+			break
+		}
+	}
+
+	if time.Since(d.lastPrune) > time.Hour {
+		d.lastPrune = time.Now()
+		if _, err := database.DB.Exec(`DELETE FROM webhook_deliveries WHERE status <> 'pending'
+              AND created_at < NOW() - make_interval(days => $1)`, webhookLogRetention); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) deliver(c claimedDelivery) {
+	if !c.active {
+		d.finish(c, false, nil, "webhook is disabled")
+		return
+	}
+
+	status, err := d.post(c)
+	switch {
+	case err == nil:
+		d.finish(c, true, &status, "")
+	case status == http.StatusGone:
+		// The receiver says it's gone for good
+		if _, dbErr := database.DB.Exec(`UPDATE webhooks SET active = FALSE, updated_at = NOW() WHERE id = $1`, c.webhook); dbErr != nil {
+			fmt.Printf("[Webhooks] ⚠️  Failed to disable webhook %d: %v\n", c.webhook, dbErr)
+		}
+		d.finish(c, false, &status, err.Error())
+	default:
+		var code *int
+		if status != 0 {
+			code = &status
+		}
+		if c.attempts >= maxWebhookAttempts {
+			d.finish(c, false, code, err.Error())
+			return
+		}
+		if _, dbErr := database.DB.Exec(`UPDATE webhook_deliveries SET next_attempt_at = NOW() + make_interval(secs => $2),
+                  response_status = $3, last_error = $4
+              WHERE id = $1`, c.id, webhookBackoff(c.attempts).Seconds(), code, err.Error()); dbErr != nil {
+			fmt.Printf("[Webhooks] ⚠️  Failed to reschedule delivery %d: %v\n", c.id, dbErr)
+		}
+	}
+}
+
+func (d *WebhookDispatcher) finish(c claimedDelivery, succeeded bool, status *int, lastError string) {
+	result := "failed"
+	if succeeded {
+		result = "succeeded"
+	}
+	if _, err := database.DB.Exec(`UPDATE webhook_deliveries SET status = $2, response_status = $3, last_error = NULLIF($4, ''),
+              completed_at = NOW() WHERE id = $1`, c.id, result, status, lastError); err != nil {
+		fmt.Printf("[Webhooks] ⚠️  Failed to update delivery %d: %v\n", c.id, err)
+	}
+}
+
+// post sends one attempt and returns the response status (0 when there was none)
+func (d *WebhookDispatcher) post(c claimedDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(c.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AnimeTanyaAyomi-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", c.event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(c.id, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(c.secret, timestamp, c.payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		return resp.StatusCode, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	message := fmt.Sprintf("HTTP %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(body)); text != "" {
+		message += ": " + strings.ToValidUTF8(text, "")
+	}
+	return resp.StatusCode, errors.New(message)
+}
+
+// SignWebhook is the hex HMAC-SHA256 receivers recompute to verify a delivery
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	// attempts < maxWebhookAttempts, so the wait stays below an hour
+	return webhookBaseBackoff << (attempts - 1)
+}
+
+// releaseGenres looks up the series genres in the enriched metadata (empty when unknown)
+func releaseGenres(event models.ReleaseEvent) []string {
+	var genre string
+	err := database.DB.QueryRow(`SELECT COALESCE(genre, '') FROM enriched_metadata
+              WHERE media_type = $1 AND (slug = $2 OR LOWER(title) = LOWER($3)) LIMIT 1`,
+		event.Type, event.Slug, event.Title).Scan(&genre)
+	if err != nil {
+		return nil
+	}
+	var genres []string
+	for _, g := range strings.Split(genre, ",") {
+		if key := genreKey(g); key != "" {
+			genres = append(genres, key)
+		}
+	}
+	return genres
+}
+
+// anyString reports whether values and candidates share an element
+func anyString(values []string, candidates []string) bool {
+	return slices.ContainsFunc(candidates, func(candidate string) bool { return slices.Contains(values, candidate) })
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"anime-tanyaayomi/internal/database"
+	"anime-tanyaayomi/internal/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxWebhooksPerUser  = 20
+	maxWebhookFilterLen = 100
+)
+
+var (
+	ErrInvalidWebhook          = errors.New("invalid webhook")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookService manages webhooks and their delivery log. Methods take an ownerID to scope
+// the lookup to one user; ownerID 0 means any user (admin routes).
+type WebhookService struct {
+	Dispatcher *WebhookDispatcher
+}
+
+func NewWebhookService() *WebhookService {
+	return &WebhookService{Dispatcher: GetWebhookDispatcher()}
+}
+
+const webhookColumns = `id, user_id, url, COALESCE(description, ''), types, genres, slugs, bookmarked_only, active, created_at, updated_at`
+
+func (s *WebhookService) Create(userID int, req models.WebhookRequest) (*models.Webhook, error) {
+	if req.URL == nil {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidWebhook)
+	}
+	webhook := models.Webhook{Active: true, Types: []string{}, Genres: []string{}, Slugs: []string{}}
+	if err := s.apply(&webhook, req); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := database.DB.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, fmt.Errorf("%w: at most %d webhooks per user", ErrInvalidWebhook, maxWebhooksPerUser)
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	query := `INSERT INTO webhooks (user_id, url, secret, description, types, genres, slugs, bookmarked_only, active)
+              VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9)
+              RETURNING ` + webhookColumns
+	created, err := scanWebhook(database.DB.QueryRow(query, userID, webhook.URL, secret, webhook.Description,
+		pq.Array(webhook.Types), pq.Array(webhook.Genres), pq.Array(webhook.Slugs), webhook.BookmarkedOnly, webhook.Active))
+	if err != nil {
+		return nil, err
+	}
+	created.Secret = secret
+	return created, nil
+}
+
+// List returns one page of webhooks, newest first
+func (s *WebhookService) List(ownerID int, page int, limit int) ([]models.Webhook, int, error) {
+	rows, err := database.DB.Query(`SELECT `+webhookColumns+`, COUNT(*) OVER() FROM webhooks
+              WHERE ($1 = 0 OR user_id = $1) ORDER BY created_at DESC, id DESC LIMIT $2 OFFSET $3`,
+		ownerID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	total := 0
+	for rows.Next() {
+		webhook, err := scanWebhook(rows, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, total, rows.Err()
+}
+
+func (s *WebhookService) Get(ownerID int, webhookID int) (*models.Webhook, error) {
+	webhook, err := scanWebhook(database.DB.QueryRow(`SELECT `+webhookColumns+` FROM webhooks
+              WHERE id = $1 AND ($2 = 0 OR user_id = $2)`, webhookID, ownerID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// Update changes the fields set in req
+func (s *WebhookService) Update(ownerID int, webhookID int, req models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := s.Get(ownerID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(webhook, req); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE webhooks SET url = $2, description = NULLIF($3, ''), types = $4, genres = $5, slugs = $6,
+                  bookmarked_only = $7, active = $8, updated_at = NOW()
+              WHERE id = $1 RETURNING ` + webhookColumns
+	return scanWebhook(database.DB.QueryRow(query, webhook.ID, webhook.URL, webhook.Description, pq.Array(webhook.Types),
+		pq.Array(webhook.Genres), pq.Array(webhook.Slugs), webhook.BookmarkedOnly, webhook.Active))
+}
+
+func (s *WebhookService) Delete(ownerID int, webhookID int) error {
+	result, err := database.DB.Exec(`DELETE FROM webhooks WHERE id = $1 AND ($2 = 0 OR user_id = $2)`, webhookID, ownerID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// RotateSecret replaces the signing secret and returns the webhook with the new one
+func (s *WebhookService) RotateSecret(ownerID int, webhookID int) (*models.Webhook, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := scanWebhook(database.DB.QueryRow(`UPDATE webhooks SET secret = $3, updated_at = NOW()
+              WHERE id = $1 AND ($2 = 0 OR user_id = $2) RETURNING `+webhookColumns, webhookID, ownerID, secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = secret
+	return webhook, nil
+}
+
+// Ping queues a "ping" delivery so receivers can check their signature verification
+func (s *WebhookService) Ping(ownerID int, webhookID int) (*models.WebhookDelivery, error) {
+	if _, err := s.Get(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(models.WebhookPayload{Event: "ping", CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhookDelivery(database.DB.QueryRow(`INSERT INTO webhook_deliveries (webhook_id, event, payload)
+              VALUES ($1, 'ping', $2) RETURNING `+webhookDeliveryColumns, webhookID, string(payload)))
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, release_event_id, payload, status, attempts, next_attempt_at,
+                  response_status, COALESCE(last_error, ''), replay_of, created_at, completed_at`
+
+// Deliveries returns one page of a webhook's delivery log, newest first; status filters when set
+func (s *WebhookService) Deliveries(ownerID int, webhookID int, status string, page int, limit int) ([]models.WebhookDelivery, int, error) {
+	if _, err := s.Get(ownerID, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := database.DB.Query(`SELECT `+webhookDeliveryColumns+`, COUNT(*) OVER() FROM webhook_deliveries
+              WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+              ORDER BY created_at DESC, id DESC LIMIT $3 OFFSET $4`, webhookID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	total := 0
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows, &total)
+		if err != nil {
+			return nil, 0, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// Replay queues a new delivery with the payload of an earlier one; the log keeps both
+func (s *WebhookService) Replay(ownerID int, webhookID int, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.Get(ownerID, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, release_event_id, payload, replay_of)
+              SELECT webhook_id, event, release_event_id, payload, id FROM webhook_deliveries
+              WHERE id = $1 AND webhook_id = $2
+              RETURNING ` + webhookDeliveryColumns
+	delivery, err := scanWebhookDelivery(database.DB.QueryRow(query, deliveryID, webhookID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// apply validates and copies the set fields of req onto webhook
+func (s *WebhookService) apply(webhook *models.Webhook, req models.WebhookRequest) error {
+	if req.URL != nil {
+		target := strings.TrimSpace(*req.URL)
+		if err := s.Dispatcher.CheckURL(target); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+		}
+		webhook.URL = target
+	}
+	if req.Description != nil {
+		webhook.Description = strings.TrimSpace(*req.Description)
+		if len(webhook.Description) > 255 {
+			return fmt.Errorf("%w: description is too long", ErrInvalidWebhook)
+		}
+	}
+	if req.Types != nil {
+		types, err := normalizeWebhookFilter(*req.Types, func(value string) (string, error) {
+			value = strings.ToLower(value)
+			if value != "anime" && value != "manga" {
+				return "", fmt.Errorf("%w: unknown type %q", ErrInvalidWebhook, value)
+			}
+			return value, nil
+		})
+		if err != nil {
+			return err
+		}
+		webhook.Types = types
+	}
+	if req.Genres != nil {
+		genres, err := normalizeWebhookFilter(*req.Genres, func(value string) (string, error) {
+			return genreKey(value), nil
+		})
+		if err != nil {
+			return err
+		}
+		webhook.Genres = genres
+	}
+	if req.Slugs != nil {
+		slugs, err := normalizeWebhookFilter(*req.Slugs, func(value string) (string, error) { return value, nil })
+		if err != nil {
+			return err
+		}
+		webhook.Slugs = slugs
+	}
+	if req.BookmarkedOnly != nil {
+		webhook.BookmarkedOnly = *req.BookmarkedOnly
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	return nil
+}
+
+// normalizeWebhookFilter trims, normalizes and de-duplicates filter values
+func normalizeWebhookFilter(values []string, normalize func(string) (string, error)) ([]string, error) {
+	if len(values) > maxWebhookFilterLen {
+		return nil, fmt.Errorf("%w: at most %d filter values", ErrInvalidWebhook, maxWebhookFilterLen)
+	}
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		normalized, err := normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		if normalized != "" && !seen[normalized] {
+			seen[normalized] = true
+			result = append(result, normalized)
+		}
+	}
+	return result, nil
+}
+
+// genreKey makes "Slice of Life" and "slice-of-life" the same genre
+func genreKey(genre string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(genre, "-", " "))), "-")
+}
+
+func newWebhookSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func scanWebhook(row rowScanner, extra ...interface{}) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var types, genres, slugs pq.StringArray
+	dest := append([]interface{}{&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.Description, &types, &genres, &slugs,
+		&webhook.BookmarkedOnly, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	webhook.Types, webhook.Genres, webhook.Slugs = []string(types), []string(genres), []string(slugs)
+	return &webhook, nil
+}
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var releaseEventID, replayOf sql.NullInt64
+	var nextAttempt, completed sql.NullTime
+	var responseStatus sql.NullInt32
+	var payload []byte
+	dest := append([]interface{}{&delivery.ID, &delivery.WebhookID, &delivery.Event, &releaseEventID, &payload, &delivery.Status,
+		&delivery.Attempts, &nextAttempt, &responseStatus, &delivery.LastError, &replayOf, &delivery.CreatedAt, &completed}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	delivery.Payload = json.RawMessage(payload)
+	if releaseEventID.Valid {
+		delivery.ReleaseEventID = &releaseEventID.Int64
+	}
+	if replayOf.Valid {
+		delivery.ReplayOf = &replayOf.Int64
+	}
+	if nextAttempt.Valid && delivery.Status == "pending" {
+		delivery.NextAttemptAt = &nextAttempt.Time
+	}
+	if responseStatus.Valid {
+		status := int(responseStatus.Int32)
+		delivery.ResponseStatus = &status
+	}
+	if completed.Valid {
+		delivery.CompletedAt = &completed.Time
+	}
+	return &delivery, nil
+}
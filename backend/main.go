@@ -5,6 +5,7 @@ import (
 
 	"anime-tanyaayomi/internal/database"
 	"anime-tanyaayomi/internal/routes"
+	"anime-tanyaayomi/internal/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -20,7 +21,9 @@ func main() {
 	// Database
 	database.Connect()
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit: 16 * 1024 * 1024, // Tachiyomi backups of big libraries exceed the 4 MB default
+	})
 
 	// Middleware
 	app.Use(cors.New())
@@ -28,6 +31,16 @@ func main() {
 	// Routes
 	routes.SetupRoutes(app)
 
+	// New episode/chapter notifications for bookmarked titles
+	services.GetReleasePoller().Start()
+	services.GetEventBroker().Start() // Streams the poller's releases to /api/events
+	services.GetWebhookDispatcher().Start()
+	services.GetEnrichmentQueue().Start() // Fills in metadata for titles the list pages queued
+	services.GetImportQueue().Start()     // Matches uploaded lists and backups
+
+	// Daily/weekly email digests (only when SMTP_HOST is set)
+	services.StartDigestScheduler()
+
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Anime-TanyaAyomi Backend Running")
 	})
@@ -0,0 +1,17 @@
+-- Migration: Create auth_sessions table for JWT refresh tokens
+-- Each row is one refresh token; access tokens reference it via the "sid" claim
+-- so logging out (revoking the row) also invalidates outstanding access tokens.
+
+CREATE TABLE IF NOT EXISTS auth_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_id VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user ON auth_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_expires ON auth_sessions(expires_at);
+
+COMMENT ON TABLE auth_sessions IS 'Server-side refresh token sessions used for JWT revocation';
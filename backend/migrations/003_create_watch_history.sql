@@ -0,0 +1,24 @@
+-- Migration: Create watch_history table for per-user episode progress
+-- One row per user+episode, updated in place while the player reports progress.
+-- "Continue watching" reads the latest row per anime.
+
+CREATE TABLE IF NOT EXISTS watch_history (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    anime_slug VARCHAR(255) NOT NULL,
+    episode_slug VARCHAR(255) NOT NULL,
+    anime_title VARCHAR(500),
+    episode_title VARCHAR(500),
+    cover_image TEXT,
+    position_seconds INT NOT NULL DEFAULT 0,
+    duration_seconds INT NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, episode_slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watch_history_user_updated ON watch_history(user_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_watch_history_user_anime ON watch_history(user_id, anime_slug);
+
+COMMENT ON TABLE watch_history IS 'Episode watch progress reported by the video player';
@@ -0,0 +1,28 @@
+-- Migration: Create manga reading progress tables
+-- manga_reading_progress keeps where a user stopped in each manga (chapter + page),
+-- manga_chapter_reads is the set of chapters the user has read.
+
+CREATE TABLE IF NOT EXISTS manga_reading_progress (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    manga_slug VARCHAR(255) NOT NULL,
+    chapter_slug VARCHAR(255) NOT NULL,
+    page_index INT NOT NULL DEFAULT 0,
+    total_pages INT NOT NULL DEFAULT 0,
+    manga_title VARCHAR(500),
+    cover_image TEXT,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, manga_slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_progress_user_updated ON manga_reading_progress(user_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS manga_chapter_reads (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    manga_slug VARCHAR(255) NOT NULL,
+    chapter_slug VARCHAR(255) NOT NULL,
+    read_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (user_id, manga_slug, chapter_slug)
+);
+
+COMMENT ON TABLE manga_reading_progress IS 'Last chapter and page per user and manga';
+COMMENT ON TABLE manga_chapter_reads IS 'Chapters marked as read per user';
@@ -0,0 +1,35 @@
+-- Migration: Turn bookmarks into a tracking list
+-- Adds status / personal score / notes / tags per bookmark and user-defined collections.
+-- Statuses use the same names as list import/export (current = watching or reading).
+
+ALTER TABLE bookmarks
+    ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'planning'
+        CHECK (status IN ('planning', 'current', 'completed', 'paused', 'dropped')),
+    ADD COLUMN IF NOT EXISTS score SMALLINT CHECK (score BETWEEN 1 AND 10),
+    ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_bookmarks_user_status ON bookmarks(user_id, status);
+CREATE INDEX IF NOT EXISTS idx_bookmarks_tags ON bookmarks USING GIN(tags);
+
+CREATE TABLE IF NOT EXISTS bookmark_collections (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS bookmark_collection_items (
+    collection_id INT NOT NULL REFERENCES bookmark_collections(id) ON DELETE CASCADE,
+    bookmark_id INT NOT NULL REFERENCES bookmarks(id) ON DELETE CASCADE,
+    added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (collection_id, bookmark_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_items_bookmark ON bookmark_collection_items(bookmark_id);
+
+COMMENT ON TABLE bookmark_collections IS 'User-defined bookmark lists';
+COMMENT ON TABLE bookmark_collection_items IS 'Bookmarks in each collection (a bookmark can be in several)';
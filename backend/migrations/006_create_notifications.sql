@@ -0,0 +1,31 @@
+-- Migration: New-episode / new-chapter notifications
+-- release_feed_state remembers the latest release seen per series in each upstream "latest" feed;
+-- the poller diffs the feeds against it and writes one notification per bookmarking user.
+
+CREATE TABLE IF NOT EXISTS release_feed_state (
+    source VARCHAR(50) NOT NULL,
+    series_slug VARCHAR(255) NOT NULL,
+    latest_release VARCHAR(255) NOT NULL,
+    updated_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (source, series_slug)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('anime', 'manga')),
+    slug VARCHAR(255) NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    release VARCHAR(255) NOT NULL,
+    target_slug VARCHAR(255),
+    cover_image TEXT,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE(user_id, type, slug, release)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+COMMENT ON TABLE release_feed_state IS 'Last release seen per series in each latest-releases feed';
+COMMENT ON TABLE notifications IS 'In-app notifications about new episodes/chapters of bookmarked titles';
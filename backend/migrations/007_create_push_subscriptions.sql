@@ -0,0 +1,25 @@
+-- Migration: Web Push subscriptions
+-- One row per browser/device subscription (the endpoint is unique per device).
+-- web_push_vapid_keys holds the generated VAPID key pair when VAPID_PRIVATE_KEY is not set.
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT NOT NULL UNIQUE,
+    p256dh VARCHAR(255) NOT NULL,
+    auth VARCHAR(64) NOT NULL,
+    device_name VARCHAR(255),
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id);
+
+CREATE TABLE IF NOT EXISTS web_push_vapid_keys (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    private_key TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+COMMENT ON TABLE push_subscriptions IS 'Browser push subscriptions per user and device';
+COMMENT ON TABLE web_push_vapid_keys IS 'Single-row VAPID key pair; subscriptions break if it changes';
@@ -0,0 +1,16 @@
+-- Migration: Email digest preferences
+-- Users opt in with an address and a frequency; the digest job sends the notifications
+-- created since last_digest_at and moves it forward.
+
+CREATE TABLE IF NOT EXISTS email_digest_preferences (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'daily', 'weekly')),
+    last_digest_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_digest_active ON email_digest_preferences(last_digest_at) WHERE frequency <> 'off';
+
+COMMENT ON TABLE email_digest_preferences IS 'Daily/weekly release digest settings per user';
@@ -0,0 +1,12 @@
+-- Migration: Private Atom feed tokens
+-- The token in a personal feed URL works without a session (feed readers can't log in),
+-- so only its SHA-256 is stored and users can rotate it if the URL leaks.
+
+CREATE TABLE IF NOT EXISTS feed_tokens (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    last_used_at TIMESTAMP
+);
+
+COMMENT ON TABLE feed_tokens IS 'Secret tokens for per-user release feeds (/feeds/users/<token>.atom)';
@@ -0,0 +1,19 @@
+-- Migration: Live release events
+-- The release poller appends one row per new episode/chapter; every backend instance tails
+-- the table and streams new rows to its /api/events clients. The id is the SSE event id,
+-- so reconnecting clients resume with Last-Event-ID.
+
+CREATE TABLE IF NOT EXISTS release_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('anime', 'manga')),
+    slug VARCHAR(255) NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    release VARCHAR(255) NOT NULL,
+    target_slug VARCHAR(255),
+    cover_image TEXT,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_release_events_created ON release_events(created_at);
+
+COMMENT ON TABLE release_events IS 'New releases streamed to /api/events (kept a few days for reconnects)';
@@ -0,0 +1,47 @@
+-- Migration: Outbound webhooks for release events
+-- Users register URLs with filters; every new release event that matches gets a delivery
+-- row, which the dispatcher POSTs (HMAC-signed) with exponential-backoff retries.
+-- The rows double as the delivery log shown to the user and can be replayed.
+
+-- Admins can see and manage every user's webhooks: UPDATE users SET is_admin = TRUE WHERE username = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    description VARCHAR(255),
+    -- Filters: an empty array matches everything
+    types TEXT[] NOT NULL DEFAULT '{}',
+    genres TEXT[] NOT NULL DEFAULT '{}',
+    slugs TEXT[] NOT NULL DEFAULT '{}',
+    bookmarked_only BOOLEAN NOT NULL DEFAULT FALSE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    release_event_id BIGINT, -- No FK: release_events are pruned sooner than the log
+    payload JSONB NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT NOW(),
+    response_status INT,
+    last_error TEXT,
+    replay_of BIGINT REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+COMMENT ON TABLE webhooks IS 'User-registered URLs notified about new releases';
+COMMENT ON TABLE webhook_deliveries IS 'Webhook delivery queue and log';
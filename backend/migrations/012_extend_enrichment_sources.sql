@@ -0,0 +1,7 @@
+-- Migration: Allow the pluggable enricher backends as enriched_metadata.source
+-- The source column records which backend produced a row: gemini, openai (any
+-- OpenAI-compatible server, including local models) or fake (offline testing).
+
+ALTER TABLE enriched_metadata DROP CONSTRAINT IF EXISTS enriched_metadata_source_check;
+ALTER TABLE enriched_metadata ADD CONSTRAINT enriched_metadata_source_check
+    CHECK (source IN ('gemini', 'openai', 'fake', 'manual', 'api'));
@@ -0,0 +1,10 @@
+-- Migration: Confidence scoring for AI-enriched metadata
+-- Every enricher response is validated (year range, 0-10 rating, status, genre taxonomy) and
+-- scored. Low-confidence results are rejected; results in between are stored but flagged
+-- for review together with the reason. Rows from before this migration have no score.
+
+ALTER TABLE enriched_metadata ADD COLUMN IF NOT EXISTS confidence NUMERIC(3, 2);
+ALTER TABLE enriched_metadata ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE enriched_metadata ADD COLUMN IF NOT EXISTS review_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_enriched_needs_review ON enriched_metadata(last_updated_at) WHERE needs_review;
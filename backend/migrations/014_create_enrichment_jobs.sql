@@ -0,0 +1,30 @@
+-- Migration: Background enrichment queue
+-- List pages no longer wait for the LLM: titles without enriched_metadata are queued here
+-- and filled in by workers (SELECT ... FOR UPDATE SKIP LOCKED, so any number of instances
+-- can run them).
+
+CREATE TABLE IF NOT EXISTS enrichment_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(10) NOT NULL CHECK (media_type IN ('anime', 'manga')),
+    -- done: stored; rejected: the result failed validation; failed: the enricher kept erroring
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'rejected', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    -- A running job whose lease has passed is picked up again (its worker died)
+    locked_until TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_enrichment_job UNIQUE (title, media_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrichment_jobs_due ON enrichment_jobs(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_enrichment_jobs_status ON enrichment_jobs(status, updated_at DESC);
+
+-- LLM calls per minute/day bucket, shared by every instance for the global rate and budget limits
+CREATE TABLE IF NOT EXISTS enrichment_usage (
+    bucket VARCHAR(32) PRIMARY KEY, -- "minute:2024-01-02T15:04" or "day:2024-01-02" (UTC)
+    calls INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL
+);
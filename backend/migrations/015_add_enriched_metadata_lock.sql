@@ -0,0 +1,5 @@
+-- Migration: Curator overrides for enriched metadata
+-- Admins can edit rows (source becomes 'manual') and lock them; the enricher never
+-- overwrites a locked row.
+
+ALTER TABLE enriched_metadata ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,48 @@
+-- Migration: Revision history for enriched_metadata
+-- Every write (enricher upserts, curator edits, locks, deletes, restores) appends a row with
+-- who did it, the resulting source, a field diff and a snapshot to restore from. Rows are
+-- never updated or deleted; metadata_id and actor_id are plain columns (no foreign keys) so
+-- the history outlives deleted rows and users.
+
+CREATE TABLE IF NOT EXISTS enriched_metadata_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    metadata_id INT NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    media_type VARCHAR(10) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('baseline', 'create', 'update', 'delete', 'restore')),
+    source VARCHAR(50) NOT NULL,
+    actor_id INT, -- NULL for the enricher
+    -- {"author": {"old": "...", "new": "..."}, ...}
+    diff JSONB NOT NULL DEFAULT '{}',
+    -- The row after the change (before it, for deletes)
+    snapshot JSONB NOT NULL,
+    restored_from BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_enriched_revisions_title ON enriched_metadata_revisions(title, media_type, id DESC);
+
+CREATE OR REPLACE FUNCTION enriched_metadata_revisions_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'enriched_metadata_revisions is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS enriched_metadata_revisions_append_only ON enriched_metadata_revisions;
+CREATE TRIGGER enriched_metadata_revisions_append_only
+    BEFORE UPDATE OR DELETE ON enriched_metadata_revisions
+    FOR EACH ROW EXECUTE FUNCTION enriched_metadata_revisions_append_only();
+
+-- Existing rows get a baseline revision so they can be restored to what they were
+INSERT INTO enriched_metadata_revisions (metadata_id, title, media_type, action, source, snapshot, created_at)
+SELECT m.id, m.title, m.media_type, 'baseline', COALESCE(m.source, 'gemini'),
+       jsonb_build_object(
+           'id', m.id, 'title', m.title, 'mediaType', m.media_type, 'slug', COALESCE(m.slug, ''),
+           'author', COALESCE(m.author, ''), 'genre', COALESCE(m.genre, ''), 'type', COALESCE(m.type, ''),
+           'rating', COALESCE(m.rating, ''), 'status', COALESCE(m.status, ''),
+           'releaseYear', COALESCE(m.release_year, ''), 'synopsis', COALESCE(m.synopsis, ''),
+           'source', COALESCE(m.source, 'gemini'), 'confidence', m.confidence, 'needsReview', m.needs_review,
+           'reviewReason', COALESCE(m.review_reason, ''), 'locked', m.locked),
+       COALESCE(m.last_updated_at, NOW())
+FROM enriched_metadata m
+WHERE NOT EXISTS (SELECT 1 FROM enriched_metadata_revisions r WHERE r.metadata_id = m.id);
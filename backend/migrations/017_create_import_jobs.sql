@@ -0,0 +1,31 @@
+-- Migration: Background list/backup imports
+-- Matching an import takes one or more upstream searches per entry, more than the upstream
+-- rate limit can serve within one request, so POST /api/me/import(/tachiyomi) only queues
+-- the parsed entries here. Workers claim jobs with SKIP LOCKED and report progress as they
+-- go.
+
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL, -- mal, anilist, tachiyomi
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'done', 'failed')),
+    total INT NOT NULL,
+    processed INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    -- The parsed entries; cleared once the job has finished
+    payload JSONB,
+    report JSONB,
+    error TEXT,
+    -- A running job whose lease has passed is started over (its worker died)
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_jobs_user ON import_jobs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_import_jobs_queued ON import_jobs(created_at) WHERE status IN ('queued', 'running');
+
+-- One import at a time per user
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_import_job ON import_jobs(user_id) WHERE status IN ('queued', 'running');
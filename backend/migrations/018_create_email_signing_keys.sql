@@ -0,0 +1,12 @@
+-- Migration: Stored email signing key
+-- Unsubscribe (and address confirmation) links are signed with EMAIL_SIGNING_KEY, or when
+-- that is unset with the key generated into this table on first start, so links in emails
+-- already sent keep working across restarts and every instance agrees on it.
+
+CREATE TABLE IF NOT EXISTS email_signing_keys (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    signing_key TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+COMMENT ON TABLE email_signing_keys IS 'Single-row key for signed email links; sent links break if it changes';
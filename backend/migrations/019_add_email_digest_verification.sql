@@ -0,0 +1,14 @@
+-- Migration: Confirmed digest addresses
+-- Digests only go to an address the user confirmed through a signed link; until then the
+-- requested frequency waits in pending_frequency. Confirmation and test emails are
+-- throttled per user so the server can't be used to mail arbitrary addresses.
+
+ALTER TABLE email_digest_preferences
+    ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS pending_frequency VARCHAR(10) CHECK (pending_frequency IN ('daily', 'weekly')),
+    ADD COLUMN IF NOT EXISTS verification_sent_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS test_sent_at TIMESTAMP;
+
+-- Addresses saved before confirmation existed were never checked: they wait for it too
+UPDATE email_digest_preferences SET pending_frequency = frequency, frequency = 'off'
+WHERE frequency <> 'off' AND verified_at IS NULL;
@@ -0,0 +1,15 @@
+-- Migration: First-seen times of genre feed entries
+-- Genre lists carry no dates and their series aren't tracked in release_feed_state, so the
+-- genre feeds date an entry by when it first showed up. The entry id changes with the
+-- episode count or chapter, which makes that the time of the latest update.
+-- Rows of entries no longer listed are pruned after last_seen_at stops moving.
+
+CREATE TABLE IF NOT EXISTS feed_entries (
+    entry_id TEXT PRIMARY KEY,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feed_entries_last_seen ON feed_entries(last_seen_at);
+
+COMMENT ON TABLE feed_entries IS 'When each genre feed entry was first seen, for stable Atom <updated> times';
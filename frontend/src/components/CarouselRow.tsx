@@ -1,8 +1,9 @@
-import { useRef, useState } from 'react';
+import { useEffect, useState } from 'react';
 import { Swiper, SwiperSlide } from 'swiper/react';
 import { Autoplay, Navigation } from 'swiper/modules';
 import { FaPlay, FaChevronLeft, FaChevronRight } from "react-icons/fa";
 import { getAnimeImage } from '../lib/utils';
+import { prependRelease, subscribeReleases } from '../lib/events';
 
 // Import Swiper styles
 import 'swiper/css';
@@ -14,14 +15,23 @@ interface Props {
     endpoint?: string;
     type: 'anime' | 'manga';
     autoPlay?: boolean;
+    live?: boolean; // Prepend new releases of this type as they are streamed
 }
 
-export default function CarouselRow({ title, items = [], type, autoPlay = true }: Props) {
-    if (items.length === 0) return null;
-
+export default function CarouselRow({ title, items: initialItems = [], type, autoPlay = true, live = false }: Props) {
+    const [items, setItems] = useState<any[]>(initialItems);
     const [prevEl, setPrevEl] = useState<HTMLElement | null>(null);
     const [nextEl, setNextEl] = useState<HTMLElement | null>(null);
 
+    useEffect(() => {
+        if (!live) return;
+        return subscribeReleases((event) => {
+            if (event.type === type) setItems((current) => prependRelease(current, event));
+        });
+    }, [live, type]);
+
+    if (items.length === 0) return null;
+
     return (
         <div className="py-6 px-4 md:px-12 group relative">
             <div className="flex items-center justify-between mb-4">
@@ -3,6 +3,7 @@ import { FaChevronLeft, FaChevronRight, FaPlay } from "react-icons/fa";
 import SkeletonRow from "./skeletons/SkeletonRow";
 import { apiFetch } from '../lib/api';
 import { getAnimeImage } from '../lib/utils';
+import { prependRelease, subscribeReleases } from '../lib/events';
 
 interface Props {
     title: string;
@@ -10,9 +11,10 @@ interface Props {
     endpoint?: string;
     type: 'anime' | 'manga';
     dataKey?: string; // Optional key to extract from response (e.g., "ongoing", "completed")
+    live?: boolean; // Prepend new releases of this type as they are streamed
 }
 
-export default function ContentRow({ title, items: initialItems = [], endpoint, type, dataKey }: Props) {
+export default function ContentRow({ title, items: initialItems = [], endpoint, type, dataKey, live = false }: Props) {
     const rowRef = useRef<HTMLDivElement>(null);
     const [items, setItems] = useState<any[]>(initialItems);
     const [loading, setLoading] = useState(!!endpoint && initialItems.length === 0);
@@ -47,6 +49,13 @@ export default function ContentRow({ title, items: initialItems = [], endpoint,
         fetchData();
     }, [endpoint, dataKey]);
 
+    useEffect(() => {
+        if (!live) return;
+        return subscribeReleases((event) => {
+            if (event.type === type) setItems((current) => prependRelease(current, event));
+        });
+    }, [live, type]);
+
     const scroll = (direction: 'left' | 'right') => {
         if (rowRef.current) {
             const { scrollLeft, clientWidth } = rowRef.current;
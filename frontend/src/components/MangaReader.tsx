@@ -55,7 +55,7 @@ export default function MangaReader({
                 // Prefer formatted slug to ensure Series Name is present
                 // (API sometimes returns just "Chapter X")
                 title: res.title && res.title.length > 10 ? res.title : formattedTitle, 
-                images: res.images || [],
+                images: res.proxyImages || res.images || [],
                 nextSlug: res.nextSlug, // Navigation from API
             };
 
@@ -155,7 +155,7 @@ export default function MangaReader({
                         {chapter.images.map((img, idx) => (
                             <img
                                 key={`${chapter.slug}-${idx}`}
-                                src={`/api/proxy/image?url=${encodeURIComponent(img)}`}
+                                src={img.startsWith("/api/proxy/") ? img : `/api/proxy/image?url=${encodeURIComponent(img)}`}
                                 className="max-w-full mx-auto shadow-lg md:rounded-lg"
                                 loading="lazy"
                                 alt={`${chapter.title} - Page ${idx + 1}`}
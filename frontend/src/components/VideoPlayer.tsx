@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import ReactPlayer from 'react-player';
 import { FaPlay, FaSpinner, FaExclamationTriangle } from "react-icons/fa";
 
@@ -6,12 +6,15 @@ interface VideoPlayerProps {
     src: string;
     poster?: string;
     title?: string;
+    // Playback position reports (only for direct/HLS sources, not iframes)
+    onProgress?: (positionSeconds: number, durationSeconds: number, completed: boolean) => void;
 }
 
-export default function VideoPlayer({ src, poster, title }: VideoPlayerProps) {
+export default function VideoPlayer({ src, poster, title, onProgress }: VideoPlayerProps) {
     const [hasMounted, setHasMounted] = useState(false);
     const [error, setError] = useState(false);
     const [loading, setLoading] = useState(true);
+    const duration = useRef(0);
 
     // Prevent hydration issues
     useEffect(() => {
@@ -72,12 +75,17 @@ export default function VideoPlayer({ src, poster, title }: VideoPlayerProps) {
                             </div>
                         }
                         onReady={() => setLoading(false)}
+                        onDuration={(d) => { duration.current = d; }}
+                        onProgress={({ playedSeconds }) => onProgress?.(playedSeconds, duration.current, false)}
+                        onEnded={() => onProgress?.(duration.current, duration.current, true)}
                         onError={() => {
                             setError(true);
                             setLoading(false);
                         }}
                         config={{
                             file: {
+                                // Proxied HLS links don't end in .m3u8, so tell ReactPlayer to use hls.js
+                                forceHLS: src.includes('/api/proxy/hls'),
                                 attributes: {
                                     crossOrigin: 'anonymous', 
                                 }
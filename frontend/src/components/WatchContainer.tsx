@@ -1,12 +1,14 @@
-import React, { useState } from 'react';
+import React, { useState, useRef } from 'react';
 import VideoPlayer from './VideoPlayer';
 import { FaChevronLeft, FaChevronRight, FaServer } from 'react-icons/fa';
 import { MdHighQuality } from 'react-icons/md';
+import { authFetch, getTokens } from '../lib/auth';
 
 interface StreamServer {
   title: string;
   serverId: string;
   href: string;
+  isM3U8?: boolean;
 }
 
 interface Quality {
@@ -21,6 +23,9 @@ interface WatchContainerProps {
     animeTitle: string;
     episodeTitle: string;
     episodeNumber: string;
+    animeSlug?: string;
+    episodeSlug?: string;
+    cover?: string;
   };
   nav: {
     prevSlug?: string;
@@ -41,6 +46,30 @@ export default function WatchContainer({ sources, qualities = [], info, nav, epi
   const [selectedQualityServer, setSelectedQualityServer] = useState<string>('');
 
 
+  // Save watch progress for logged-in users (throttled, always on completion)
+  const lastReport = useRef(0);
+  const reportProgress = (position: number, duration: number, completed: boolean) => {
+    if (!getTokens() || !info.animeSlug || !info.episodeSlug) return;
+
+    const now = Date.now();
+    if (!completed && now - lastReport.current < 15000) return;
+    lastReport.current = now;
+
+    authFetch('/api/me/progress', {
+      method: 'POST',
+      body: JSON.stringify({
+        anime_slug: info.animeSlug,
+        episode_slug: info.episodeSlug,
+        anime_title: info.animeTitle,
+        episode_title: info.episodeTitle,
+        cover_image: info.cover,
+        position_seconds: Math.floor(position),
+        duration_seconds: Math.floor(duration),
+        completed,
+      }),
+    }).catch(() => {});
+  };
+
   const handleServerChange = (src: string, name: string) => {
     setCurrentSrc(src);
     setActiveServer(name);
@@ -66,7 +95,8 @@ export default function WatchContainer({ sources, qualities = [], info, nav, epi
     let videoUrl = server.href;
     
     // If it's a relative path (starts with /), prefix with Otakudesu base URL
-    if (videoUrl.startsWith('/')) {
+    // (our own /api/proxy/hls links stay relative to the backend)
+    if (videoUrl.startsWith('/') && !videoUrl.startsWith('/api/')) {
       videoUrl = `https://otakudesu.cloud${videoUrl}`;
     }
     
@@ -79,7 +109,7 @@ export default function WatchContainer({ sources, qualities = [], info, nav, epi
   return (
     <div className="space-y-6">
        {/* Player */}
-       <VideoPlayer src={currentSrc} title={`Episode ${info.episodeNumber}`} />
+       <VideoPlayer src={currentSrc} title={`Episode ${info.episodeNumber}`} onProgress={reportProgress} />
 
        {/* Title Header (Moved Below Video) */}
        <div>
@@ -0,0 +1,79 @@
+// Browser session: the token pair from /api/auth/login, refreshed when the access token expires
+
+export interface Tokens {
+  access_token: string;
+  refresh_token: string;
+  token_type: string;
+  expires_at: string;
+}
+
+export function getTokens(): Tokens | null {
+  if (typeof window === 'undefined') return null;
+  return JSON.parse(localStorage.getItem('tokens') || 'null');
+}
+
+export function saveSession(user: unknown, tokens: Tokens): void {
+  localStorage.setItem('user', JSON.stringify(user));
+  localStorage.setItem('tokens', JSON.stringify(tokens));
+}
+
+export function clearSession(): void {
+  localStorage.removeItem('user');
+  localStorage.removeItem('tokens');
+}
+
+let refreshing: Promise<string | null> | null = null;
+
+// Exchanges the refresh token for a new pair and returns the new access token, null when the
+// session is gone. Concurrent callers share one request: a refresh token only works once.
+export function refreshTokens(): Promise<string | null> {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const tokens = getTokens();
+      if (!tokens?.refresh_token) return null;
+
+      const res = await fetch('/api/auth/refresh', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: tokens.refresh_token }),
+      }).catch(() => null);
+      if (!res) return null; // Offline: keep the session, the next call tries again
+      if (!res.ok) {
+        // Another tab may have refreshed first and stored the new pair
+        const current = getTokens();
+        if (current && current.refresh_token !== tokens.refresh_token) return current.access_token;
+        if (res.status === 401) clearSession();
+        return null;
+      }
+
+      const result = await res.json();
+      localStorage.setItem('tokens', JSON.stringify(result.tokens));
+      return result.tokens.access_token as string;
+    })().finally(() => {
+      refreshing = null;
+    });
+  }
+  return refreshing;
+}
+
+// fetch with the access token; on a 401 the tokens are refreshed and the request is sent once
+// more. Resolves to null when the user isn't logged in.
+export async function authFetch(
+  input: string,
+  init: Omit<RequestInit, 'headers'> & { headers?: Record<string, string> } = {},
+): Promise<Response | null> {
+  const tokens = getTokens();
+  if (!tokens?.access_token) return null;
+
+  const send = (accessToken: string) =>
+    fetch(input, {
+      ...init,
+      headers: { 'Content-Type': 'application/json', ...init.headers, Authorization: `Bearer ${accessToken}` },
+    });
+
+  const res = await send(tokens.access_token);
+  if (res.status !== 401) return res;
+
+  const accessToken = await refreshTokens();
+  return accessToken ? send(accessToken) : res;
+}
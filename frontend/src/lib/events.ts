@@ -0,0 +1,80 @@
+// Live release updates from /api/events (Server-Sent Events)
+
+import { getTokens, refreshTokens } from './auth';
+
+export interface ReleaseEvent {
+  id: number;
+  type: 'anime' | 'manga';
+  slug: string;
+  title: string;
+  release: string;
+  target_slug?: string;
+  cover_image: string;
+  url: string;
+  created_at: string;
+}
+
+// Calls onRelease for every new episode/chapter; bookmarkedOnly needs a logged-in user.
+// Returns a function that closes the stream.
+export function subscribeReleases(
+  onRelease: (event: ReleaseEvent) => void,
+  { bookmarkedOnly = false } = {},
+): () => void {
+  let source: EventSource | null = null;
+  let lastEventId = '';
+  let retryTimer: ReturnType<typeof setTimeout> | undefined;
+  let closed = false;
+
+  const connect = (accessToken = getTokens()?.access_token) => {
+    if (closed) return;
+    const params = new URLSearchParams();
+    if (bookmarkedOnly) {
+      if (!accessToken) return;
+      params.set('bookmarked', 'true');
+      params.set('access_token', accessToken);
+    }
+    // EventSource resends Last-Event-ID on its own reconnects, not on a new EventSource
+    if (lastEventId) params.set('lastEventId', lastEventId);
+
+    source = new EventSource(`/api/events?${params}`);
+    const handle = (e: MessageEvent) => {
+      lastEventId = e.lastEventId;
+      onRelease(JSON.parse(e.data));
+    };
+    source.addEventListener('episode.released', handle as EventListener);
+    source.addEventListener('chapter.released', handle as EventListener);
+    source.onerror = () => {
+      // A rejected request (e.g. expired token) closes the stream for good: start a new one,
+      // with a refreshed token since EventSource doesn't expose the status
+      if (source?.readyState !== EventSource.CLOSED || closed) return;
+      if (bookmarkedOnly) {
+        refreshTokens().then((token) => {
+          if (token) connect(token);
+          else retryTimer = setTimeout(connect, 10000);
+        });
+      } else {
+        retryTimer = setTimeout(connect, 10000);
+      }
+    };
+  };
+
+  connect();
+  return () => {
+    closed = true;
+    clearTimeout(retryTimer);
+    source?.close();
+  };
+}
+
+// Puts a streamed release at the front of a home-page row in the shape of the upstream
+// list items, replacing the series' older entry
+export function prependRelease(items: any[], event: ReleaseEvent): any[] {
+  const item = {
+    slug: event.slug,
+    title: event.title,
+    poster: event.cover_image,
+    image: event.cover_image,
+    releaseDate: event.release,
+  };
+  return [item, ...items.filter((existing) => (existing.slug || existing.animeId) !== event.slug)];
+}
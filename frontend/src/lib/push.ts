@@ -0,0 +1,49 @@
+// Browser side of Web Push: registers /push-sw.js and the subscription with the backend
+
+import { authFetch, getTokens } from './auth';
+
+function base64UrlToBytes(value: string): Uint8Array {
+  const base64 = (value + '='.repeat((4 - (value.length % 4)) % 4)).replace(/-/g, '+').replace(/_/g, '/');
+  return Uint8Array.from(atob(base64), (c) => c.charCodeAt(0));
+}
+
+export function pushSupported(): boolean {
+  return typeof window !== 'undefined' && 'serviceWorker' in navigator && 'PushManager' in window;
+}
+
+// Asks for permission and registers this device. Returns false when not possible.
+export async function enablePushNotifications(): Promise<boolean> {
+  if (!pushSupported() || !getTokens()) return false;
+  if ((await Notification.requestPermission()) !== 'granted') return false;
+
+  const keyRes = await fetch('/api/push/vapid-public-key');
+  if (!keyRes.ok) return false;
+  const { data } = await keyRes.json();
+
+  const registration = await navigator.serviceWorker.register('/push-sw.js');
+  const subscription =
+    (await registration.pushManager.getSubscription()) ||
+    (await registration.pushManager.subscribe({
+      userVisibleOnly: true,
+      applicationServerKey: base64UrlToBytes(data.public_key),
+    }));
+
+  const res = await authFetch('/api/me/push/subscriptions', {
+    method: 'POST',
+    body: JSON.stringify(subscription.toJSON()),
+  });
+  return !!res?.ok;
+}
+
+export async function disablePushNotifications(): Promise<void> {
+  if (!pushSupported()) return;
+  const registration = await navigator.serviceWorker.getRegistration('/push-sw.js');
+  const subscription = await registration?.pushManager.getSubscription();
+  if (!subscription) return;
+
+  await authFetch('/api/me/push/subscriptions', {
+    method: 'DELETE',
+    body: JSON.stringify({ endpoint: subscription.endpoint }),
+  }).catch(() => {});
+  await subscription.unsubscribe();
+}